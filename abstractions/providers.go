@@ -10,6 +10,7 @@ import (
 type ICourseProvider interface {
 	CreateNewCourse(model dao.CourseModel) (string, error)
 	UpdateCourse(model dao.CourseModel) error
+	UpsertCourses(models []dao.CourseModel) error
 	ArchiveCourse(id string) error
 	GetCourseByParams(name string) (*dao.CourseModel, error)
 	GetCourseById(id string) (*dao.CourseModel, error)
@@ -18,13 +19,21 @@ type ICourseProvider interface {
 
 type IScheduleProvider interface {
 	CreateNewSchedule(model dao.ScheduleModel) error
-	GetScheduleByDate(time time.Time) ([]dao.ScheduleModel, error)
+	GetScheduleByDate(time time.Time, subgroup string) ([]dao.ScheduleModel, error)
+	GetAdditionalSchedulesByDate(date time.Time, subgroup string) []dao.AdditionalScheduleModel
 	CreateNewAdditionalSchedule(model dao.AdditionalScheduleModel) error
-	ValidateAddScheduleCreation(date time.Time, order int) (bool, error)
-	ValidateScheduleCreation(weekday time.Weekday, order int, weekOrder util.WeekOrder) (bool, error)
+	ReplaceAdditionalSchedules(models []dao.AdditionalScheduleModel) error
+	RestoreAdditionalSchedules(additionals map[string][]dao.AdditionalScheduleModel) error
+	ValidateAddScheduleCreation(date time.Time, order int, subgroup string) (bool, error)
+	ValidateScheduleCreation(weekday time.Weekday, order int, weekOrder util.WeekOrder, subgroup string) (bool, error)
 	DropAllSchedules() error
+	ReplaceCommonSchedule(schedules map[time.Weekday][]dao.ScheduleModel) error
 	GetCommonSchedule() map[time.Weekday][]dao.ScheduleModel
-	LinkCourseToUser(userId int, courseId string) error
+	GetScheduleById(id string) (*dao.ScheduleModel, error)
+	LinkCoursesToUsers(links map[string]map[int]string) error
+	IsInitialized() bool
+	Export() (map[time.Weekday][]dao.ScheduleModel, map[string][]dao.AdditionalScheduleModel)
+	Import(schedules map[time.Weekday][]dao.ScheduleModel, additionals map[string][]dao.AdditionalScheduleModel) error
 }
 
 type IUserActionProvider interface {
@@ -36,3 +45,87 @@ type IChatProvider interface {
 	GetChatByUserId(userId int) (int64, error)
 	SaveChatForUser(userId int, chatId int64) error
 }
+
+type IExamProvider interface {
+	CreateNewExam(model dao.ExamModel) (string, error)
+	UpdateExam(model dao.ExamModel) error
+	DeleteExam(id string) error
+	GetExamById(id string) (*dao.ExamModel, error)
+	GetExams() ([]dao.ExamModel, error)
+}
+
+type ISnapshotProvider interface {
+	CreateNewSnapshot(model dao.ScheduleSnapshotModel) (string, error)
+	DeleteSnapshot(id string) error
+	GetSnapshotById(id string) (*dao.ScheduleSnapshotModel, error)
+	GetSnapshots() ([]dao.ScheduleSnapshotModel, error)
+}
+
+type IElectiveProvider interface {
+	CreateNewPool(model dao.ElectivePoolModel) (string, error)
+	DeletePool(id string) error
+	GetPools() ([]dao.ElectivePoolModel, error)
+	GetPoolByScheduleId(scheduleId string) (*dao.ElectivePoolModel, error)
+	GetWaitlist(scheduleId string, courseId string) ([]int, error)
+	GetWaitlistsBySchedule(scheduleId string) (map[string][]int, error)
+	SaveWaitlist(scheduleId string, courseId string, userIds []int) error
+	GetSelectionWindow() (*dao.ElectiveSelectionWindowModel, error)
+	SaveSelectionWindow(model dao.ElectiveSelectionWindowModel) error
+}
+
+type IUserProvider interface {
+	SaveUser(model dao.UserModel) error
+	GetUserById(userId int) (*dao.UserModel, error)
+	GetUserByUserName(userName string) (*dao.UserModel, error)
+	SetBlockedBot(userId int, blocked bool) error
+}
+
+type IGroupProvider interface {
+	CreateNewGroup(model dao.GroupModel) (string, error)
+	SaveGroup(model dao.GroupModel) error
+	GetGroups() ([]dao.GroupModel, error)
+	GetGroupById(id string) (*dao.GroupModel, error)
+	GetActiveGroupId(userId int) (string, error)
+	SaveActiveGroupId(userId int, groupId string) error
+}
+
+type ITeacherProvider interface {
+	CreateNewTeacher(model dao.TeacherModel) (string, error)
+	UpdateTeacher(model dao.TeacherModel) error
+	GetTeachers() ([]dao.TeacherModel, error)
+	GetTeacherById(id string) (*dao.TeacherModel, error)
+	GetTeacherByUserId(userId int) (*dao.TeacherModel, error)
+	GetTeacherByName(name string) (*dao.TeacherModel, error)
+}
+
+type IPreferencesProvider interface {
+	GetPreferences(userId int) (*dao.PreferencesModel, error)
+	SavePreferences(model dao.PreferencesModel) error
+}
+
+type IOutboxProvider interface {
+	SaveEntries(models []dao.OutboxEntryModel) error
+	GetEntry(id string) (*dao.OutboxEntryModel, error)
+	SetEntryState(id string, state util.OutboxState, reason string) error
+	DeleteEntries(ids []string) error
+	DeleteEntriesBefore(date time.Time) error
+}
+
+type IDeferredProvider interface {
+	SaveDeferred(model dao.DeferredMessageModel) (string, error)
+	GetDeferred() ([]dao.DeferredMessageModel, error)
+	DeleteDeferred(id string) error
+}
+
+type IPublicationTargetProvider interface {
+	SaveTarget(model dao.PublicationTargetModel) error
+	DeleteTarget(chatId int64) error
+	GetTarget(chatId int64) (*dao.PublicationTargetModel, error)
+	GetTargetsByGroupId(groupId string) ([]dao.PublicationTargetModel, error)
+}
+
+type ISubgroupProvider interface {
+	GetSubgroups() (dao.SubgroupsModel, error)
+	GetUserSubgroup(userId int) string
+	SaveSubgroups(model dao.SubgroupsModel) error
+}
@@ -1,11 +1,18 @@
 package abstractions
 
-import "telegram-notification-bot-core/dto"
+import (
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
 
 type ICourseService interface {
 	CreateNewCourse(request dto.CreateNewCourseRequest) (string, error)
 	UpdateCourse(request dto.UpdateCourseInfoRequest) error
 	DeleteCourse(request dto.ArchiveCourseRequest) error
+	SetCourseCapacity(request dto.SetCourseCapacityRequest) error
+	UpdateMeetLink(request dto.UpdateMeetLinkRequest) error
+	LinkTeacher(teacherId string, teacherName string) error
 	GetCourses() (*dto.GetCoursesResponse, error)
 	GetOptionalCourses() (*dto.GetCoursesResponse, error)
 	GetCourseById(id string) (*dto.CourseDto, error)
@@ -15,10 +22,81 @@ type IScheduleService interface {
 	CreateNewSchedule(request dto.CreateNewScheduleRequest) error
 	ClearSchedule() error
 	InsertAdditionalSchedule(request dto.CreateNewAdditionalScheduleRequest) error
+	PreviewCancelRange(request dto.CancelRangeRequest) (*dto.CancelRangeResponse, error)
+	CancelRange(request dto.CancelRangeRequest) (*dto.CancelRangeResponse, error)
 	GetCurrentSchedule(userId int) (*dto.GetScheduleResponse, error)
+	GetScheduleForDate(userId int, date time.Time) (*dto.GetScheduleResponse, error)
+	GetUpcomingSchedules(userIds []int) (map[int][]dto.GetScheduleResponse, error)
+	DiffUpcomingSchedules(before, after map[int][]dto.GetScheduleResponse) map[int][]dto.LessonChangeDto
+	IsUpcomingDate(date time.Time) bool
 	GetCommonSchedule(userId int) (*dto.GetCommonScheduleResponse, error)
+	PrepareChatScheduleForNotify() ([]dto.ScheduleDto, error)
 	PrepareSchedulesListForNotify(userIds []int) (map[int][]dto.ScheduleDto, error)
-	LinkOptionalCourseToUser(request dto.LinkOptionalCourseToUserRequest) error
+	GetTeacherLessons(teacherId string, from time.Time, to time.Time) ([]dto.TeacherLessonDto, error)
+	GetOptionalSlots(userId int) (*dto.GetOptionalSlotsResponse, error)
+	SaveScheduleSnapshot(request dto.CreateScheduleSnapshotRequest) (string, error)
+	GetScheduleSnapshots() (*dto.GetScheduleSnapshotsResponse, error)
+	CloneScheduleSnapshot(request dto.CloneScheduleSnapshotRequest) error
+	DeleteScheduleSnapshot(request dto.DeleteScheduleSnapshotRequest) error
+	GetDraftDiff() (*dto.ScheduleDiffResponse, error)
+	PublishDraft() (*dto.ScheduleDiffResponse, error)
+	DiscardDraft() error
+}
+
+type IExamService interface {
+	CreateNewExam(request dto.CreateNewExamRequest) (string, error)
+	UpdateExam(request dto.UpdateExamRequest) error
+	DeleteExam(request dto.DeleteExamRequest) error
+	GetExamById(id string) (*dto.ExamDto, error)
+	GetUpcomingExams() (*dto.GetExamsResponse, error)
+}
+
+type IElectiveService interface {
+	CreateElectivePool(request dto.CreateElectivePoolRequest) (string, error)
+	DeleteElectivePool(request dto.DeleteElectivePoolRequest) error
+	GetElectivePools() (*dto.GetElectivePoolsResponse, error)
+	GetAllowedCourses(scheduleId string) (*dto.GetCoursesResponse, error)
+	LinkOptionalCourseToUser(request dto.LinkOptionalCourseToUserRequest) (*dto.LinkOptionalCourseToUserResponse, error)
+	OpenSelectionWindow(request dto.OpenSelectionWindowRequest) error
+	GetSelectionWindow() (*dto.SelectionWindowDto, error)
+	PrepareSelectionReminders(studentIds []int) (*dto.SelectionRemindersResponse, error)
+	AutoAssignElectives(studentIds []int) (*dto.AutoAssignElectivesResponse, error)
+	ImportElectivesCsv(data []byte, studentIds []int) (*dto.ImportElectivesResponse, error)
+	GetElectiveRoster(studentIds []int) (*dto.ElectiveRosterResponse, error)
+	ExportElectiveRosterCsv(studentIds []int) ([]byte, error)
+}
+
+type ISubgroupService interface {
+	CreateSubgroup(request dto.CreateSubgroupRequest) error
+	DeleteSubgroup(request dto.DeleteSubgroupRequest) error
+	AssignStudent(request dto.AssignSubgroupRequest) error
+	GetSubgroups() (*dto.GetSubgroupsResponse, error)
+	GetStudentSubgroup(userId int) string
+}
+
+type IPreferencesService interface {
+	GetReminderSettings(userId int) dto.ReminderSettingsDto
+	UpdateReminderSettings(request dto.UpdateReminderSettingsRequest) error
+	GetDefaultReminderOffsets() []int
+	GetQuietHours(chatId int64) dto.QuietHoursDto
+	UpdateQuietHours(request dto.UpdateQuietHoursRequest) error
+	CheckQuietHours(chatId int64, at time.Time) dto.QuietCheckDto
+	GetDigestSettings(userId int, kind util.DigestKind) dto.DigestSettingsDto
+	UpdateDigestSettings(request dto.UpdateDigestSettingsRequest) error
+	IsDigestDue(userId int, kind util.DigestKind, at time.Time) bool
+	MarkDigestSent(userId int, kind util.DigestKind, sentAt time.Time) error
+	GetMutes(userId int) dto.MutesDto
+	UpdateMute(request dto.UpdateMuteRequest) error
+}
+
+type ITeacherService interface {
+	RegisterTeacher(request dto.RegisterTeacherRequest) (string, error)
+	GetTeacher(userId int) (*dto.TeacherDto, error)
+	GetTeacherClasses(userId int, from time.Time, to time.Time) (*dto.GetTeacherClassesResponse, error)
+	GetTeacherCourses(userId int) (*dto.GetTeacherCoursesResponse, error)
+	CancelClass(request dto.CancelClassRequest) (*dto.CancelClassResponse, error)
+	UpdateMeetLink(request dto.UpdateTeacherMeetLinkRequest) (*dto.UpdateTeacherMeetLinkResponse, error)
+	PrepareTeacherSchedulesForNotify() (map[int][]dto.ScheduleDto, error)
 }
 
 type IBackgroundService interface {
@@ -29,3 +107,33 @@ type IActionService interface {
 	SaveUserCurrentState(id int, action dto.UserActionDto)
 	GetUserCurrentState(ud int) dto.UserActionDto
 }
+
+// GroupScope bundles the services owning the data of one student group
+type GroupScope struct {
+	GroupId   string
+	Name      string
+	MemberIds []int
+	AdminIds  []int
+	Course    ICourseService
+	Schedule  IScheduleService
+	Exams     IExamService
+	Electives IElectiveService
+	Subgroups ISubgroupService
+}
+
+type IGroupService interface {
+	CreateGroup(request dto.CreateGroupRequest) (string, error)
+	AddGroupMember(request dto.GroupMemberRequest) error
+	RemoveGroupMember(request dto.GroupMemberRequest) error
+	GetUserGroups(userId int) (*dto.GetGroupsResponse, error)
+	SelectGroup(request dto.SelectGroupRequest) error
+	GetUserScope(userId int) GroupScope
+	GetScope(groupId string) (GroupScope, error)
+	GetScopes() []GroupScope
+	AddPublicationTarget(request dto.AddPublicationTargetRequest) error
+	RemovePublicationTarget(chatId int64) error
+	GetPublicationTarget(chatId int64) (*dto.PublicationTargetDto, error)
+	GetPublicationTargets(groupId string) ([]dto.PublicationTargetDto, error)
+	UpdatePublicationTarget(request dto.UpdatePublicationTargetRequest) error
+	MarkDailySchedulePosted(chatId int64, postedAt time.Time) error
+}
@@ -3,15 +3,50 @@ package actions
 type UserAction int
 
 const (
-	UserActionNone                UserAction = 0
-	UserActionInputCourseName     UserAction = 1
-	UserActionInputTeacherName    UserAction = 2
-	UserActionInputTeacherContact UserAction = 3
-	UserActionInputMeetLink       UserAction = 4
-	UserActionChooseCourse        UserAction = 6
-	UserActionInputWeekday        UserAction = 7
-	UserActionInputWeekOrder      UserAction = 8
-	UserActionInputOrder          UserAction = 9
-	UserActionInputDate           UserAction = 10
-	UserActionSelectOptionality   UserAction = 11
+	UserActionNone                 UserAction = 0
+	UserActionInputCourseName      UserAction = 1
+	UserActionInputTeacherName     UserAction = 2
+	UserActionInputTeacherContact  UserAction = 3
+	UserActionInputMeetLink        UserAction = 4
+	UserActionChooseCourse         UserAction = 6
+	UserActionInputWeekday         UserAction = 7
+	UserActionInputWeekOrder       UserAction = 8
+	UserActionInputOrder           UserAction = 9
+	UserActionInputDate            UserAction = 10
+	UserActionSelectOptionality    UserAction = 11
+	UserActionChooseExam           UserAction = 12
+	UserActionInputExamKind        UserAction = 13
+	UserActionInputExamTime        UserAction = 14
+	UserActionInputExamRoom        UserAction = 15
+	UserActionInputRangeStart      UserAction = 16
+	UserActionInputRangeEnd        UserAction = 17
+	UserActionChooseFilter         UserAction = 18
+	UserActionConfirm              UserAction = 19
+	UserActionInputSnapshotName    UserAction = 20
+	UserActionChooseSnapshot       UserAction = 21
+	UserActionChooseOptionalSlot   UserAction = 22
+	UserActionInputPoolName        UserAction = 23
+	UserActionChoosePool           UserAction = 24
+	UserActionInputCapacity        UserAction = 25
+	UserActionInputDeadline        UserAction = 26
+	UserActionUploadDocument       UserAction = 27
+	UserActionInputGroupName       UserAction = 28
+	UserActionChooseGroup          UserAction = 29
+	UserActionInputGroupMember     UserAction = 30
+	UserActionChooseTargetSettings UserAction = 31
+	UserActionInputChannel         UserAction = 32
+	UserActionChooseTarget         UserAction = 33
+	UserActionInputSubgroup        UserAction = 34
+	UserActionInputSubgroupName    UserAction = 35
+	UserActionInputSubgroupMember  UserAction = 36
+	UserActionInputTeacher         UserAction = 37
+	UserActionChooseClass          UserAction = 38
+	UserActionChooseReminders      UserAction = 39
+	UserActionInputReminderOffsets UserAction = 40
+	UserActionChooseQuietHours     UserAction = 41
+	UserActionInputQuietHours      UserAction = 42
+	UserActionInputMutedUntil      UserAction = 43
+	UserActionChooseDigest         UserAction = 44
+	UserActionInputDigestTime      UserAction = 45
+	UserActionChooseMutes          UserAction = 46
 )
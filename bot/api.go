@@ -1,8 +1,15 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/commands"
 	"telegram-notification-bot-core/configuration"
 	"telegram-notification-bot-core/dto"
 	"telegram-notification-bot-core/util"
@@ -12,31 +19,255 @@ import (
 type Api struct {
 	client      *tgbotapi.BotAPI
 	cfg         configuration.Configuration
+	users       abstractions.IUserProvider
+	preferences abstractions.IPreferencesService
+	deferred    abstractions.IDeferredProvider
+	metrics     *deliveryMetrics
+	queue       *sendQueue
 	updatesChan tgbotapi.UpdatesChannel
 }
 
-func NewApi(cfg configuration.Configuration) (*Api, error) {
+func NewApi(
+	cfg configuration.Configuration,
+	users abstractions.IUserProvider,
+	preferences abstractions.IPreferencesService,
+	deferred abstractions.IDeferredProvider) (*Api, error) {
 	client, err := tgbotapi.NewBotAPI(cfg.TelegramTokenBot)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &Api{client: client, cfg: cfg}, nil
+	return &Api{
+		client:      client,
+		cfg:         cfg,
+		users:       users,
+		preferences: preferences,
+		deferred:    deferred,
+		metrics:     &deliveryMetrics{},
+		queue:       newSendQueue(),
+	}, nil
 }
 
-func (a *Api) SendNotification(scheduleDto dto.ScheduleDto, startTime time.Time, recipient int64) {
+func (a *Api) SendNotification(scheduleDto dto.ScheduleDto, startTime time.Time, recipient int64) error {
 
 	msg := tgbotapi.NewMessage(recipient, fmt.Sprintf(
 		"Пара № %d, тиждень: %s, %s \n Вчитель: %s \n Контакт: %s \n Посилання на зустріч: %s \n Час зустрічі: %s",
 		scheduleDto.Order,
 		util.ConvertToHumanReadableWeekOrder(scheduleDto.WeekOrder),
-		scheduleDto.CourseInfo.Name,
+		scheduleDto.CourseInfo.Name+formatSubgroup(scheduleDto.Subgroup),
 		scheduleDto.CourseInfo.TeacherName,
 		scheduleDto.CourseInfo.TeacherContact,
 		scheduleDto.CourseInfo.MeetLink,
 		startTime.Format(time.DateTime)))
-	go a.executeMessage(msg)
+
+	if scheduleDto.GroupName != "" {
+		msg.Text += " \n Група: " + scheduleDto.GroupName
+	}
+
+	// the members may mute the lesson in their private chat, the lessons of teachers have the group name
+	if keys := prepareMuteKeyboard(scheduleDto); recipient > 0 && scheduleDto.GroupName == "" && keys != nil {
+		msg.ReplyMarkup = keys
+	}
+
+	return a.sendReminder(msg)
+}
+
+// SendMissedReminders sends the summary of the lessons whose reminders were missed while the bot was stopped,
+// done is called when the summary is delivered
+func (a *Api) SendMissedReminders(schedules []dto.ScheduleDto, recipient int64, done func(err error)) {
+	parts := []string{"Бот був недоступний, пропущені нагадування на сьогодні:\n"}
+
+	for _, val := range schedules {
+		parts = append(parts, fmt.Sprintf("\n№ %d. %s, початок о %s \n Посилання на зустріч: %s \n",
+			val.Order,
+			val.CourseInfo.Name+formatSubgroup(val.Subgroup),
+			util.GetMidnightTime().Add(a.cfg.ScheduleSettings.TimeSlotsConfiguration[val.Order].StartTime).Format("15:04"),
+			val.CourseInfo.MeetLink))
+	}
+
+	a.deliverDeferrable(priorityReminder, prepareLongMessages(recipient, parts), done)
+}
+
+func (a *Api) SendExamNotification(examDto dto.ExamDto, recipient int64) {
+
+	msg := tgbotapi.NewMessage(recipient, fmt.Sprintf(
+		"%s: %s \n Час: %s \n Аудиторія: %s \n Вчитель: %s \n До початку: %s",
+		util.ConvertToHumanReadableExamKind(examDto.Kind),
+		examDto.CourseInfo.Name,
+		examDto.StartTime.Format("2006-01-02 15:04"),
+		examDto.Room,
+		examDto.CourseInfo.TeacherName,
+		util.ConvertToHumanReadableCountdown(time.Until(examDto.StartTime))))
+	go a.sendDeferrable(priorityReminder, []tgbotapi.MessageConfig{msg})
+}
+
+func (a *Api) SendDailySchedule(schedules []dto.ScheduleDto, recipient int64) {
+	var parts []string
+
+	for _, val := range schedules {
+		parts = append(parts, fmt.Sprintf("\n№ %d. %s \n Вчитель: %s \n Посилання на зустріч: %s \n",
+			val.Order, val.CourseInfo.Name+formatSubgroup(val.Subgroup), val.CourseInfo.TeacherName, val.CourseInfo.MeetLink))
+	}
+
+	parts = append([]string{"Розклад на сьогодні. Дата: " + util.GetMidnightTime().Format(dateLayout) + "\n"}, parts...)
+
+	go a.sendDeferrable(priorityBulk, prepareLongMessages(recipient, parts))
+}
+
+// SendDailyDigest sends the user's schedule of today in the morning or of tomorrow in the evening
+func (a *Api) SendDailyDigest(schedule dto.GetScheduleResponse, kind util.DigestKind, recipient int64) {
+	go a.sendDeferrable(priorityBulk, prepareLongMessages(recipient, a.prepareDigestParts(schedule, kind)))
+}
+
+// prepareDigestParts formats the digest, the replacements and the cancellations are highlighted
+func (a *Api) prepareDigestParts(schedule dto.GetScheduleResponse, kind util.DigestKind) []string {
+	var parts []string
+
+	if kind == util.DigestKindEvening {
+		parts = append(parts, a.formatPreviewHeader(schedule))
+	} else {
+		parts = append(parts, fmt.Sprintf("Доброго ранку! Розклад на %s, тиждень: %s\n",
+			schedule.CurrentDate.Format(dateLayout), util.ConvertToHumanReadableWeekOrder(schedule.CurrentWeekOrder)))
+
+		if schedule.IsHoliday {
+			parts = append(parts, "\nСьогодні свято, пар немає\n")
+		} else if len(schedule.Schedules) == 0 {
+			parts = append(parts, "\nСьогодні пар немає\n")
+		}
+	}
+
+	if schedule.IsHoliday {
+		return parts
+	}
+
+	for _, val := range schedule.Schedules {
+		mark := ""
+
+		if val.IsReplacement {
+			mark = "🔄 Заміна: "
+		}
+
+		parts = append(parts, fmt.Sprintf("\n№ %d, %s. %s%s \n Вчитель: %s \n Посилання на зустріч: %s \n",
+			val.Order,
+			a.formatLessonTime(schedule.CurrentDate, val.Order),
+			mark,
+			val.CourseInfo.Name+formatSubgroup(val.Subgroup),
+			val.CourseInfo.TeacherName,
+			val.CourseInfo.MeetLink))
+	}
+
+	for _, val := range schedule.Cancelled {
+		parts = append(parts, fmt.Sprintf("\n❌ Скасовано: № %d, %s. %s \n",
+			val.Order,
+			a.formatLessonTime(schedule.CurrentDate, val.Order),
+			val.CourseInfo.Name+formatSubgroup(val.Subgroup)))
+	}
+
+	return parts
+}
+
+// formatPreviewHeader tells when tomorrow's first lesson starts and whether the day differs from the weekly schedule
+func (a *Api) formatPreviewHeader(schedule dto.GetScheduleResponse) string {
+	text := fmt.Sprintf("Завтра %s, %s, тиждень: %s\n",
+		strings.ToLower(util.ConvertToHumanReadableWeek(schedule.CurrentDate.Weekday())),
+		schedule.CurrentDate.Format(dateLayout),
+		util.ConvertToHumanReadableWeekOrder(schedule.CurrentWeekOrder))
+
+	if schedule.IsHoliday {
+		return text + "Завтра свято, пар немає\n"
+	}
+
+	if len(schedule.Schedules) == 0 {
+		if len(schedule.Cancelled) > 0 {
+			return text + "Усі пари скасовано, завтра пар немає\n"
+		}
+
+		return text + "Завтра пар немає\n"
+	}
+
+	first := schedule.Schedules[0]
+	text += fmt.Sprintf("Перша пара о %s\n",
+		schedule.CurrentDate.Add(a.cfg.ScheduleSettings.TimeSlotsConfiguration[first.Order].StartTime).Format("15:04"))
+
+	changed := len(schedule.Cancelled) > 0
+
+	for _, val := range schedule.Schedules {
+		changed = changed || val.IsReplacement
+	}
+
+	if changed {
+		return text + "Є зміни відносно звичайного розкладу\n"
+	}
+
+	return text + "Без змін відносно звичайного розкладу\n"
+}
+
+func (a *Api) SendSelectionReminder(pendingDto dto.RosterPendingDto, deadline time.Time, recipient int64) {
+	text := fmt.Sprintf(
+		"Оберіть курси за вибором до %s, залишилось: %s \n Команда: /%s \n Пари без вибору:",
+		deadline.Format(examTimeLayout),
+		util.ConvertToHumanReadableCountdown(time.Until(deadline)),
+		commands.LinkOptionalCourseCommand)
+
+	for _, slot := range pendingDto.MissingSlots {
+		text += "\n  " + formatSlotTime(slot)
+	}
+
+	go a.sendDeferrable(priorityBulk, []tgbotapi.MessageConfig{tgbotapi.NewMessage(recipient, text)})
+}
+
+// formatLessonTime returns the start and the end of the lesson of the order on the date
+func (a *Api) formatLessonTime(date time.Time, order int) string {
+	slot := a.cfg.ScheduleSettings.TimeSlotsConfiguration[order]
+
+	return date.Add(slot.StartTime).Format("15:04") + "-" + date.Add(slot.EndTime).Format("15:04")
+}
+
+// userName returns the username of the bot, commands addressed to other bots contain it after "@"
+func (a *Api) userName() string {
+	return a.client.Self.UserName
+}
+
+// resolveChannel finds the channel by its username, the bot must be an admin allowed to post there
+func (a *Api) resolveChannel(userName string) (*tgbotapi.Chat, error) {
+	if !strings.HasPrefix(userName, "@") {
+		userName = "@" + userName
+	}
+
+	chat, err := a.client.GetChat(tgbotapi.ChatConfig{SuperGroupUsername: userName})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !chat.IsChannel() {
+		return nil, errors.New("NotChannel")
+	}
+
+	member, err := a.client.GetChatMember(tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: a.client.Self.ID})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !member.IsCreator() && !(member.IsAdministrator() && member.CanPostMessages) {
+		return nil, errors.New("NotAdmin")
+	}
+
+	return &chat, nil
+}
+
+// isChatAdmin tells whether the user is the creator or an administrator of the telegram chat
+func (a *Api) isChatAdmin(chatId int64, userId int) bool {
+	member, err := a.client.GetChatMember(tgbotapi.ChatConfigWithUser{ChatID: chatId, UserID: userId})
+
+	if err != nil {
+		logrus.Warnf("Failed to check the admin %d of the chat %d: %s", userId, chatId, err)
+		return false
+	}
+
+	return member.IsCreator() || member.IsAdministrator()
 }
 
 func (a *Api) StartServe() {
@@ -50,9 +281,51 @@ func (a *Api) StartServe() {
 }
 
 func (a *Api) executeCallback(config tgbotapi.CallbackConfig) {
-	a.client.AnswerCallbackQuery(config)
+	_ = a.deliver(priorityReminder, 0, func() error {
+		_, err := a.client.AnswerCallbackQuery(config)
+		return err
+	})
 }
 
 func (a *Api) executeMessage(config tgbotapi.MessageConfig) {
-	a.client.Send(config)
+	_ = a.sendMessage(priorityAnswer, config)
+}
+
+func (a *Api) sendMessage(priority sendPriority, config tgbotapi.MessageConfig) error {
+	return a.deliver(priority, config.ChatID, func() error {
+		_, err := a.client.Send(config)
+		return err
+	})
+}
+
+func (a *Api) executeEdit(config tgbotapi.EditMessageReplyMarkupConfig) {
+	_ = a.deliver(priorityAnswer, config.ChatID, func() error {
+		_, err := a.client.Send(config)
+		return err
+	})
+}
+
+func (a *Api) downloadFile(fileId string) ([]byte, error) {
+	url, err := a.client.GetFileDirectURL(fileId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (a *Api) executeDocument(config tgbotapi.DocumentConfig) {
+	_ = a.deliver(priorityAnswer, config.ChatID, func() error {
+		_, err := a.client.Send(config)
+		return err
+	})
 }
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"gopkg.in/yaml.v2"
+	"strings"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+func TestPrepareDigestParts(t *testing.T) {
+	var cfg configuration.Configuration
+
+	err := yaml.Unmarshal([]byte(`
+schedule-settings:
+  time-slots-configuration:
+    1: {start-time: 8h, end-time: 9h30m}
+    2: {start-time: 10h, end-time: 11h30m}
+`), &cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &Api{cfg: cfg}
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)
+	math := dto.ScheduleDto{Order: 1, CourseInfo: dto.CourseDto{Name: "Math"}}
+	history := dto.ScheduleDto{Order: 2, CourseInfo: dto.CourseDto{Name: "History"}, IsReplacement: true}
+	art := dto.ScheduleDto{Order: 2, CourseInfo: dto.CourseDto{Name: "Art"}}
+
+	tests := []struct {
+		name     string
+		schedule dto.GetScheduleResponse
+		kind     util.DigestKind
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "morning without classes",
+			schedule: dto.GetScheduleResponse{CurrentDate: day},
+			kind:     util.DigestKindMorning,
+			want:     []string{"Доброго ранку! Розклад на 2024-01-10", "Сьогодні пар немає"},
+		},
+		{
+			name:     "morning of a holiday",
+			schedule: dto.GetScheduleResponse{CurrentDate: day, IsHoliday: true, Schedules: []dto.ScheduleDto{math}},
+			kind:     util.DigestKindMorning,
+			want:     []string{"Сьогодні свято, пар немає"},
+			notWant:  []string{"Math"},
+		},
+		{
+			name:     "morning with changes",
+			schedule: dto.GetScheduleResponse{CurrentDate: day, Schedules: []dto.ScheduleDto{math, history}, Cancelled: []dto.ScheduleDto{art}},
+			kind:     util.DigestKindMorning,
+			want:     []string{"№ 1, 08:00-09:30. Math", "№ 2, 10:00-11:30. 🔄 Заміна: History", "❌ Скасовано: № 2, 10:00-11:30. Art"},
+			notWant:  []string{"Сьогодні пар немає", "Заміна: Math"},
+		},
+		{
+			name:     "evening without classes",
+			schedule: dto.GetScheduleResponse{CurrentDate: day},
+			kind:     util.DigestKindEvening,
+			want:     []string{"Завтра пар немає"},
+		},
+		{
+			name:     "evening with every class cancelled",
+			schedule: dto.GetScheduleResponse{CurrentDate: day, Cancelled: []dto.ScheduleDto{math}},
+			kind:     util.DigestKindEvening,
+			want:     []string{"Усі пари скасовано, завтра пар немає", "❌ Скасовано: № 1, 08:00-09:30. Math"},
+		},
+		{
+			name:     "evening with a replacement",
+			schedule: dto.GetScheduleResponse{CurrentDate: day, Schedules: []dto.ScheduleDto{math, history}},
+			kind:     util.DigestKindEvening,
+			want:     []string{"Перша пара о 08:00", "Є зміни відносно звичайного розкладу", "🔄 Заміна: History"},
+		},
+		{
+			name:     "evening without changes",
+			schedule: dto.GetScheduleResponse{CurrentDate: day, Schedules: []dto.ScheduleDto{math}},
+			kind:     util.DigestKindEvening,
+			want:     []string{"Без змін відносно звичайного розкладу"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := strings.Join(api.prepareDigestParts(tt.schedule, tt.kind), "")
+
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("expected %q in %q", want, text)
+				}
+			}
+
+			for _, notWant := range tt.notWant {
+				if strings.Contains(text, notWant) {
+					t.Errorf("unexpected %q in %q", notWant, text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+func (h *Handler) handleCommandCancelRange(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CancelRangeCommand,
+		Action:  actions.UserActionInputRangeStart,
+	})
+
+	h.cancelRangeRequests[userId] = dto.CancelRangeRequest{}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть першу дату скасування у форматі "+dateLayout)}
+}
+
+func (h *Handler) handleActionInputRangeStart(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	date, err := time.ParseInLocation(dateLayout, upd.Message.Text, time.Local)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+	}
+
+	req := h.cancelRangeRequests[userId]
+	req.From = date
+	h.cancelRangeRequests[userId] = req
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CancelRangeCommand,
+		Action:  actions.UserActionInputRangeEnd,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть останню дату скасування у форматі "+dateLayout)}
+}
+
+func (h *Handler) handleActionInputRangeEnd(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	date, err := time.ParseInLocation(dateLayout, upd.Message.Text, time.Local)
+
+	req := h.cancelRangeRequests[userId]
+
+	if err != nil || date.Before(req.From) {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+	}
+
+	req.To = date
+	h.cancelRangeRequests[userId] = req
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CancelRangeCommand,
+		Action:  actions.UserActionChooseFilter,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+	keys.InlineKeyboard = append(keys.InlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Усі пари", AllLessonsCallbackId)))
+
+	var orders []int
+
+	for order := range h.cfg.ScheduleSettings.TimeSlotsConfiguration {
+		orders = append(orders, order)
+	}
+
+	sort.Ints(orders)
+
+	for _, order := range orders {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("Лише пара № %d", order), fmt.Sprintf("%s%d", orderCallbackPrefix, order))))
+	}
+
+	courses, err := h.scope(userId).Course.GetCourses()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	for _, course := range courses.Courses {
+		// optional slots are shared by several courses, they are cancelled with the order filter
+		if course.IsOptional {
+			continue
+		}
+
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Лише "+course.Name, course.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть, які пари скасувати")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseFilterForCancelRange(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+	req := h.cancelRangeRequests[userId]
+
+	switch data := query.CallbackQuery.Data; {
+	case data == AllLessonsCallbackId:
+	case strings.HasPrefix(data, orderCallbackPrefix):
+		order, err := strconv.Atoi(strings.TrimPrefix(data, orderCallbackPrefix))
+
+		if err != nil {
+			return tgbotapi.CallbackConfig{
+				CallbackQueryID: query.CallbackQuery.ID,
+				Text:            "Невірні дані, спробуйте ще раз",
+			}
+		}
+
+		req.Order = order
+	default:
+		req.CourseId = data
+	}
+
+	h.cancelRangeRequests[userId] = req
+
+	preview, err := h.scope(userId).Schedule.PreviewCancelRange(req)
+
+	if err != nil || len(preview.Lessons) == 0 {
+		delete(h.cancelRangeRequests, userId)
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+		text := "Немає пар для скасування"
+
+		if err == exceptions.ElectiveNotCancellable {
+			text = "Пари курсів за вибором скасовуються лише цілою парою, оберіть номер пари"
+		}
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            text,
+		}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CancelRangeCommand,
+		Action:  actions.UserActionConfirm,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Фільтр обрано",
+	}
+}
+
+func (h *Handler) prepareCancelRangePreviewMessage(chatId int64, userId int) tgbotapi.MessageConfig {
+	preview, err := h.scope(userId).Schedule.PreviewCancelRange(h.cancelRangeRequests[userId])
+
+	if err != nil {
+		return tgbotapi.NewMessage(chatId, "Під час запиту сталася помилка"+err.Error())
+	}
+
+	text := fmt.Sprintf("Буде скасовано пар: %d", len(preview.Lessons))
+
+	for i, lesson := range preview.Lessons {
+		line := formatCancelledLesson(lesson)
+
+		// the preview must fit into one message with the buttons
+		if len(text)+len(line) > 4000 {
+			text += fmt.Sprintf("\n ... та ще %d", len(preview.Lessons)-i)
+			break
+		}
+
+		text += line
+	}
+
+	msg := tgbotapi.NewMessage(chatId, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Підтвердити", ConfirmCallbackId),
+		tgbotapi.NewInlineKeyboardButtonData("Відмінити", RejectCallbackId)))
+	return msg
+}
+
+func (h *Handler) handleConfirmCancelRange(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	req := h.cancelRangeRequests[userId]
+	delete(h.cancelRangeRequests, userId)
+
+	if query.CallbackQuery.Data != ConfirmCallbackId {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Скасування відмінено",
+		}
+	}
+
+	var result *dto.CancelRangeResponse
+	scope := h.scope(userId)
+
+	err := h.trackScheduleChanges(scope, func() error {
+		var err error
+		result, err = scope.Schedule.CancelRange(req)
+		return err
+	})
+
+	if err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	parts := []string{"Увага! Наступні пари скасовано:"}
+
+	for _, lesson := range result.Lessons {
+		parts = append(parts, formatCancelledLesson(lesson))
+	}
+
+	h.broadcastChange(scope, cancelledLessonDates(result.Lessons), parts)
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Пари скасовано",
+	}
+}
+
+func formatCancelledLesson(lesson dto.CancelledLessonDto) string {
+	return fmt.Sprintf("\n %s (%s) № %d. %s",
+		lesson.Date.Format(dateLayout),
+		util.ConvertToHumanReadableWeek(lesson.Date.Weekday()),
+		lesson.Order,
+		lesson.CourseInfo.Name+formatSubgroup(lesson.Subgroup))
+}
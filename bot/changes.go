@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// trackScheduleChanges performs the edit and tells every member of the group whose lessons of the next days
+// it changed, the notifications are computed per user, so the subgroups and the chosen electives are respected
+func (h *Handler) trackScheduleChanges(scope abstractions.GroupScope, change func() error) error {
+	before, err := scope.Schedule.GetUpcomingSchedules(scope.MemberIds)
+
+	if err != nil {
+		return change()
+	}
+
+	if err = change(); err != nil {
+		return err
+	}
+
+	after, err := scope.Schedule.GetUpcomingSchedules(scope.MemberIds)
+
+	if err != nil {
+		return nil
+	}
+
+	for userId, changes := range scope.Schedule.DiffUpcomingSchedules(before, after) {
+		chatId, err := h.chats.GetChatByUserId(userId)
+
+		if err != nil {
+			continue
+		}
+
+		go h.api.sendDeferrable(priorityBulk, prepareLongMessages(chatId, h.formatLessonChanges(changes)))
+	}
+
+	return nil
+}
+
+// broadcastChange tells about the change of the lessons of the dates, the members hear about the next days
+// from trackScheduleChanges, so the whole group is told only when the change reaches the later dates
+func (h *Handler) broadcastChange(scope abstractions.GroupScope, dates []time.Time, parts []string) {
+	for _, date := range dates {
+		if !scope.Schedule.IsUpcomingDate(date) {
+			h.broadcastToScope(scope, broadcastReplacements, parts)
+			return
+		}
+	}
+
+	h.broadcastToTargets(scope, broadcastReplacements, parts)
+}
+
+func cancelledLessonDates(lessons []dto.CancelledLessonDto) []time.Time {
+	var dates []time.Time
+
+	for _, lesson := range lessons {
+		dates = append(dates, lesson.Date)
+	}
+
+	return dates
+}
+
+// getTeacherCourseScope returns the group of the teacher's course
+func (h *Handler) getTeacherCourseScope(userId int, courseId string) (abstractions.GroupScope, error) {
+	courses, err := h.teachers.GetTeacherCourses(userId)
+
+	if err != nil {
+		return abstractions.GroupScope{}, err
+	}
+
+	for _, course := range courses.Courses {
+		if course.CourseInfo.Id == courseId {
+			return h.groups.GetScope(course.GroupId)
+		}
+	}
+
+	return abstractions.GroupScope{}, exceptions.NotFound
+}
+
+func (h *Handler) formatLessonChanges(changes []dto.LessonChangeDto) []string {
+	parts := []string{"Зміни у вашому розкладі:\n"}
+
+	for _, change := range changes {
+		parts = append(parts, fmt.Sprintf("\n%s (%s) № %d, %s \n Було: %s \n Стало: %s \n",
+			change.Date.Format(dateLayout),
+			util.ConvertToHumanReadableWeek(change.Date.Weekday()),
+			change.Order,
+			h.api.formatLessonTime(change.Date, change.Order),
+			formatChangedLesson(change.Before, "пари не було"),
+			formatChangedLesson(change.After, "пару скасовано")))
+	}
+
+	return parts
+}
+
+func formatChangedLesson(lesson *dto.ScheduleDto, absent string) string {
+	if lesson == nil {
+		return absent
+	}
+
+	return fmt.Sprintf("%s, вчитель: %s, посилання на зустріч: %s",
+		lesson.CourseInfo.Name+formatSubgroup(lesson.Subgroup),
+		lesson.CourseInfo.TeacherName,
+		lesson.CourseInfo.MeetLink)
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+)
+
+func (h *Handler) handleCommandAddChannel(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.AddChannelCommand,
+		Action:  actions.UserActionInputChannel,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Додайте бота адміністратором каналу з правом публікації та введіть username каналу, наприклад @my_channel")}
+}
+
+func (h *Handler) handleActionInputChannel(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	channel, err := h.api.resolveChannel(strings.TrimSpace(upd.Message.Text))
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Канал не знайдено або бот не може в ньому публікувати, спробуйте ще раз")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	err = h.groups.AddPublicationTarget(dto.AddPublicationTargetRequest{
+		ChatId:  channel.ID,
+		Kind:    util.TargetKindChannel,
+		Title:   channel.Title,
+		GroupId: h.scope(userId).GroupId,
+	})
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Канал додано. Налаштування: /"+string(commands.ChannelSettingsCommand))}
+}
+
+func (h *Handler) handleCommandChooseChannel(userId int, upd tgbotapi.Update, command commands.CommandType) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	channels := h.getChannels(userId)
+
+	if len(channels) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Каналів немає, додайте канал командою /"+string(commands.AddChannelCommand))}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: command,
+		Action:  actions.UserActionChooseTarget,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, channel := range channels {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(channel.Title, strconv.FormatInt(channel.ChatId, 10))))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть канал")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseChannelForRemove(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	channel, err := h.getChannel(userId, query.CallbackQuery.Data)
+
+	if err == nil {
+		err = h.groups.RemovePublicationTarget(channel.ChatId)
+	}
+
+	if err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Канал видалено",
+	}
+}
+
+func (h *Handler) handleChooseChannelForSettings(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	channel, err := h.getChannel(userId, query.CallbackQuery.Data)
+
+	if err != nil {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	go h.api.executeMessage(h.prepareTargetSettingsMessage(userId, query.CallbackQuery.Message.Chat.ID, *channel))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+// getChannels returns the channels of the active group of the user
+func (h *Handler) getChannels(userId int) []dto.PublicationTargetDto {
+	targets, err := h.groups.GetPublicationTargets(h.scope(userId).GroupId)
+
+	if err != nil {
+		return nil
+	}
+
+	var channels []dto.PublicationTargetDto
+
+	for _, target := range targets {
+		if target.Kind == util.TargetKindChannel {
+			channels = append(channels, target)
+		}
+	}
+
+	return channels
+}
+
+func (h *Handler) getChannel(userId int, data string) (*dto.PublicationTargetDto, error) {
+	chatId, err := strconv.ParseInt(data, 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range h.getChannels(userId) {
+		if channel.ChatId == chatId {
+			return &channel, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
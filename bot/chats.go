@@ -0,0 +1,237 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+)
+
+const (
+	chatRemindersSettingId     = "reminders"
+	chatDailyScheduleSettingId = "daily_schedule"
+	chatReplacementsSettingId  = "replacements"
+	chatAnnouncementsSettingId = "announcements"
+)
+
+var chatSettingItems = []toggleItem{
+	{Id: chatRemindersSettingId, Name: "Нагадування про пари"},
+	{Id: chatDailyScheduleSettingId, Name: "Розклад на день"},
+	{Id: chatReplacementsSettingId, Name: "Заміни та скасування"},
+	{Id: chatAnnouncementsSettingId, Name: "Оголошення"},
+}
+
+// handleGroupChatMsg serves the commands sent in group chats, the multistep operations
+// are available only in the private chat with the bot
+func (h *Handler) handleGroupChatMsg(upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	if !upd.Message.IsCommand() {
+		return nil
+	}
+
+	command := upd.Message.CommandWithAt()
+
+	if i := strings.Index(command, "@"); i != -1 && !strings.EqualFold(command[i+1:], h.api.userName()) {
+		return nil
+	}
+
+	h.users.SaveUser(dao.UserModel{
+		Id:        upd.Message.From.ID,
+		UserName:  upd.Message.From.UserName,
+		FirstName: upd.Message.From.FirstName,
+		LastName:  upd.Message.From.LastName,
+	})
+	userId := upd.Message.From.ID
+
+	if authenticated := h.baseAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	if h.actions.GetUserCurrentState(userId).Action != actions.UserActionNone {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не закінчили минулу операцію")}
+	}
+
+	switch upd.Message.Command() {
+	case string(commands.GetScheduleCommonCommand):
+		return h.handleGetCommonSchedules(upd)
+	case string(commands.GetScheduleTodayCommand):
+		return h.handleGetScheduleAtToday(upd)
+	case string(commands.GetExamsCommand):
+		return h.handleGetExamsCommand(upd)
+	case string(commands.BindChatCommand):
+		return h.handleBindChatCommand(userId, upd)
+	case string(commands.UnbindChatCommand):
+		return h.handleUnbindChatCommand(userId, upd)
+	case string(commands.ChatSettingsCommand):
+		return h.handleCommandChatSettings(userId, upd)
+	default:
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Команда доступна лише в особистих повідомленнях з ботом")}
+	}
+}
+
+func (h *Handler) handleBindChatCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuthForChat(userId, upd.Message.Chat.ID); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Ви не маєте прав, команда доступна адміністраторам групи, які є адміністраторами цього чату")}
+	}
+
+	err := h.groups.AddPublicationTarget(dto.AddPublicationTargetRequest{
+		ChatId:  upd.Message.Chat.ID,
+		Kind:    util.TargetKindGroupChat,
+		Title:   upd.Message.Chat.Title,
+		GroupId: h.scope(userId).GroupId,
+	})
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Чат прив'язано до групи. Налаштування: /"+string(commands.ChatSettingsCommand))}
+}
+
+func (h *Handler) handleUnbindChatCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuthForChat(userId, upd.Message.Chat.ID); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Ви не маєте прав, команда доступна адміністраторам групи, які є адміністраторами цього чату")}
+	}
+
+	if err := h.groups.RemovePublicationTarget(upd.Message.Chat.ID); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Чат не прив'язано до групи")}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Чат відв'язано від групи")}
+}
+
+func (h *Handler) handleCommandChatSettings(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuthForChat(userId, upd.Message.Chat.ID); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Ви не маєте прав, команда доступна адміністраторам групи, які є адміністраторами цього чату")}
+	}
+
+	chat, err := h.groups.GetPublicationTarget(upd.Message.Chat.ID)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Чат не прив'язано до групи, використайте /"+string(commands.BindChatCommand))}
+	}
+
+	return []tgbotapi.MessageConfig{h.prepareTargetSettingsMessage(userId, upd.Message.Chat.ID, *chat)}
+}
+
+// prepareTargetSettingsMessage remembers the chat or channel being configured, the settings of a channel are changed
+// from the private chat
+func (h *Handler) prepareTargetSettingsMessage(userId int, chatId int64, target dto.PublicationTargetDto) tgbotapi.MessageConfig {
+	h.targetSettingsRequests[userId] = target.ChatId
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.ChatSettingsCommand,
+		Action:  actions.UserActionChooseTargetSettings,
+	})
+
+	msg := tgbotapi.NewMessage(chatId, "Оберіть, що публікувати в \""+target.Title+"\", та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareToggleKeyboard(chatSettingItems, getEnabledChatSettings(target))
+	return msg
+}
+
+func (h *Handler) handleToggleChatSetting(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	if query.CallbackQuery.Data == DoneCallbackId {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+		delete(h.targetSettingsRequests, userId)
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Налаштування збережено",
+		}
+	}
+
+	chat, err := h.groups.GetPublicationTarget(h.targetSettingsRequests[userId])
+
+	if err != nil {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+		delete(h.targetSettingsRequests, userId)
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Чат не прив'язано до групи",
+		}
+	}
+
+	req := dto.UpdatePublicationTargetRequest{
+		ChatId:        chat.ChatId,
+		Reminders:     chat.Reminders,
+		DailySchedule: chat.DailySchedule,
+		Replacements:  chat.Replacements,
+		Announcements: chat.Announcements,
+	}
+
+	switch query.CallbackQuery.Data {
+	case chatRemindersSettingId:
+		req.Reminders = !req.Reminders
+	case chatDailyScheduleSettingId:
+		req.DailySchedule = !req.DailySchedule
+	case chatReplacementsSettingId:
+		req.Replacements = !req.Replacements
+	case chatAnnouncementsSettingId:
+		req.Announcements = !req.Announcements
+	}
+
+	if err = h.groups.UpdatePublicationTarget(req); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	chat.Reminders, chat.DailySchedule = req.Reminders, req.DailySchedule
+	chat.Replacements, chat.Announcements = req.Replacements, req.Announcements
+
+	go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+		query.CallbackQuery.Message.Chat.ID,
+		query.CallbackQuery.Message.MessageID,
+		prepareToggleKeyboard(chatSettingItems, getEnabledChatSettings(*chat))))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+// adminAuthForChat allows the admins of the group which the chat is bound to, any admin for unbound chats.
+// The user must administer the telegram chat as well, so a group admin who is a member of someone else's chat
+// can not publish there
+func (h *Handler) adminAuthForChat(userId int, chatId int64) bool {
+	chat, err := h.groups.GetPublicationTarget(chatId)
+
+	if err == nil && chat.GroupId != h.scope(userId).GroupId {
+		return false
+	}
+
+	return h.adminAuth(userId) && h.api.isChatAdmin(chatId, userId)
+}
+
+func getEnabledChatSettings(chat dto.PublicationTargetDto) []string {
+	var enabled []string
+
+	if chat.Reminders {
+		enabled = append(enabled, chatRemindersSettingId)
+	}
+
+	if chat.DailySchedule {
+		enabled = append(enabled, chatDailyScheduleSettingId)
+	}
+
+	if chat.Replacements {
+		enabled = append(enabled, chatReplacementsSettingId)
+	}
+
+	if chat.Announcements {
+		enabled = append(enabled, chatAnnouncementsSettingId)
+	}
+
+	return enabled
+}
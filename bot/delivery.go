@@ -0,0 +1,176 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxDeliveryAttempts = 5
+	retryBaseDelay      = time.Second
+	retryMaxDelay       = 30 * time.Second
+)
+
+var errBotBlocked = errors.New("BotBlocked")
+
+type deliveryErrorKind int
+
+const (
+	deliveryErrorRetryable deliveryErrorKind = 1
+	deliveryErrorFloodWait deliveryErrorKind = 2
+	deliveryErrorPermanent deliveryErrorKind = 3
+	deliveryErrorBlocked   deliveryErrorKind = 4 // the recipient blocked the bot or the chat is gone
+)
+
+// deliveryMetrics counts the outcomes of the outgoing telegram calls since the start
+type deliveryMetrics struct {
+	sent       int64
+	retried    int64
+	floodWaits int64
+	failed     int64
+	blocked    int64
+	skipped    int64 // not sent because the recipient blocked the bot earlier
+	deferred   int64 // postponed until the end of the quiet hours
+	dropped    int64 // lesson reminders not sent during the quiet hours
+}
+
+func (m *deliveryMetrics) format() string {
+	return fmt.Sprintf("Надіслано: %d \n Повторних спроб: %d \n Очікувань через ліміт Telegram (429): %d \n "+
+		"Помилок без повтору: %d \n Заблокували бота: %d \n Пропущено заблокованих: %d \n "+
+		"Відкладено через тихі години: %d \n Не надіслано через тихі години: %d",
+		atomic.LoadInt64(&m.sent),
+		atomic.LoadInt64(&m.retried),
+		atomic.LoadInt64(&m.floodWaits),
+		atomic.LoadInt64(&m.failed),
+		atomic.LoadInt64(&m.blocked),
+		atomic.LoadInt64(&m.skipped),
+		atomic.LoadInt64(&m.deferred),
+		atomic.LoadInt64(&m.dropped))
+}
+
+func (h *Handler) handleDeliveryStatsCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.ownerAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Статистика надсилання з моменту запуску: \n "+h.api.metrics.format())}
+}
+
+// deliver performs the telegram call through the send queue with retries, transient errors are retried with
+// an exponential backoff, flood waits pause the chat in the queue for retry_after and the recipients who blocked
+// the bot are marked and skipped afterwards
+func (a *Api) deliver(priority sendPriority, chatId int64, call func() error) error {
+	if a.isBlocked(chatId) {
+		atomic.AddInt64(&a.metrics.skipped, 1)
+		return errBotBlocked
+	}
+
+	var err error
+
+	for attempt := 0; attempt < maxDeliveryAttempts; attempt++ {
+		if err = a.queue.do(priority, chatId, call); err == nil {
+			atomic.AddInt64(&a.metrics.sent, 1)
+			return nil
+		}
+
+		kind, wait := classifyDeliveryError(err)
+
+		switch kind {
+		case deliveryErrorBlocked:
+			atomic.AddInt64(&a.metrics.blocked, 1)
+			a.markBlocked(chatId)
+			return errBotBlocked
+		case deliveryErrorPermanent:
+			atomic.AddInt64(&a.metrics.failed, 1)
+			logrus.Warnf("Failed to deliver to the chat %d: %s", chatId, err)
+			return err
+		case deliveryErrorFloodWait:
+			// the queue holds the chat until retry_after passes, the next attempt waits there
+			atomic.AddInt64(&a.metrics.floodWaits, 1)
+			a.queue.pause(chatId, wait)
+			continue
+		default:
+			atomic.AddInt64(&a.metrics.retried, 1)
+			wait = retryDelay(attempt)
+		}
+
+		if attempt < maxDeliveryAttempts-1 {
+			time.Sleep(wait)
+		}
+	}
+
+	atomic.AddInt64(&a.metrics.failed, 1)
+	logrus.Warnf("Gave up delivering to the chat %d after %d attempts: %s", chatId, maxDeliveryAttempts, err)
+	return err
+}
+
+// isBlocked checks the private chats only, their ids are the ids of the users
+func (a *Api) isBlocked(chatId int64) bool {
+	if chatId <= 0 || a.users == nil {
+		return false
+	}
+
+	user, err := a.users.GetUserById(int(chatId))
+	return err == nil && user.BlockedBot
+}
+
+func (a *Api) markBlocked(chatId int64) {
+	if chatId <= 0 || a.users == nil {
+		return
+	}
+
+	if err := a.users.SetBlockedBot(int(chatId), true); err != nil {
+		logrus.Warnf("Failed to mark the user %d who blocked the bot: %s", chatId, err)
+	}
+}
+
+func classifyDeliveryError(err error) (deliveryErrorKind, time.Duration) {
+	var apiErr tgbotapi.Error
+
+	if !errors.As(err, &apiErr) {
+		// network errors and broken responses
+		return deliveryErrorRetryable, 0
+	}
+
+	if apiErr.RetryAfter > 0 {
+		return deliveryErrorFloodWait, time.Duration(apiErr.RetryAfter) * time.Second
+	}
+
+	message := strings.ToLower(apiErr.Message)
+
+	switch {
+	case strings.Contains(message, "bot was blocked"),
+		strings.Contains(message, "user is deactivated"),
+		strings.Contains(message, "bot was kicked"),
+		strings.Contains(message, "chat not found"):
+		return deliveryErrorBlocked, 0
+	case strings.Contains(message, "too many requests"):
+		return deliveryErrorFloodWait, retryBaseDelay
+	case strings.Contains(message, "internal server error"),
+		strings.Contains(message, "bad gateway"),
+		strings.Contains(message, "gateway timeout"),
+		strings.Contains(message, "service unavailable"):
+		return deliveryErrorRetryable, 0
+	default:
+		return deliveryErrorPermanent, 0
+	}
+}
+
+// retryDelay doubles the delay with every attempt, the jitter spreads the retries of simultaneous reminders
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"testing"
+	"time"
+)
+
+func TestClassifyDeliveryError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind deliveryErrorKind
+		wantWait time.Duration
+	}{
+		{
+			name:     "flood wait with retry_after",
+			err:      tgbotapi.Error{Message: "Too Many Requests: retry after 35", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 35}},
+			wantKind: deliveryErrorFloodWait,
+			wantWait: 35 * time.Second,
+		},
+		{
+			name:     "flood wait without retry_after",
+			err:      tgbotapi.Error{Message: "Too Many Requests: retry after 5"},
+			wantKind: deliveryErrorFloodWait,
+			wantWait: retryBaseDelay,
+		},
+		{
+			name:     "blocked by the user",
+			err:      tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"},
+			wantKind: deliveryErrorBlocked,
+		},
+		{
+			name:     "deactivated user",
+			err:      tgbotapi.Error{Message: "Forbidden: user is deactivated"},
+			wantKind: deliveryErrorBlocked,
+		},
+		{
+			name:     "kicked from the group",
+			err:      tgbotapi.Error{Message: "Forbidden: bot was kicked from the supergroup chat"},
+			wantKind: deliveryErrorBlocked,
+		},
+		{
+			name:     "deleted chat",
+			err:      tgbotapi.Error{Message: "Bad Request: chat not found"},
+			wantKind: deliveryErrorBlocked,
+		},
+		{
+			name:     "telegram is down",
+			err:      tgbotapi.Error{Message: "Internal Server Error"},
+			wantKind: deliveryErrorRetryable,
+		},
+		{
+			name:     "bad gateway",
+			err:      tgbotapi.Error{Message: "Bad Gateway"},
+			wantKind: deliveryErrorRetryable,
+		},
+		{
+			name:     "malformed message",
+			err:      tgbotapi.Error{Message: "Bad Request: message text is empty"},
+			wantKind: deliveryErrorPermanent,
+		},
+		{
+			name:     "network error",
+			err:      errors.New("Post \"https://api.telegram.org/bot/sendMessage\": dial tcp: i/o timeout"),
+			wantKind: deliveryErrorRetryable,
+		},
+		{
+			name:     "wrapped flood wait",
+			err:      fmt.Errorf("send: %w", tgbotapi.Error{Message: "Too Many Requests: retry after 3", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}),
+			wantKind: deliveryErrorFloodWait,
+			wantWait: 3 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, wait := classifyDeliveryError(tt.err)
+
+			if kind != tt.wantKind || wait != tt.wantWait {
+				t.Errorf("got %v %v, want %v %v", kind, wait, tt.wantKind, tt.wantWait)
+			}
+		})
+	}
+}
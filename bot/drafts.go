@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+func (h *Handler) handleGetDraftDiffCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	diff, err := h.scope(userId).Schedule.GetDraftDiff()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if isEmptyDiff(diff) {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Чернетка не відрізняється від опублікованого розкладу")}
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, append([]string{"Зміни в чернетці:"}, formatScheduleDiff(diff)...))
+}
+
+func (h *Handler) handlePublishDraftCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	var diff *dto.ScheduleDiffResponse
+	scope := h.scope(userId)
+
+	err := h.trackScheduleChanges(scope, func() error {
+		var err error
+		diff, err = scope.Schedule.PublishDraft()
+		return err
+	})
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if isEmptyDiff(diff) {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Розклад опубліковано, змін немає")}
+	}
+
+	parts := append([]string{"Розклад оновлено. Зміни:"}, formatScheduleDiff(diff)...)
+
+	// the changes of the weekly schedule concern everyone, the replacements of the next days reach only the affected members
+	if len(diff.AddedSchedules) > 0 || len(diff.RemovedSchedules) > 0 {
+		h.broadcastToScope(scope, broadcastReplacements, parts)
+	} else {
+		h.broadcastChange(scope, replacementDates(diff), parts)
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Розклад опубліковано")}
+}
+
+func (h *Handler) handleDiscardDraftCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	if err := h.scope(userId).Schedule.DiscardDraft(); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Зміни в чернетці скасовано")}
+}
+
+func replacementDates(diff *dto.ScheduleDiffResponse) []time.Time {
+	var dates []time.Time
+
+	for _, val := range append(diff.AddedReplacements, diff.RemovedReplacements...) {
+		dates = append(dates, val.Date)
+	}
+
+	return dates
+}
+
+func isEmptyDiff(diff *dto.ScheduleDiffResponse) bool {
+	return len(diff.AddedSchedules) == 0 &&
+		len(diff.RemovedSchedules) == 0 &&
+		len(diff.AddedReplacements) == 0 &&
+		len(diff.RemovedReplacements) == 0
+}
+
+func formatScheduleDiff(diff *dto.ScheduleDiffResponse) []string {
+	var parts []string
+
+	for _, val := range diff.AddedSchedules {
+		parts = append(parts, "\n + "+formatScheduleChange(val))
+	}
+
+	for _, val := range diff.RemovedSchedules {
+		parts = append(parts, "\n - "+formatScheduleChange(val))
+	}
+
+	for _, val := range diff.AddedReplacements {
+		parts = append(parts, "\n + Заміна "+formatReplacementChange(val))
+	}
+
+	for _, val := range diff.RemovedReplacements {
+		parts = append(parts, "\n - Заміна "+formatReplacementChange(val))
+	}
+
+	return parts
+}
+
+func formatScheduleChange(change dto.ScheduleChangeDto) string {
+	return fmt.Sprintf("%s, тиждень: %s, № %d. %s",
+		util.ConvertToHumanReadableWeek(change.Weekday),
+		util.ConvertToHumanReadableWeekOrder(change.WeekOrder),
+		change.Order,
+		change.CourseInfo.Name+formatSubgroup(change.Subgroup))
+}
+
+func formatReplacementChange(change dto.ReplacementChangeDto) string {
+	name := change.CourseInfo.Name
+
+	if change.IsEmpty {
+		name = "Пари не буде"
+	}
+
+	return fmt.Sprintf("%s (%s) № %d. %s",
+		change.Date.Format(dateLayout),
+		util.ConvertToHumanReadableWeek(change.Date.Weekday()),
+		change.Order,
+		name+formatSubgroup(change.Subgroup))
+}
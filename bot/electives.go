@@ -0,0 +1,583 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"time"
+)
+
+type toggleItem struct {
+	Id   string
+	Name string
+}
+
+func (h *Handler) handleCommandCreateElectivePool(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateElectivePoolCommand,
+		Action:  actions.UserActionInputPoolName,
+	})
+
+	h.createPoolRequests[userId] = dto.CreateElectivePoolRequest{}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть назву пулу курсів за вибором")}
+}
+
+func (h *Handler) handleActionInputPoolName(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	req := h.createPoolRequests[userId]
+	req.Name = upd.Message.Text
+	h.createPoolRequests[userId] = req
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateElectivePoolCommand,
+		Action:  actions.UserActionChooseCourse,
+	})
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть курси пулу та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareToggleKeyboard(h.getPoolCourseItems(userId), nil)
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleToggleCourseForPool(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+	req := h.createPoolRequests[userId]
+
+	if query.CallbackQuery.Data == DoneCallbackId {
+		if len(req.CourseIds) == 0 {
+			return tgbotapi.CallbackConfig{
+				CallbackQueryID: query.CallbackQuery.ID,
+				Text:            "Оберіть хоча б один курс",
+			}
+		}
+
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+			Command: commands.CreateElectivePoolCommand,
+			Action:  actions.UserActionChooseOptionalSlot,
+		})
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Курси обрано",
+		}
+	}
+
+	req.CourseIds = toggleId(req.CourseIds, query.CallbackQuery.Data)
+	h.createPoolRequests[userId] = req
+
+	go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+		query.CallbackQuery.Message.Chat.ID,
+		query.CallbackQuery.Message.MessageID,
+		prepareToggleKeyboard(h.getPoolCourseItems(userId), req.CourseIds)))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+func (h *Handler) preparePoolSlotsMessage(chatId int64, userId int) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatId, "Оберіть опціональні пари, до яких належить пул, та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareToggleKeyboard(h.getPoolSlotItems(userId), nil)
+	return msg
+}
+
+func (h *Handler) handleToggleSlotForPool(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+	req := h.createPoolRequests[userId]
+
+	if query.CallbackQuery.Data != DoneCallbackId {
+		req.ScheduleIds = toggleId(req.ScheduleIds, query.CallbackQuery.Data)
+		h.createPoolRequests[userId] = req
+
+		go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+			query.CallbackQuery.Message.Chat.ID,
+			query.CallbackQuery.Message.MessageID,
+			prepareToggleKeyboard(h.getPoolSlotItems(userId), req.ScheduleIds)))
+
+		return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+	}
+
+	if len(req.ScheduleIds) == 0 {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Оберіть хоча б одну пару",
+		}
+	}
+
+	delete(h.createPoolRequests, userId)
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if _, err := h.scope(userId).Electives.CreateElectivePool(req); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту: " + err.Error(),
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Пул створено",
+	}
+}
+
+func (h *Handler) handleCommandSetCourseCapacity(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	courses, err := h.scope(userId).Course.GetOptionalCourses()
+
+	if err != nil || len(courses.Courses) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Курсів за вибором немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.SetCourseCapacityCommand,
+		Action:  actions.UserActionChooseCourse,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, course := range courses.Courses {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(formatCapacity(course), course.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть курс")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseCourseForCapacity(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	h.setCapacityRequests[query.CallbackQuery.From.ID] = dto.SetCourseCapacityRequest{CourseId: query.CallbackQuery.Data}
+
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Command: commands.SetCourseCapacityCommand,
+		Action:  actions.UserActionInputCapacity,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Курс обрано",
+	}
+}
+
+func (h *Handler) handleActionInputCapacity(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	capacity, err := strconv.Atoi(strings.TrimSpace(upd.Message.Text))
+
+	if err != nil || capacity < 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви ввели невірні значення")}
+	}
+
+	req := h.setCapacityRequests[userId]
+	req.Capacity = capacity
+
+	delete(h.setCapacityRequests, userId)
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if err = h.scope(userId).Course.SetCourseCapacity(req); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Кількість місць оновлено")}
+}
+
+func (h *Handler) handleCommandOpenElectiveSelection(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.OpenElectiveSelectionCommand,
+		Action:  actions.UserActionInputDeadline,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Введіть кінцевий термін вибору курсів у форматі "+examTimeLayout)}
+}
+
+func (h *Handler) handleActionInputDeadline(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	deadline, err := time.ParseInLocation(examTimeLayout, upd.Message.Text, time.Local)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+	}
+
+	if err = h.scope(userId).Electives.OpenSelectionWindow(dto.OpenSelectionWindowRequest{Deadline: deadline}); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	h.broadcast(userId, broadcastAnnouncements, []string{fmt.Sprintf("Відкрито вибір курсів за вибором до %s. Обрати курси: /%s",
+		deadline.Format(examTimeLayout), commands.LinkOptionalCourseCommand)})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Вибір курсів відкрито")}
+}
+
+func (h *Handler) handleAutoAssignElectivesCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	resp, err := h.scope(userId).Electives.AutoAssignElectives(h.scope(userId).MemberIds)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	parts := []string{fmt.Sprintf("Розподілено автоматично: %d", len(resp.Assigned))}
+
+	for _, val := range resp.Assigned {
+		h.notifyAssignedStudent(val)
+
+		patchedTxt := fmt.Sprintf("\n %s - %s: %s", formatStudent(val.Student), formatSlotTime(val.Slot), val.CourseInfo.Name)
+		parts = append(parts, patchedTxt)
+	}
+
+	if len(resp.Unassigned) > 0 {
+		parts = append(parts, fmt.Sprintf("\n\n Немає вільних місць: %d", len(resp.Unassigned)))
+	}
+
+	for _, val := range resp.Unassigned {
+		patchedTxt := "\n " + formatStudent(val.Student)
+
+		for _, slot := range val.MissingSlots {
+			patchedTxt += "\n  " + formatSlotTime(slot)
+		}
+
+		parts = append(parts, patchedTxt)
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) notifyAssignedStudent(assigned dto.AssignedStudentDto) {
+	chatId, err := h.chats.GetChatByUserId(assigned.Student.UserId)
+
+	if err != nil {
+		return
+	}
+
+	go h.api.sendDeferrable(priorityAnswer, []tgbotapi.MessageConfig{tgbotapi.NewMessage(chatId, fmt.Sprintf(
+		"Вас записано на курс %s: %s",
+		assigned.CourseInfo.Name,
+		formatSlotTime(assigned.Slot)))})
+}
+
+func (h *Handler) handleCommandImportElectives(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.ImportElectivesCommand,
+		Action:  actions.UserActionUploadDocument,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Надішліть CSV файл з рядками: id або username студента, назва курсу")}
+}
+
+func (h *Handler) handleActionUploadElectivesCsv(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	if upd.Message.Document == nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Надішліть CSV файл")}
+	}
+
+	data, err := h.api.downloadFile(upd.Message.Document.FileID)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Не вдалося завантажити файл, спробуйте ще раз")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	resp, err := h.scope(userId).Electives.ImportElectivesCsv(data, h.scope(userId).MemberIds)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	for _, val := range resp.Applied {
+		h.notifyAssignedStudent(dto.AssignedStudentDto{Student: val.Student, Slot: val.Slot, CourseInfo: val.CourseInfo})
+	}
+
+	for _, val := range resp.Promoted {
+		h.notifyPromotedStudent(val)
+	}
+
+	parts := []string{fmt.Sprintf("Записів застосовано: %d, помилок: %d", len(resp.Applied), len(resp.Errors))}
+
+	for _, val := range resp.Applied {
+		parts = append(parts, fmt.Sprintf("\n Рядок %d: %s — %s, %s",
+			val.Line, val.Student.FullName, val.CourseInfo.Name, formatSlotTime(val.Slot)))
+	}
+
+	for _, val := range resp.Errors {
+		parts = append(parts, fmt.Sprintf("\n Рядок %d: %s", val.Line, val.Reason))
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+// notifyPromotedStudent tells the student that the seat from the waitlist is taken for them
+func (h *Handler) notifyPromotedStudent(promoted dto.PromotedStudentDto) {
+	chatId, err := h.chats.GetChatByUserId(promoted.UserId)
+
+	if err != nil {
+		return
+	}
+
+	go h.api.sendDeferrable(priorityAnswer, []tgbotapi.MessageConfig{tgbotapi.NewMessage(chatId, fmt.Sprintf(
+		"Звільнилося місце, вас записано на курс %s: %s",
+		promoted.CourseInfo.Name,
+		formatSlotTime(promoted.Slot)))})
+}
+
+func (h *Handler) handleGetElectivePoolsCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	pools, err := h.scope(userId).Electives.GetElectivePools()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if len(pools.Pools) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Пулів курсів за вибором немає")}
+	}
+
+	parts := []string{"Пули курсів за вибором"}
+
+	for _, pool := range pools.Pools {
+		patchedTxt := fmt.Sprintf("\n\n %s \n Курси:", pool.Name)
+
+		for _, course := range pool.Courses {
+			patchedTxt += "\n  " + course.Name
+		}
+
+		patchedTxt += "\n Пари:"
+
+		for _, slot := range pool.Slots {
+			patchedTxt += "\n  " + formatSlotTime(slot)
+		}
+
+		parts = append(parts, patchedTxt)
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleCommandDeleteElectivePool(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	pools, err := h.scope(userId).Electives.GetElectivePools()
+
+	if err != nil || len(pools.Pools) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Пулів курсів за вибором немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.DeleteElectivePoolCommand,
+		Action:  actions.UserActionChoosePool,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, pool := range pools.Pools {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(pool.Name, pool.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть пул")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChoosePoolForDelete(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if err := h.scope(query.CallbackQuery.From.ID).Electives.DeleteElectivePool(dto.DeleteElectivePoolRequest{PoolId: query.CallbackQuery.Data}); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Пул видалено",
+	}
+}
+
+func (h *Handler) handleGetElectiveRosterCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	roster, err := h.scope(userId).Electives.GetElectiveRoster(h.scope(userId).MemberIds)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	parts := []string{"Записи на курси за вибором. Файл: /" + string(commands.ExportElectiveRosterCommand)}
+
+	for _, course := range roster.Courses {
+		patchedTxt := fmt.Sprintf("\n\n %s: %d", course.CourseInfo.Name, course.Count)
+
+		for _, slot := range course.Slots {
+			patchedTxt += fmt.Sprintf("\n %s: %d", formatSlotTime(slot.Slot), len(slot.Students))
+
+			if course.CourseInfo.Capacity > 0 {
+				patchedTxt += fmt.Sprintf("/%d", course.CourseInfo.Capacity)
+			}
+
+			for _, student := range slot.Students {
+				patchedTxt += "\n  " + formatStudent(student)
+			}
+
+			if len(slot.Waitlisted) > 0 {
+				patchedTxt += "\n  Черга:"
+			}
+
+			for i, student := range slot.Waitlisted {
+				patchedTxt += fmt.Sprintf("\n   %d. %s", i+1, formatStudent(student))
+			}
+		}
+
+		parts = append(parts, patchedTxt)
+	}
+
+	if len(roster.Pending) > 0 {
+		parts = append(parts, fmt.Sprintf("\n\n Ще не обрали: %d", len(roster.Pending)))
+	}
+
+	for _, val := range roster.Pending {
+		patchedTxt := "\n " + formatStudent(val.Student)
+
+		for _, slot := range val.MissingSlots {
+			patchedTxt += "\n  " + formatSlotTime(slot)
+		}
+
+		parts = append(parts, patchedTxt)
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleExportElectiveRosterCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	data, err := h.scope(userId).Electives.ExportElectiveRosterCsv(h.scope(userId).MemberIds)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	go h.api.executeDocument(tgbotapi.NewDocumentUpload(upd.Message.Chat.ID, tgbotapi.FileBytes{
+		Name:  "elective_roster.csv",
+		Bytes: data,
+	}))
+
+	return nil
+}
+
+func (h *Handler) getPoolCourseItems(userId int) []toggleItem {
+	courses, _ := h.scope(userId).Course.GetOptionalCourses()
+
+	var items []toggleItem
+
+	for _, course := range courses.Courses {
+		items = append(items, toggleItem{Id: course.Id, Name: course.Name})
+	}
+
+	return items
+}
+
+func (h *Handler) getPoolSlotItems(userId int) []toggleItem {
+	slots, _ := h.scope(userId).Schedule.GetOptionalSlots(userId)
+
+	var items []toggleItem
+
+	for _, slot := range slots.Slots {
+		items = append(items, toggleItem{Id: slot.ScheduleId, Name: formatSlotTime(slot)})
+	}
+
+	return items
+}
+
+// prepareToggleKeyboard renders a multiple choice keyboard, the choice is finished by the done button
+func prepareToggleKeyboard(items []toggleItem, selected []string) tgbotapi.InlineKeyboardMarkup {
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, item := range items {
+		name := item.Name
+
+		for _, id := range selected {
+			if id == item.Id {
+				name = "✅ " + name
+			}
+		}
+
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(name, item.Id)))
+	}
+
+	keys.InlineKeyboard = append(keys.InlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Готово", DoneCallbackId)))
+
+	return keys
+}
+
+func toggleId(ids []string, id string) []string {
+	var result []string
+
+	for _, val := range ids {
+		if val != id {
+			result = append(result, val)
+		}
+	}
+
+	if len(result) == len(ids) {
+		result = append(result, id)
+	}
+
+	return result
+}
+
+func formatCapacity(course dto.CourseDto) string {
+	if course.Capacity == 0 {
+		return course.Name
+	}
+
+	return fmt.Sprintf("%s (%d місць)", course.Name, course.Capacity)
+}
+
+func formatStudent(student dto.StudentDto) string {
+	if student.UserName == "" {
+		return student.FullName
+	}
+
+	return fmt.Sprintf("%s (@%s)", student.FullName, student.UserName)
+}
@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+const examTimeLayout = "2006-01-02 15:04"
+
+func (h *Handler) handleCommandCreateExam(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateExamCommand,
+		Action:  actions.UserActionChooseCourse,
+	})
+
+	h.createExamRequests[userId] = dto.CreateNewExamRequest{}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть предмет")
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+	courses, _ := h.scope(userId).Course.GetCourses()
+
+	for _, course := range courses.Courses {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(course.Name, course.Id)))
+	}
+
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleCommandUpdateExam(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.UpdateExamCommand,
+		Action:  actions.UserActionChooseExam,
+	})
+
+	h.updateExamRequests[userId] = dto.UpdateExamRequest{}
+
+	return []tgbotapi.MessageConfig{h.prepareChooseExamMessage(upd.Message.Chat.ID, userId)}
+}
+
+func (h *Handler) handleCommandDeleteExam(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.DeleteExamCommand,
+		Action:  actions.UserActionChooseExam,
+	})
+
+	return []tgbotapi.MessageConfig{h.prepareChooseExamMessage(upd.Message.Chat.ID, userId)}
+}
+
+func (h *Handler) handleGetExamsCommand(upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	exams, err := h.scope(upd.Message.From.ID).Exams.GetUpcomingExams()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if len(exams.Exams) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Найближчих іспитів немає")}
+	}
+
+	var res []tgbotapi.MessageConfig
+	text := "Сесія"
+	res = append(res, tgbotapi.NewMessage(upd.Message.Chat.ID, text))
+	text = ""
+	for _, val := range exams.Exams {
+		patchedTxt := fmt.Sprintf("\n %s: %s \n Час: %s \n Аудиторія: %s \n Вчитель: %s \n До початку: %s \n",
+			util.ConvertToHumanReadableExamKind(val.Kind),
+			val.CourseInfo.Name,
+			val.StartTime.Format(examTimeLayout),
+			val.Room,
+			val.CourseInfo.TeacherName,
+			util.ConvertToHumanReadableCountdown(time.Until(val.StartTime)))
+		if len(text)+len(patchedTxt) > 4096 {
+			res = append(res, tgbotapi.NewMessage(upd.Message.Chat.ID, text))
+			text = ""
+		}
+		text += patchedTxt
+	}
+
+	if text != "" {
+		res = append(res, tgbotapi.NewMessage(upd.Message.Chat.ID, text))
+	}
+
+	return res
+}
+
+func (h *Handler) handleChooseCourseForExam(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	req := h.createExamRequests[query.CallbackQuery.From.ID]
+	req.CourseId = query.CallbackQuery.Data
+	h.createExamRequests[query.CallbackQuery.From.ID] = req
+
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Command: commands.CreateExamCommand,
+		Action:  actions.UserActionInputExamKind,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Предмет обрано",
+	}
+}
+
+func (h *Handler) handleChooseExamForUpdate(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	info, err := h.scope(query.CallbackQuery.From.ID).Exams.GetExamById(query.CallbackQuery.Data)
+
+	if err != nil {
+		h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{Action: actions.UserActionNone})
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Іспит не знайдено",
+		}
+	}
+
+	h.updateExamRequests[query.CallbackQuery.From.ID] = dto.UpdateExamRequest{
+		Id:        info.Id,
+		CourseId:  info.CourseInfo.Id,
+		Kind:      info.Kind,
+		StartTime: info.StartTime,
+		Room:      info.Room,
+	}
+
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Command: commands.UpdateExamCommand,
+		Action:  actions.UserActionInputExamKind,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Іспит для оновлення обрано!",
+	}
+}
+
+func (h *Handler) handleChooseExamForDelete(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Action: actions.UserActionNone,
+	})
+
+	if err := h.scope(query.CallbackQuery.From.ID).Exams.DeleteExam(dto.DeleteExamRequest{ExamId: query.CallbackQuery.Data}); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Іспит видалено",
+	}
+}
+
+func (h *Handler) handleActionInputExamKind(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	isUpdate := action.Command == commands.UpdateExamCommand
+
+	if !isUpdate || upd.Message.Text != "Без змін" {
+		kind, err := util.ConvertFromHumanReadableExamKind(upd.Message.Text)
+
+		if err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+		}
+
+		if isUpdate {
+			req := h.updateExamRequests[userId]
+			req.Kind = kind
+			h.updateExamRequests[userId] = req
+		} else {
+			req := h.createExamRequests[userId]
+			req.Kind = kind
+			h.createExamRequests[userId] = req
+		}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Command: action.Command, Action: actions.UserActionInputExamTime})
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть дату та час у форматі "+examTimeLayout)
+	msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+
+	if isUpdate {
+		msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Без змін")))
+	}
+
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleActionInputExamTime(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	isUpdate := action.Command == commands.UpdateExamCommand
+
+	if !isUpdate || upd.Message.Text != "Без змін" {
+		startTime, err := time.ParseInLocation(examTimeLayout, upd.Message.Text, time.Local)
+
+		if err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+		}
+
+		if isUpdate {
+			req := h.updateExamRequests[userId]
+			req.StartTime = startTime
+			h.updateExamRequests[userId] = req
+		} else {
+			req := h.createExamRequests[userId]
+			req.StartTime = startTime
+			h.createExamRequests[userId] = req
+		}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Command: action.Command, Action: actions.UserActionInputExamRoom})
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть аудиторію")
+	msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+
+	if isUpdate {
+		msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Без змін")))
+	}
+
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleActionInputExamRoom(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	var err error
+
+	if action.Command == commands.UpdateExamCommand {
+		req := h.updateExamRequests[userId]
+		delete(h.updateExamRequests, userId)
+
+		if upd.Message.Text != "Без змін" {
+			req.Room = upd.Message.Text
+		}
+
+		err = h.scope(userId).Exams.UpdateExam(req)
+	} else {
+		req := h.createExamRequests[userId]
+		delete(h.createExamRequests, userId)
+
+		req.Room = upd.Message.Text
+
+		_, err = h.scope(userId).Exams.CreateNewExam(req)
+	}
+
+	if err != nil {
+		msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час виконання запиту трапилась помилка "+err.Error())
+		msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+		return []tgbotapi.MessageConfig{msg}
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Іспит було збережено")
+	msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) prepareInputExamKindMessage(chatId int64, command commands.CommandType) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatId, "Оберіть тип контролю")
+
+	row := tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Іспит"), tgbotapi.NewKeyboardButton("Залік"))
+
+	if command == commands.UpdateExamCommand {
+		row = append(row, tgbotapi.NewKeyboardButton("Без змін"))
+	}
+
+	msg.ReplyMarkup = tgbotapi.NewReplyKeyboard(row)
+	return msg
+}
+
+func (h *Handler) prepareChooseExamMessage(chatId int64, userId int) tgbotapi.MessageConfig {
+	exams, _ := h.scope(userId).Exams.GetUpcomingExams()
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, val := range exams.Exams {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(
+					fmt.Sprintf("%s %s, %s", util.ConvertToHumanReadableExamKind(val.Kind), val.CourseInfo.Name, val.StartTime.Format(examTimeLayout)),
+					val.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(chatId, "Оберіть іспит")
+	msg.ReplyMarkup = keys
+	return msg
+}
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+)
+
+func (h *Handler) handleCommandCreateGroup(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.ownerAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateGroupCommand,
+		Action:  actions.UserActionInputGroupName,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть назву групи")}
+}
+
+func (h *Handler) handleActionInputGroupName(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if _, err := h.groups.CreateGroup(dto.CreateGroupRequest{Name: upd.Message.Text, OwnerId: userId}); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Групу створено та обрано, додайте учасників командою /"+string(commands.AddGroupMemberCommand))}
+}
+
+func (h *Handler) handleGetGroupsCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	groups, err := h.groups.GetUserGroups(userId)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	parts := []string{"Ваші групи. Змінити групу: /" + string(commands.SwitchGroupCommand) + "\n"}
+
+	for _, group := range groups.Groups {
+		parts = append(parts, "\n "+formatGroup(group))
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleCommandSwitchGroup(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	groups, err := h.groups.GetUserGroups(userId)
+
+	if err != nil || len(groups.Groups) < 2 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Інших груп немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.SwitchGroupCommand,
+		Action:  actions.UserActionChooseGroup,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, group := range groups.Groups {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(formatGroup(group), group.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть групу")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseGroupForSwitch(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if err := h.groups.SelectGroup(dto.SelectGroupRequest{UserId: userId, GroupId: query.CallbackQuery.Data}); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Групу обрано",
+	}
+}
+
+func (h *Handler) handleCommandGroupMember(userId int, upd tgbotapi.Update, command commands.CommandType) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: command,
+		Action:  actions.UserActionInputGroupMember,
+	})
+
+	text := "Введіть id або username учасника"
+
+	if command == commands.AddGroupMemberCommand {
+		text += ", додайте \"admin\" через пробіл, щоб надати права адміністратора"
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, text)}
+}
+
+func (h *Handler) handleActionInputGroupMember(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	fields := strings.Fields(upd.Message.Text)
+
+	if len(fields) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви ввели невірні значення")}
+	}
+
+	memberId, err := strconv.Atoi(fields[0])
+
+	if err != nil {
+		user, err := h.users.GetUserByUserName(fields[0])
+
+		if err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+				"Користувача не знайдено, він має спочатку написати боту, або введіть його id")}
+		}
+
+		memberId = user.Id
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	req := dto.GroupMemberRequest{
+		GroupId: h.scope(userId).GroupId,
+		UserId:  memberId,
+		IsAdmin: len(fields) > 1 && strings.EqualFold(fields[1], "admin"),
+	}
+
+	if action.Command == commands.RemoveGroupMemberCommand {
+		if err = h.groups.RemoveGroupMember(req); err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+		}
+
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Учасника видалено")}
+	}
+
+	if err = h.groups.AddGroupMember(req); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Учасника додано")}
+}
+
+func formatGroup(group dto.GroupDto) string {
+	text := fmt.Sprintf("%s (%d)", group.Name, group.MembersCount)
+
+	if group.IsAdmin {
+		text += ", адміністратор"
+	}
+
+	if group.IsActive {
+		text = "✅ " + text
+	}
+
+	return text
+}
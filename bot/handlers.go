@@ -11,23 +11,37 @@ import (
 	"telegram-notification-bot-core/actions"
 	"telegram-notification-bot-core/commands"
 	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
 	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
 	"telegram-notification-bot-core/util"
 	"time"
 )
 
 type Handler struct {
-	course   abstractions.ICourseService
-	schedule abstractions.IScheduleService
-	actions  abstractions.IActionService
-	chats    abstractions.IChatProvider
-	cfg      configuration.Configuration
+	actions     abstractions.IActionService
+	groups      abstractions.IGroupService
+	teachers    abstractions.ITeacherService
+	preferences abstractions.IPreferencesService
+	chats       abstractions.IChatProvider
+	users       abstractions.IUserProvider
+	cfg         configuration.Configuration
 
 	createCourseRequests       map[int]dto.CreateNewCourseRequest
 	createScheduleRequests     map[int]dto.CreateNewScheduleRequest
 	createAddScheduleRequests  map[int]dto.CreateNewAdditionalScheduleRequest
 	updateCourseRequests       map[int]dto.UpdateCourseInfoRequest
 	linkOptionalCourseRequests map[int]dto.LinkOptionalCourseToUserRequest
+	createExamRequests         map[int]dto.CreateNewExamRequest
+	updateExamRequests         map[int]dto.UpdateExamRequest
+	cancelRangeRequests        map[int]dto.CancelRangeRequest
+	cloneSnapshotRequests      map[int]dto.CloneScheduleSnapshotRequest
+	createPoolRequests         map[int]dto.CreateElectivePoolRequest
+	setCapacityRequests        map[int]dto.SetCourseCapacityRequest
+	targetSettingsRequests     map[int]int64
+	teacherMeetLinkRequests    map[int]dto.UpdateTeacherMeetLinkRequest
+	quietHoursRequests         map[int]int64
+	digestTimeRequests         map[int]util.DigestKind
 	calendarPosition           map[int]dto.CalendarPositionDto
 
 	api *Api
@@ -36,21 +50,31 @@ type Handler struct {
 var (
 	EmptyCourseCallbackDataId = uuid.NewString()
 	OptionalCourseCallbackId  = uuid.NewString()
+	AllLessonsCallbackId      = uuid.NewString()
+	ConfirmCallbackId         = uuid.NewString()
+	RejectCallbackId          = uuid.NewString()
+	DoneCallbackId            = uuid.NewString()
 )
 
+const orderCallbackPrefix = "order:"
+
 func NewHandler(
-	course abstractions.ICourseService,
 	actions abstractions.IActionService,
-	schedules abstractions.IScheduleService,
+	groups abstractions.IGroupService,
+	teachers abstractions.ITeacherService,
+	preferences abstractions.IPreferencesService,
 	chats abstractions.IChatProvider,
+	users abstractions.IUserProvider,
 	cfg configuration.Configuration, api *Api) *Handler {
 
 	return &Handler{
 		actions:                    actions,
+		groups:                     groups,
+		teachers:                   teachers,
+		preferences:                preferences,
 		cfg:                        cfg,
-		course:                     course,
-		schedule:                   schedules,
 		chats:                      chats,
+		users:                      users,
 		api:                        api,
 		createCourseRequests:       map[int]dto.CreateNewCourseRequest{},
 		updateCourseRequests:       map[int]dto.UpdateCourseInfoRequest{},
@@ -58,6 +82,16 @@ func NewHandler(
 		createScheduleRequests:     map[int]dto.CreateNewScheduleRequest{},
 		createAddScheduleRequests:  map[int]dto.CreateNewAdditionalScheduleRequest{},
 		linkOptionalCourseRequests: map[int]dto.LinkOptionalCourseToUserRequest{},
+		createExamRequests:         map[int]dto.CreateNewExamRequest{},
+		updateExamRequests:         map[int]dto.UpdateExamRequest{},
+		cancelRangeRequests:        map[int]dto.CancelRangeRequest{},
+		cloneSnapshotRequests:      map[int]dto.CloneScheduleSnapshotRequest{},
+		createPoolRequests:         map[int]dto.CreateElectivePoolRequest{},
+		setCapacityRequests:        map[int]dto.SetCourseCapacityRequest{},
+		targetSettingsRequests:     map[int]int64{},
+		teacherMeetLinkRequests:    map[int]dto.UpdateTeacherMeetLinkRequest{},
+		quietHoursRequests:         map[int]int64{},
+		digestTimeRequests:         map[int]util.DigestKind{},
 	}
 
 }
@@ -87,6 +121,10 @@ func (h *Handler) Run(ctx context.Context) {
 						return
 					}
 
+					if handled := h.handleMuteButton(update); handled {
+						return
+					}
+
 					answer := h.handleCallback(update)
 					go h.api.executeCallback(answer)
 
@@ -120,6 +158,74 @@ func (h *Handler) handleCallback(query tgbotapi.Update) tgbotapi.CallbackConfig
 			return h.handleChooseCourseForDelete(query)
 		case commands.LinkOptionalCourseCommand:
 			return h.handleChooseCourseForLink(query)
+		case commands.CreateExamCommand:
+			return h.handleChooseCourseForExam(query)
+		case commands.CreateElectivePoolCommand:
+			return h.handleToggleCourseForPool(query)
+		case commands.SetCourseCapacityCommand:
+			return h.handleChooseCourseForCapacity(query)
+		case commands.SetMeetLinkCommand:
+			return h.handleChooseCourseForMeetLink(query)
+		}
+	case actions.UserActionChooseExam:
+
+		switch action.Command {
+		case commands.UpdateExamCommand:
+			return h.handleChooseExamForUpdate(query)
+		case commands.DeleteExamCommand:
+			return h.handleChooseExamForDelete(query)
+		}
+	case actions.UserActionChooseOptionalSlot:
+
+		switch action.Command {
+		case commands.LinkOptionalCourseCommand:
+			return h.handleChooseSlotForLink(query)
+		case commands.CreateElectivePoolCommand:
+			return h.handleToggleSlotForPool(query)
+		}
+	case actions.UserActionChoosePool:
+		return h.handleChoosePoolForDelete(query)
+	case actions.UserActionChooseGroup:
+		return h.handleChooseGroupForSwitch(query)
+	case actions.UserActionChooseTargetSettings:
+		return h.handleToggleChatSetting(query)
+	case actions.UserActionChooseTarget:
+
+		switch action.Command {
+		case commands.RemoveChannelCommand:
+			return h.handleChooseChannelForRemove(query)
+		case commands.ChannelSettingsCommand:
+			return h.handleChooseChannelForSettings(query)
+		case commands.QuietHoursCommand:
+			return h.handleChooseTargetForQuietHours(query)
+		}
+	case actions.UserActionChooseClass:
+		return h.handleChooseClassForCancel(query)
+	case actions.UserActionChooseReminders:
+		return h.handleChooseReminders(query)
+	case actions.UserActionChooseQuietHours:
+		return h.handleChooseQuietHours(query)
+	case actions.UserActionChooseDigest:
+		return h.handleChooseDigest(query)
+	case actions.UserActionChooseMutes:
+		return h.handleToggleMute(query)
+	case actions.UserActionChooseFilter:
+		return h.handleChooseFilterForCancelRange(query)
+	case actions.UserActionConfirm:
+
+		switch action.Command {
+		case commands.CancelRangeCommand:
+			return h.handleConfirmCancelRange(query)
+		case commands.CloneScheduleSnapshotCommand:
+			return h.handleConfirmCloneSnapshot(query)
+		}
+	case actions.UserActionChooseSnapshot:
+
+		switch action.Command {
+		case commands.CloneScheduleSnapshotCommand:
+			return h.handleChooseSnapshotForClone(query)
+		case commands.DeleteScheduleSnapshotCommand:
+			return h.handleChooseSnapshotForDelete(query)
 		}
 	}
 	return tgbotapi.CallbackConfig{}
@@ -127,10 +233,20 @@ func (h *Handler) handleCallback(query tgbotapi.Update) tgbotapi.CallbackConfig
 
 func (h *Handler) handleMsg(upd tgbotapi.Update) []tgbotapi.MessageConfig {
 
+	if !upd.Message.Chat.IsPrivate() {
+		return h.handleGroupChatMsg(upd)
+	}
+
 	h.chats.SaveChatForUser(upd.Message.From.ID, upd.Message.Chat.ID)
+	h.users.SaveUser(dao.UserModel{
+		Id:        upd.Message.From.ID,
+		UserName:  upd.Message.From.UserName,
+		FirstName: upd.Message.From.FirstName,
+		LastName:  upd.Message.From.LastName,
+	})
 	userId := upd.Message.From.ID
 
-	if authenticated := h.baseAuth(userId); !authenticated {
+	if authenticated := h.baseAuth(userId) || h.teacherAuth(userId); !authenticated {
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
 	}
 
@@ -205,6 +321,75 @@ func (h *Handler) handlePreparingActionsAfterCallback(update tgbotapi.Update) {
 		go h.api.executeMessage(msg)
 	}
 
+	if action.Action == actions.UserActionChooseCourse && action.Command == commands.LinkOptionalCourseCommand {
+		courses, err := h.scope(update.CallbackQuery.From.ID).Electives.GetAllowedCourses(h.linkOptionalCourseRequests[update.CallbackQuery.From.ID].ScheduleId)
+
+		if err != nil {
+			courses = &dto.GetCoursesResponse{}
+		}
+
+		msg := tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Виберіть курс: ")
+		reply := tgbotapi.NewInlineKeyboardMarkup()
+
+		for _, info := range courses.Courses {
+			reply.InlineKeyboard = append(reply.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(info.Name, info.Id)))
+		}
+
+		msg.ReplyMarkup = reply
+		go h.api.executeMessage(msg)
+	}
+
+	if action.Action == actions.UserActionInputMeetLink && action.Command == commands.SetMeetLinkCommand {
+		go h.api.executeMessage(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Введіть нове посилання на зустріч"))
+	}
+
+	if action.Action == actions.UserActionInputReminderOffsets {
+		go h.api.executeMessage(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID,
+			"Введіть через кому, за скільки хвилин до пари нагадувати, 0 - на початку пари, наприклад: 30, 5"))
+	}
+
+	if action.Action == actions.UserActionInputQuietHours {
+		go h.api.executeMessage(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID,
+			"Введіть початок і кінець тихих годин, наприклад: 22:00-08:00"))
+	}
+
+	if action.Action == actions.UserActionInputMutedUntil {
+		go h.api.executeMessage(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID,
+			"Введіть дату та час, до яких не турбувати, у форматі 2024-01-31 08:00"))
+	}
+
+	if action.Action == actions.UserActionInputDigestTime {
+		go h.api.executeMessage(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID,
+			"Введіть час, наприклад: 07:30"))
+	}
+
+	if action.Action == actions.UserActionInputCapacity {
+		msg := tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Введіть кількість місць на кожній парі, 0 - без обмежень")
+		go h.api.executeMessage(msg)
+	}
+
+	if action.Action == actions.UserActionChooseOptionalSlot && action.Command == commands.CreateElectivePoolCommand {
+		go h.api.executeMessage(h.preparePoolSlotsMessage(update.CallbackQuery.Message.Chat.ID, update.CallbackQuery.From.ID))
+	}
+
+	if action.Action == actions.UserActionInputExamKind {
+		go h.api.executeMessage(h.prepareInputExamKindMessage(update.CallbackQuery.Message.Chat.ID, action.Command))
+	}
+
+	if action.Action == actions.UserActionConfirm && action.Command == commands.CancelRangeCommand {
+		go h.api.executeMessage(h.prepareCancelRangePreviewMessage(update.CallbackQuery.Message.Chat.ID, update.CallbackQuery.From.ID))
+	}
+
+	if action.Action == actions.UserActionConfirm && action.Command == commands.CloneScheduleSnapshotCommand {
+		msg := tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID,
+			"Поточний розклад, заміни та вибір опціональних курсів буде замінено. Продовжити?")
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Підтвердити", ConfirmCallbackId),
+			tgbotapi.NewInlineKeyboardButtonData("Відмінити", RejectCallbackId)))
+		go h.api.executeMessage(msg)
+	}
+
 	if action.Action == actions.UserActionInputOrder && action.Command == commands.CreateAdditionalScheduleCommand {
 		msg := tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Виберіть, коли буде пара")
 		markup := tgbotapi.NewReplyKeyboard()
@@ -237,7 +422,7 @@ func (h *Handler) handleInputDate(query tgbotapi.Update, userId int) tgbotapi.Ca
 
 	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
 
-	err = h.schedule.InsertAdditionalSchedule(req)
+	err = h.scope(userId).Schedule.InsertAdditionalSchedule(req)
 
 	if err != nil {
 		return tgbotapi.CallbackConfig{
@@ -248,7 +433,7 @@ func (h *Handler) handleInputDate(query tgbotapi.Update, userId int) tgbotapi.Ca
 
 	return tgbotapi.CallbackConfig{
 		CallbackQueryID: query.CallbackQuery.ID,
-		Text:            "Заміну успішно додано",
+		Text:            "Заміну додано до чернетки, опублікуйте зміни командою /publish",
 	}
 }
 
@@ -303,12 +488,13 @@ func (h *Handler) handleChooseCourseForUpdate(query tgbotapi.Update) tgbotapi.Ca
 	req := h.updateCourseRequests[query.CallbackQuery.From.ID]
 	req.Id = query.CallbackQuery.Data
 
-	info, _ := h.course.GetCourseById(req.Id)
+	info, _ := h.scope(query.CallbackQuery.From.ID).Course.GetCourseById(req.Id)
 	req.Name = info.Name
 	req.MeetLink = info.MeetLink
 	req.TeacherName = info.TeacherName
 	req.TeacherContact = info.TeacherContact
 	req.IsOptional = info.IsOptional
+	req.Capacity = info.Capacity
 
 	h.updateCourseRequests[query.CallbackQuery.From.ID] = req
 
@@ -328,7 +514,7 @@ func (h *Handler) handleChooseCourseForDelete(query tgbotapi.Update) tgbotapi.Ca
 		Action: actions.UserActionNone,
 	})
 
-	h.course.DeleteCourse(dto.ArchiveCourseRequest{
+	h.scope(query.CallbackQuery.From.ID).Course.DeleteCourse(dto.ArchiveCourseRequest{
 		CourseId: query.CallbackQuery.Data,
 	})
 
@@ -348,13 +534,47 @@ func (h *Handler) handleChooseCourseForLink(query tgbotapi.Update) tgbotapi.Call
 	req.CourseId = query.CallbackQuery.Data
 	delete(h.linkOptionalCourseRequests, query.CallbackQuery.From.ID)
 
-	if err := h.schedule.LinkOptionalCourseToUser(req); err != nil {
+	resp, err := h.scope(query.CallbackQuery.From.ID).Electives.LinkOptionalCourseToUser(req)
+
+	if err == exceptions.CourseIsFull {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "На курсі немає вільних місць",
+		}
+	}
+
+	if err == exceptions.SelectionLocked {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Вибір курсів закрито, зверніться до адміністратора",
+		}
+	}
+
+	if err == exceptions.SubgroupMismatch {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Ця пара призначена для іншої підгрупи",
+		}
+	}
+
+	if err != nil {
 		return tgbotapi.CallbackConfig{
 			CallbackQueryID: query.CallbackQuery.ID,
 			Text:            "Помилка при зв'язці: " + err.Error(),
 		}
 	}
 
+	for _, val := range resp.Promoted {
+		h.notifyPromotedStudent(val)
+	}
+
+	if resp.IsWaitlisted {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            fmt.Sprintf("Вільних місць немає, вас додано до черги: %d місце", resp.Position),
+		}
+	}
+
 	return tgbotapi.CallbackConfig{
 		CallbackQueryID: query.CallbackQuery.ID,
 		Text:            "Курс зв'язано",
@@ -362,8 +582,13 @@ func (h *Handler) handleChooseCourseForLink(query tgbotapi.Update) tgbotapi.Call
 
 }
 
+// scope returns the services of the group the user works with now
+func (h *Handler) scope(userId int) abstractions.GroupScope {
+	return h.groups.GetUserScope(userId)
+}
+
 func (h *Handler) baseAuth(userId int) bool {
-	for _, users := range h.cfg.Security.AllowedAccountIds {
+	for _, users := range h.scope(userId).MemberIds {
 		if users == userId {
 			return true
 		}
@@ -372,6 +597,16 @@ func (h *Handler) baseAuth(userId int) bool {
 }
 
 func (h *Handler) adminAuth(userId int) bool {
+	for _, users := range h.scope(userId).AdminIds {
+		if users == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerAuth allows the accounts from the configuration to manage the groups
+func (h *Handler) ownerAuth(userId int) bool {
 	for _, users := range h.cfg.Security.TrustedAccountIds {
 		if users == userId {
 			return true
@@ -380,6 +615,67 @@ func (h *Handler) adminAuth(userId int) bool {
 	return false
 }
 
+type broadcastTopic int
+
+const (
+	broadcastAnnouncements broadcastTopic = iota
+	broadcastReplacements
+)
+
+// broadcast sends the text to every member of the active group of the sender who has already talked with the bot
+// and to the chats and channels of the group subscribed to the topic
+func (h *Handler) broadcast(senderId int, topic broadcastTopic, parts []string) {
+	h.broadcastToScope(h.scope(senderId), topic, parts)
+}
+
+func (h *Handler) broadcastToScope(scope abstractions.GroupScope, topic broadcastTopic, parts []string) {
+	for _, userId := range scope.MemberIds {
+		chatId, err := h.chats.GetChatByUserId(userId)
+
+		if err != nil {
+			continue
+		}
+
+		go h.api.sendDeferrable(priorityBulk, prepareLongMessages(chatId, parts))
+	}
+
+	h.broadcastToTargets(scope, topic, parts)
+}
+
+// broadcastToTargets posts the text to the chats and channels of the group subscribed to the topic,
+// the members are told by trackScheduleChanges about the changes of their own lessons
+func (h *Handler) broadcastToTargets(scope abstractions.GroupScope, topic broadcastTopic, parts []string) {
+	targets, _ := h.groups.GetPublicationTargets(scope.GroupId)
+
+	for _, target := range targets {
+		if topic == broadcastAnnouncements && !target.Announcements || topic == broadcastReplacements && !target.Replacements {
+			continue
+		}
+
+		go h.api.sendDeferrable(priorityBulk, prepareLongMessages(target.ChatId, parts))
+	}
+}
+
+// prepareLongMessages joins parts into as few messages as telegram allows
+func prepareLongMessages(chatId int64, parts []string) []tgbotapi.MessageConfig {
+	var res []tgbotapi.MessageConfig
+	text := ""
+
+	for _, patchedTxt := range parts {
+		if len(text)+len(patchedTxt) > 4096 {
+			res = append(res, tgbotapi.NewMessage(chatId, text))
+			text = ""
+		}
+		text += patchedTxt
+	}
+
+	if text != "" {
+		res = append(res, tgbotapi.NewMessage(chatId, text))
+	}
+
+	return res
+}
+
 func (h *Handler) handleCommandCreateAdditionalSchedule(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
 
 	if authenticated := h.adminAuth(userId); !authenticated {
@@ -396,7 +692,7 @@ func (h *Handler) handleCommandCreateAdditionalSchedule(userId int, upd tgbotapi
 
 	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть предмет")
 	keys := tgbotapi.NewInlineKeyboardMarkup()
-	courses, _ := h.course.GetCourses()
+	courses, _ := h.scope(userId).Course.GetCourses()
 
 	for _, course := range courses.Courses {
 		keys.InlineKeyboard = append(keys.InlineKeyboard,
@@ -411,7 +707,7 @@ func (h *Handler) handleCommandCreateAdditionalSchedule(userId int, upd tgbotapi
 }
 
 func (h *Handler) handleGetCommonSchedules(upd tgbotapi.Update) []tgbotapi.MessageConfig {
-	schedules, _ := h.schedule.GetCommonSchedule(upd.Message.From.ID)
+	schedules, _ := h.scope(upd.Message.From.ID).Schedule.GetCommonSchedule(upd.Message.From.ID)
 
 	var res []tgbotapi.MessageConfig
 	text := "Розклад"
@@ -423,7 +719,7 @@ func (h *Handler) handleGetCommonSchedules(upd tgbotapi.Update) []tgbotapi.Messa
 		for order, value := range val.OrderToSchedules {
 			for _, v := range value {
 				patchedTxt += fmt.Sprintf("№ %d. %s \n Вчитель: %s \n Контакт: %s \n Тиждень: %s \n Посилання на зустріч: %s \n",
-					order, v.CourseInfo.Name, v.CourseInfo.TeacherName, v.CourseInfo.TeacherContact, util.ConvertToHumanReadableWeekOrder(v.WeekOrder), v.CourseInfo.MeetLink)
+					order, v.CourseInfo.Name+formatSubgroup(v.Subgroup), v.CourseInfo.TeacherName, v.CourseInfo.TeacherContact, util.ConvertToHumanReadableWeekOrder(v.WeekOrder), v.CourseInfo.MeetLink)
 			}
 
 			patchedTxt += "\n"
@@ -445,14 +741,14 @@ func (h *Handler) handleGetCommonSchedules(upd tgbotapi.Update) []tgbotapi.Messa
 }
 
 func (h *Handler) handleGetScheduleAtToday(upd tgbotapi.Update) []tgbotapi.MessageConfig {
-	schedules, _ := h.schedule.GetCurrentSchedule(upd.Message.From.ID)
+	schedules, _ := h.scope(upd.Message.From.ID).Schedule.GetCurrentSchedule(upd.Message.From.ID)
 	var res []tgbotapi.MessageConfig
 	text := "Розклад. Дата: " + schedules.CurrentDate.Format("2006-01-02") + " Тиждень: " + util.ConvertToHumanReadableWeekOrder(schedules.CurrentWeekOrder)
 	res = append(res, tgbotapi.NewMessage(upd.Message.Chat.ID, text))
 	text = ""
 	for _, val := range schedules.Schedules {
 		patchedTxt := fmt.Sprintf("№ %d. %s \n Вчитель: %s \n Контакт: %s \n Посилання на зустріч: %s \n",
-			val.Order, val.CourseInfo.Name, val.CourseInfo.TeacherName, val.CourseInfo.TeacherContact, val.CourseInfo.MeetLink)
+			val.Order, val.CourseInfo.Name+formatSubgroup(val.Subgroup), val.CourseInfo.TeacherName, val.CourseInfo.TeacherContact, val.CourseInfo.MeetLink)
 		if len(text)+len(patchedTxt) > 4096 {
 			res = append(res, tgbotapi.NewMessage(upd.Message.Chat.ID, text))
 			text = ""
@@ -489,7 +785,7 @@ func (h *Handler) handleCommandUpdateCourse(userId int, upd tgbotapi.Update) []t
 		Action:  actions.UserActionChooseCourse,
 	})
 	h.updateCourseRequests[userId] = dto.UpdateCourseInfoRequest{}
-	infos, _ := h.course.GetCourses()
+	infos, _ := h.scope(userId).Course.GetCourses()
 
 	keys := tgbotapi.NewInlineKeyboardMarkup()
 
@@ -505,7 +801,7 @@ func (h *Handler) handleCommandUpdateCourse(userId int, upd tgbotapi.Update) []t
 }
 
 func (h *Handler) handleGetCoursesCommand(upd tgbotapi.Update) []tgbotapi.MessageConfig {
-	courses, _ := h.course.GetCourses()
+	courses, _ := h.scope(upd.Message.From.ID).Course.GetCourses()
 
 	var res []tgbotapi.MessageConfig
 	text := "Список наявних курсів"
@@ -539,7 +835,7 @@ func (h *Handler) handleCommandDeleteCourse(userId int, upd tgbotapi.Update) []t
 		Action:  actions.UserActionChooseCourse,
 	})
 
-	infos, _ := h.course.GetCourses()
+	infos, _ := h.scope(userId).Course.GetCourses()
 
 	keys := tgbotapi.NewInlineKeyboardMarkup()
 
@@ -567,7 +863,7 @@ func (h *Handler) handleCommandCreateSchedule(userId int, upd tgbotapi.Update) [
 
 	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть предмет")
 	keys := tgbotapi.NewInlineKeyboardMarkup()
-	courses, _ := h.course.GetCourses()
+	courses, _ := h.scope(userId).Course.GetCourses()
 
 	for _, course := range courses.Courses {
 		keys.InlineKeyboard = append(keys.InlineKeyboard,
@@ -588,6 +884,17 @@ func (h *Handler) handleCancelCommand(userId int, upd tgbotapi.Update) []tgbotap
 	delete(h.createScheduleRequests, userId)
 	delete(h.createAddScheduleRequests, userId)
 	delete(h.calendarPosition, userId)
+	delete(h.linkOptionalCourseRequests, userId)
+	delete(h.createExamRequests, userId)
+	delete(h.updateExamRequests, userId)
+	delete(h.cancelRangeRequests, userId)
+	delete(h.cloneSnapshotRequests, userId)
+	delete(h.createPoolRequests, userId)
+	delete(h.setCapacityRequests, userId)
+	delete(h.targetSettingsRequests, userId)
+	delete(h.teacherMeetLinkRequests, userId)
+	delete(h.quietHoursRequests, userId)
+	delete(h.digestTimeRequests, userId)
 
 	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
 		Action: actions.UserActionNone,
@@ -602,35 +909,104 @@ func (h *Handler) handleClearScheduleCommand(userId int, upd tgbotapi.Update) []
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
 	}
 
-	if err := h.schedule.ClearSchedule(); err != nil {
+	if err := h.scope(userId).Schedule.ClearSchedule(); err != nil {
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
 	}
 
-	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Розклад було успішно видалено")}
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Розклад у чернетці очищено, опублікуйте зміни командою /publish")}
 }
 
 func (h *Handler) handleLinkCourseCommand(userId int, upt tgbotapi.Update) []tgbotapi.MessageConfig {
 
-	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Command: commands.LinkOptionalCourseCommand, Action: actions.UserActionChooseCourse})
+	slots, _ := h.scope(userId).Schedule.GetOptionalSlots(userId)
 
-	req := dto.LinkOptionalCourseToUserRequest{UserId: userId}
-	h.linkOptionalCourseRequests[userId] = req
+	if len(slots.Slots) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upt.Message.Chat.ID, "У розкладі немає опціональних пар")}
+	}
+
+	isAdmin := h.adminAuth(userId)
+
+	if window, err := h.scope(userId).Electives.GetSelectionWindow(); err == nil && window.IsLocked && !isAdmin {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upt.Message.Chat.ID, "Вибір курсів закрито, зверніться до адміністратора")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Command: commands.LinkOptionalCourseCommand, Action: actions.UserActionChooseOptionalSlot})
 
-	courses, _ := h.course.GetOptionalCourses()
+	req := dto.LinkOptionalCourseToUserRequest{UserId: userId, IgnoreDeadline: isAdmin}
+	h.linkOptionalCourseRequests[userId] = req
 
-	msg := tgbotapi.NewMessage(upt.Message.Chat.ID, "Виберіть курс: ")
+	msg := tgbotapi.NewMessage(upt.Message.Chat.ID, "Виберіть пару: ")
 	reply := tgbotapi.NewInlineKeyboardMarkup()
 
-	for _, info := range courses.Courses {
+	for _, slot := range slots.Slots {
 		reply.InlineKeyboard = append(reply.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(info.Name, info.Id)))
+			tgbotapi.NewInlineKeyboardButtonData(formatOptionalSlot(slot), slot.ScheduleId)))
 	}
 
 	msg.ReplyMarkup = reply
 	return []tgbotapi.MessageConfig{msg}
 }
 
+func (h *Handler) handleGetMyOptionalCoursesCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	slots, _ := h.scope(userId).Schedule.GetOptionalSlots(userId)
+
+	if len(slots.Slots) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "У розкладі немає опціональних пар")}
+	}
+
+	parts := []string{"Ваші опціональні курси. Змінити вибір: /" + string(commands.LinkOptionalCourseCommand) + "\n"}
+
+	if window, err := h.scope(userId).Electives.GetSelectionWindow(); err == nil && window.IsLocked {
+		parts = append(parts, "Вибір курсів закрито\n")
+	} else if err == nil {
+		parts = append(parts, "Обрати курси потрібно до "+window.Deadline.Format(examTimeLayout)+"\n")
+	}
+
+	for _, slot := range slots.Slots {
+		parts = append(parts, "\n "+formatOptionalSlot(slot))
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleChooseSlotForLink(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	req := h.linkOptionalCourseRequests[query.CallbackQuery.From.ID]
+	req.ScheduleId = query.CallbackQuery.Data
+	h.linkOptionalCourseRequests[query.CallbackQuery.From.ID] = req
+
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Command: commands.LinkOptionalCourseCommand,
+		Action:  actions.UserActionChooseCourse,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Пару обрано",
+	}
+}
+
+func formatOptionalSlot(slot dto.OptionalSlotDto) string {
+	selected := "Не обрано"
+
+	if slot.SelectedCourse != nil {
+		selected = slot.SelectedCourse.Name
+	}
+
+	return formatSlotTime(slot) + ": " + selected
+}
+
+func formatSlotTime(slot dto.OptionalSlotDto) string {
+	return fmt.Sprintf("%s, № %d (%s)",
+		util.ConvertToHumanReadableWeek(slot.Weekday),
+		slot.Order,
+		util.ConvertToHumanReadableWeekOrder(slot.WeekOrder))
+}
+
 func (h *Handler) handleCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	if !h.baseAuth(userId) {
+		return h.handleTeacherCommand(userId, upd)
+	}
+
 	switch upd.Message.Command() {
 	case string(commands.CreateAdditionalScheduleCommand):
 		return h.handleCommandCreateAdditionalSchedule(userId, upd)
@@ -654,6 +1030,86 @@ func (h *Handler) handleCommand(userId int, upd tgbotapi.Update) []tgbotapi.Mess
 		return h.handleClearScheduleCommand(userId, upd)
 	case string(commands.LinkOptionalCourseCommand):
 		return h.handleLinkCourseCommand(userId, upd)
+	case string(commands.CreateExamCommand):
+		return h.handleCommandCreateExam(userId, upd)
+	case string(commands.UpdateExamCommand):
+		return h.handleCommandUpdateExam(userId, upd)
+	case string(commands.DeleteExamCommand):
+		return h.handleCommandDeleteExam(userId, upd)
+	case string(commands.GetExamsCommand):
+		return h.handleGetExamsCommand(upd)
+	case string(commands.CancelRangeCommand):
+		return h.handleCommandCancelRange(userId, upd)
+	case string(commands.SaveScheduleSnapshotCommand):
+		return h.handleCommandSaveSnapshot(userId, upd)
+	case string(commands.GetScheduleSnapshotsCommand):
+		return h.handleGetSnapshotsCommand(userId, upd)
+	case string(commands.CloneScheduleSnapshotCommand):
+		return h.handleCommandChooseSnapshot(userId, upd, commands.CloneScheduleSnapshotCommand)
+	case string(commands.DeleteScheduleSnapshotCommand):
+		return h.handleCommandChooseSnapshot(userId, upd, commands.DeleteScheduleSnapshotCommand)
+	case string(commands.GetMyOptionalCoursesCommand):
+		return h.handleGetMyOptionalCoursesCommand(userId, upd)
+	case string(commands.CreateElectivePoolCommand):
+		return h.handleCommandCreateElectivePool(userId, upd)
+	case string(commands.OpenElectiveSelectionCommand):
+		return h.handleCommandOpenElectiveSelection(userId, upd)
+	case string(commands.CreateGroupCommand):
+		return h.handleCommandCreateGroup(userId, upd)
+	case string(commands.GetGroupsCommand):
+		return h.handleGetGroupsCommand(userId, upd)
+	case string(commands.SwitchGroupCommand):
+		return h.handleCommandSwitchGroup(userId, upd)
+	case string(commands.AddGroupMemberCommand):
+		return h.handleCommandGroupMember(userId, upd, commands.AddGroupMemberCommand)
+	case string(commands.RemoveGroupMemberCommand):
+		return h.handleCommandGroupMember(userId, upd, commands.RemoveGroupMemberCommand)
+	case string(commands.CreateSubgroupCommand):
+		return h.handleCommandCreateSubgroup(userId, upd)
+	case string(commands.DeleteSubgroupCommand):
+		return h.handleCommandDeleteSubgroup(userId, upd)
+	case string(commands.GetSubgroupsCommand):
+		return h.handleGetSubgroupsCommand(userId, upd)
+	case string(commands.AssignSubgroupCommand):
+		return h.handleCommandAssignSubgroup(userId, upd)
+	case string(commands.AddTeacherCommand):
+		return h.handleCommandAddTeacher(userId, upd)
+	case string(commands.GetMyClassesCommand), string(commands.CancelClassCommand), string(commands.SetMeetLinkCommand):
+		return h.handleTeacherCommand(userId, upd)
+	case string(commands.RemindersCommand):
+		return h.handleRemindersCommand(userId, upd)
+	case string(commands.QuietHoursCommand):
+		return h.handleQuietHoursCommand(userId, upd)
+	case string(commands.DigestCommand):
+		return h.handleDigestCommand(userId, upd)
+	case string(commands.MuteCommand):
+		return h.handleMuteCommand(userId, upd)
+	case string(commands.DeliveryStatsCommand):
+		return h.handleDeliveryStatsCommand(userId, upd)
+	case string(commands.AddChannelCommand):
+		return h.handleCommandAddChannel(userId, upd)
+	case string(commands.RemoveChannelCommand), string(commands.ChannelSettingsCommand):
+		return h.handleCommandChooseChannel(userId, upd, commands.CommandType(upd.Message.Command()))
+	case string(commands.ImportElectivesCommand):
+		return h.handleCommandImportElectives(userId, upd)
+	case string(commands.AutoAssignElectivesCommand):
+		return h.handleAutoAssignElectivesCommand(userId, upd)
+	case string(commands.SetCourseCapacityCommand):
+		return h.handleCommandSetCourseCapacity(userId, upd)
+	case string(commands.GetElectivePoolsCommand):
+		return h.handleGetElectivePoolsCommand(userId, upd)
+	case string(commands.DeleteElectivePoolCommand):
+		return h.handleCommandDeleteElectivePool(userId, upd)
+	case string(commands.GetElectiveRosterCommand):
+		return h.handleGetElectiveRosterCommand(userId, upd)
+	case string(commands.ExportElectiveRosterCommand):
+		return h.handleExportElectiveRosterCommand(userId, upd)
+	case string(commands.GetDraftDiffCommand):
+		return h.handleGetDraftDiffCommand(userId, upd)
+	case string(commands.PublishDraftCommand):
+		return h.handlePublishDraftCommand(userId, upd)
+	case string(commands.DiscardDraftCommand):
+		return h.handleDiscardDraftCommand(userId, upd)
 	default:
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невідома команда")}
 	}
@@ -731,7 +1187,7 @@ func (h *Handler) handleActionInputMeetLink(action dto.UserActionDto, userId int
 		req, _ := h.createCourseRequests[userId]
 		req.MeetLink = upd.Message.Text
 		delete(h.createCourseRequests, userId)
-		_, err := h.course.CreateNewCourse(req)
+		_, err := h.scope(userId).Course.CreateNewCourse(req)
 
 		if err != nil {
 			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час виконання запиту трапилась помилка "+err.Error())}
@@ -739,12 +1195,25 @@ func (h *Handler) handleActionInputMeetLink(action dto.UserActionDto, userId int
 
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Курс було створено")}
 	}
+	if action.Command == commands.SetMeetLinkCommand {
+		return h.handleActionInputTeacherMeetLink(userId, upd)
+	}
 	if action.Command == commands.UpdateCourseCommand {
 		req, _ := h.updateCourseRequests[userId]
 		if upd.Message.Text != "Без змін" {
 			req.MeetLink = upd.Message.Text
 		}
-		h.course.UpdateCourse(req)
+		scope := h.scope(userId)
+		err := h.trackScheduleChanges(scope, func() error {
+			return scope.Course.UpdateCourse(req)
+		})
+
+		if err != nil {
+			msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час виконання запиту трапилась помилка "+err.Error())
+			msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+			return []tgbotapi.MessageConfig{msg}
+		}
+
 		msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Курс було оновлено")
 		msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
 		return []tgbotapi.MessageConfig{msg}
@@ -814,43 +1283,63 @@ func (h *Handler) handleActionInputOrder(action dto.UserActionDto, userId int, u
 	if action.Command == commands.CreateAdditionalScheduleCommand {
 		req, _ := h.createAddScheduleRequests[userId]
 		req.Order = int(converted)
+		h.createAddScheduleRequests[userId] = req
+	} else {
+		req, _ := h.createScheduleRequests[userId]
+		req.Order = int(converted)
+		h.createScheduleRequests[userId] = req
+	}
 
+	if msg, ok := h.prepareChooseSubgroupMessage(userId, upd.Message.Chat.ID); ok {
 		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
-			Command: commands.CreateAdditionalScheduleCommand,
-			Action:  actions.UserActionInputDate,
+			Command: action.Command,
+			Action:  actions.UserActionInputSubgroup,
 		})
 
-		h.createAddScheduleRequests[userId] = req
+		return []tgbotapi.MessageConfig{msg}
+	}
 
-		cleanMarkup := tgbotapi.NewMessage(upd.Message.Chat.ID, "Дата заміни")
-		cleanMarkup.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+	if action.Command == commands.CreateAdditionalScheduleCommand {
+		return h.prepareInputReplacementDateMessages(userId, upd.Message.Chat.ID)
+	}
 
-		msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть час, коли відбудється заміна")
-		h.calendarPosition[userId] = dto.CalendarPositionDto{Month: time.Now().Month(), Year: time.Now().Year()}
-		markup := calendar.GenerateCalendar(time.Now().Year(), time.Now().Month())
+	return h.saveNewSchedule(userId, upd.Message.Chat.ID)
+}
 
-		msg.ReplyMarkup = markup
-		return []tgbotapi.MessageConfig{cleanMarkup, msg}
-	}
+func (h *Handler) prepareInputReplacementDateMessages(userId int, chatId int64) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateAdditionalScheduleCommand,
+		Action:  actions.UserActionInputDate,
+	})
 
-	req, _ := h.createScheduleRequests[userId]
+	cleanMarkup := tgbotapi.NewMessage(chatId, "Дата заміни")
+	cleanMarkup.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
 
-	req.Order = int(converted)
+	msg := tgbotapi.NewMessage(chatId, "Введіть час, коли відбудється заміна")
+	h.calendarPosition[userId] = dto.CalendarPositionDto{Month: time.Now().Month(), Year: time.Now().Year()}
+	markup := calendar.GenerateCalendar(time.Now().Year(), time.Now().Month())
+
+	msg.ReplyMarkup = markup
+	return []tgbotapi.MessageConfig{cleanMarkup, msg}
+}
+
+func (h *Handler) saveNewSchedule(userId int, chatId int64) []tgbotapi.MessageConfig {
+	req, _ := h.createScheduleRequests[userId]
 
 	delete(h.createScheduleRequests, userId)
 	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
 		Action: actions.UserActionNone,
 	})
 
-	err = h.schedule.CreateNewSchedule(req)
+	err := h.scope(userId).Schedule.CreateNewSchedule(req)
 
 	if err != nil {
-		msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Виникла помилка під час збереження")
+		msg := tgbotapi.NewMessage(chatId, "Виникла помилка під час збереження")
 		msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
 		return []tgbotapi.MessageConfig{msg}
 	}
 
-	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Пару було збережено")
+	msg := tgbotapi.NewMessage(chatId, "Пару збережено в чернетці, опублікуйте зміни командою /publish")
 	msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
 	return []tgbotapi.MessageConfig{msg}
 }
@@ -949,12 +1438,54 @@ func (h *Handler) handleAction(action dto.UserActionDto, userId int, upd tgbotap
 		return h.handleActionInputMeetLink(action, userId, upd)
 	case actions.UserActionInputWeekday:
 		return h.handleActionInputWeekDay(userId, upd)
+	case actions.UserActionInputReminderOffsets:
+		return h.handleActionInputReminderOffsets(userId, upd)
+	case actions.UserActionInputQuietHours:
+		return h.handleActionInputQuietHours(userId, upd)
+	case actions.UserActionInputMutedUntil:
+		return h.handleActionInputMutedUntil(userId, upd)
+	case actions.UserActionInputDigestTime:
+		return h.handleActionInputDigestTime(userId, upd)
 	case actions.UserActionInputWeekOrder:
 		return h.handleActionInputWeekOrder(userId, upd)
 	case actions.UserActionInputOrder:
 		return h.handleActionInputOrder(action, userId, upd)
 	case actions.UserActionSelectOptionality:
 		return h.handleActionInputOptionality(action, userId, upd)
+	case actions.UserActionInputExamKind:
+		return h.handleActionInputExamKind(action, userId, upd)
+	case actions.UserActionInputExamTime:
+		return h.handleActionInputExamTime(action, userId, upd)
+	case actions.UserActionInputExamRoom:
+		return h.handleActionInputExamRoom(action, userId, upd)
+	case actions.UserActionInputRangeStart:
+		return h.handleActionInputRangeStart(userId, upd)
+	case actions.UserActionInputRangeEnd:
+		return h.handleActionInputRangeEnd(userId, upd)
+	case actions.UserActionInputSnapshotName:
+		return h.handleActionInputSnapshotName(userId, upd)
+	case actions.UserActionInputPoolName:
+		return h.handleActionInputPoolName(userId, upd)
+	case actions.UserActionInputCapacity:
+		return h.handleActionInputCapacity(userId, upd)
+	case actions.UserActionInputDeadline:
+		return h.handleActionInputDeadline(userId, upd)
+	case actions.UserActionUploadDocument:
+		return h.handleActionUploadElectivesCsv(userId, upd)
+	case actions.UserActionInputGroupName:
+		return h.handleActionInputGroupName(userId, upd)
+	case actions.UserActionInputGroupMember:
+		return h.handleActionInputGroupMember(action, userId, upd)
+	case actions.UserActionInputChannel:
+		return h.handleActionInputChannel(userId, upd)
+	case actions.UserActionInputTeacher:
+		return h.handleActionInputTeacher(userId, upd)
+	case actions.UserActionInputSubgroup:
+		return h.handleActionInputSubgroup(action, userId, upd)
+	case actions.UserActionInputSubgroupName:
+		return h.handleActionInputSubgroupName(action, userId, upd)
+	case actions.UserActionInputSubgroupMember:
+		return h.handleActionInputSubgroupMember(userId, upd)
 	default:
 		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Виникла помилка, повторіть спробу пізніше")}
 	}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+)
+
+const (
+	muteCallbackPrefix = "mute|" // the button on the reminder message
+	muteCourseKind     = "course"
+	muteSlotKind       = "slot"
+)
+
+func (h *Handler) handleMuteCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	items := h.getMuteItems(userId)
+
+	if len(items) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Курсів немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.MuteCommand,
+		Action:  actions.UserActionChooseMutes,
+	})
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть курси та пари за вибором, про які не нагадувати, "+
+		"та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareToggleKeyboard(items, h.getMutedItemIds(userId))
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleToggleMute(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	if query.CallbackQuery.Data == DoneCallbackId {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Налаштування збережено",
+		}
+	}
+
+	muted := true
+
+	for _, id := range h.getMutedItemIds(userId) {
+		if id == query.CallbackQuery.Data {
+			muted = false
+		}
+	}
+
+	if err := h.updateMute(userId, query.CallbackQuery.Data, muted); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+		query.CallbackQuery.Message.Chat.ID,
+		query.CallbackQuery.Message.MessageID,
+		prepareToggleKeyboard(h.getMuteItems(userId), h.getMutedItemIds(userId))))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+// handleMuteButton handles the button on the reminder message, it works whatever the user is doing now
+func (h *Handler) handleMuteButton(update tgbotapi.Update) bool {
+	if !strings.HasPrefix(update.CallbackQuery.Data, muteCallbackPrefix) {
+		return false
+	}
+
+	answer := tgbotapi.CallbackConfig{
+		CallbackQueryID: update.CallbackQuery.ID,
+		Text:            "Нагадування про цю пару вимкнено, змінити: /" + string(commands.MuteCommand),
+	}
+
+	if err := h.updateMute(update.CallbackQuery.From.ID, strings.TrimPrefix(update.CallbackQuery.Data, muteCallbackPrefix), true); err != nil {
+		answer.Text = "Помилка під час виконання запиту"
+	} else {
+		go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+			update.CallbackQuery.Message.Chat.ID,
+			update.CallbackQuery.Message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+	}
+
+	go h.api.executeCallback(answer)
+
+	return true
+}
+
+// updateMute takes the kind and the id of the course or the slot from the callback data
+func (h *Handler) updateMute(userId int, data string, muted bool) error {
+	req := dto.UpdateMuteRequest{UserId: userId, Muted: muted}
+
+	switch kind, id, _ := strings.Cut(data, "|"); kind {
+	case muteCourseKind:
+		req.CourseId = id
+	case muteSlotKind:
+		req.ScheduleId = id
+	}
+
+	return h.preferences.UpdateMute(req)
+}
+
+func (h *Handler) getMuteItems(userId int) []toggleItem {
+	var items []toggleItem
+
+	if courses, err := h.scope(userId).Course.GetCourses(); err == nil {
+		for _, course := range courses.Courses {
+			items = append(items, toggleItem{Id: muteCourseKind + "|" + course.Id, Name: course.Name})
+		}
+	}
+
+	if slots, err := h.scope(userId).Schedule.GetOptionalSlots(userId); err == nil {
+		for _, slot := range slots.Slots {
+			items = append(items, toggleItem{Id: muteSlotKind + "|" + slot.ScheduleId, Name: "Пара за вибором: " + formatSlotTime(slot)})
+		}
+	}
+
+	return items
+}
+
+func (h *Handler) getMutedItemIds(userId int) []string {
+	var ids []string
+	mutes := h.preferences.GetMutes(userId)
+
+	for _, id := range mutes.CourseIds {
+		ids = append(ids, muteCourseKind+"|"+id)
+	}
+
+	for _, id := range mutes.SlotIds {
+		ids = append(ids, muteSlotKind+"|"+id)
+	}
+
+	return ids
+}
+
+// prepareMuteKeyboard returns the button muting the lesson of the reminder, the electives are muted by the slot
+func prepareMuteKeyboard(schedule dto.ScheduleDto) *tgbotapi.InlineKeyboardMarkup {
+	var data string
+
+	switch {
+	case schedule.ScheduleId != "":
+		data = muteCallbackPrefix + muteSlotKind + "|" + schedule.ScheduleId
+	case schedule.CourseInfo.Id != "":
+		data = muteCallbackPrefix + muteCourseKind + "|" + schedule.CourseInfo.Id
+	default:
+		return nil
+	}
+
+	keys := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔕 Не нагадувати про цю пару", data)))
+	return &keys
+}
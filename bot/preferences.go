@@ -0,0 +1,311 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+const (
+	remindersDefaultCallbackId = "default"
+	remindersOffCallbackId     = "off"
+	remindersCustomCallbackId  = "custom"
+	digestToggleCallbackId     = "toggle"
+	digestTimeCallbackId       = "time"
+)
+
+// reminderPresets are the common choices, the callback data is the list of offsets
+var reminderPresets = []toggleItem{
+	{Id: "30,5", Name: "За 30 і 5 хв"},
+	{Id: "15,0", Name: "За 15 хв і на початку"},
+	{Id: "10", Name: "За 10 хв"},
+	{Id: "0", Name: "Лише на початку пари"},
+}
+
+func (h *Handler) handleRemindersCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.RemindersCommand,
+		Action:  actions.UserActionChooseReminders,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Як за замовчуванням", remindersDefaultCallbackId)))
+
+	for _, preset := range reminderPresets {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(preset.Name, preset.Id)))
+	}
+
+	keys.InlineKeyboard = append(keys.InlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Ввести власні", remindersCustomCallbackId)),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Вимкнути нагадування", remindersOffCallbackId)))
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Зараз: "+formatReminderSettings(h.preferences.GetReminderSettings(userId))+"\nОберіть, коли нагадувати про пари")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseReminders(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	if query.CallbackQuery.Data == remindersCustomCallbackId {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+			Command: commands.RemindersCommand,
+			Action:  actions.UserActionInputReminderOffsets,
+		})
+
+		return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	req := dto.UpdateReminderSettingsRequest{UserId: userId, Enabled: true}
+	var err error
+
+	switch query.CallbackQuery.Data {
+	case remindersOffCallbackId:
+		req.Enabled = false
+	case remindersDefaultCallbackId:
+	default:
+		req.Offsets, err = parseReminderOffsets(query.CallbackQuery.Data)
+	}
+
+	if err == nil {
+		err = h.preferences.UpdateReminderSettings(req)
+	}
+
+	if err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	go h.api.executeMessage(tgbotapi.NewMessage(query.CallbackQuery.Message.Chat.ID,
+		"Нагадування: "+formatReminderSettings(h.preferences.GetReminderSettings(userId))))
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Налаштування збережено",
+	}
+}
+
+func (h *Handler) handleActionInputReminderOffsets(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	offsets, err := parseReminderOffsets(upd.Message.Text)
+
+	if err == nil && len(offsets) == 0 {
+		err = errors.New("EmptyOffsets")
+	}
+
+	if err == nil {
+		err = h.preferences.UpdateReminderSettings(dto.UpdateReminderSettingsRequest{
+			UserId:  userId,
+			Enabled: true,
+			Offsets: offsets,
+		})
+	}
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Невірні дані, введіть до 5 чисел від 0 до 1440 через кому, наприклад: 30, 5")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Нагадування: "+formatReminderSettings(h.preferences.GetReminderSettings(userId)))}
+}
+
+func (h *Handler) handleDigestCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	return []tgbotapi.MessageConfig{h.prepareDigestMessage(userId, upd.Message.Chat.ID)}
+}
+
+func (h *Handler) prepareDigestMessage(userId int, chatId int64) tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.DigestCommand,
+		Action:  actions.UserActionChooseDigest,
+	})
+
+	msg := tgbotapi.NewMessage(chatId, "Ранковий дайджест містить розклад на сьогодні, вечірній огляд - розклад на завтра "+
+		"з часом першої пари. Заміни та скасування виділяються. Оберіть налаштування та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareDigestKeyboard(
+		h.preferences.GetDigestSettings(userId, util.DigestKindMorning),
+		h.preferences.GetDigestSettings(userId, util.DigestKindEvening))
+	return msg
+}
+
+// handleChooseDigest handles the buttons of the digest settings, the data is the action and the kind of the digest
+func (h *Handler) handleChooseDigest(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	if query.CallbackQuery.Data == DoneCallbackId {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+		delete(h.digestTimeRequests, userId)
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Налаштування збережено",
+		}
+	}
+
+	data := strings.Split(query.CallbackQuery.Data, "|")
+	var kindId int
+	var err error
+
+	if len(data) == 2 {
+		kindId, err = strconv.Atoi(data[1])
+	} else {
+		err = errors.New("InvalidCallbackData")
+	}
+
+	if err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	kind := util.DigestKind(kindId)
+
+	if data[0] == digestTimeCallbackId {
+		h.digestTimeRequests[userId] = kind
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+			Command: commands.DigestCommand,
+			Action:  actions.UserActionInputDigestTime,
+		})
+
+		return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+	}
+
+	settings := h.preferences.GetDigestSettings(userId, kind)
+	req := dto.UpdateDigestSettingsRequest{UserId: userId, Kind: kind, Enabled: !settings.Enabled}
+
+	if !settings.IsDefaultTime {
+		req.Time = &settings.Time
+	}
+
+	if err = h.preferences.UpdateDigestSettings(req); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+		query.CallbackQuery.Message.Chat.ID,
+		query.CallbackQuery.Message.MessageID,
+		prepareDigestKeyboard(
+			h.preferences.GetDigestSettings(userId, util.DigestKindMorning),
+			h.preferences.GetDigestSettings(userId, util.DigestKindEvening))))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+func (h *Handler) handleActionInputDigestTime(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	digestTime, err := time.Parse(quietHoursLayout, strings.TrimSpace(upd.Message.Text))
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, введіть час, наприклад: 07:30")}
+	}
+
+	kind := h.digestTimeRequests[userId]
+	delete(h.digestTimeRequests, userId)
+	since := sinceMidnight(digestTime)
+
+	// choosing the time turns the digest on, it is what the user expects after setting it up
+	err = h.preferences.UpdateDigestSettings(dto.UpdateDigestSettingsRequest{
+		UserId:  userId,
+		Kind:    kind,
+		Enabled: true,
+		Time:    &since,
+	})
+
+	if err != nil {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{h.prepareDigestMessage(userId, upd.Message.Chat.ID)}
+}
+
+func prepareDigestKeyboard(morning dto.DigestSettingsDto, evening dto.DigestSettingsDto) tgbotapi.InlineKeyboardMarkup {
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, settings := range []dto.DigestSettingsDto{morning, evening} {
+		name := "Ранковий дайджест"
+
+		if settings.Kind == util.DigestKindEvening {
+			name = "Вечірній огляд завтра"
+		}
+
+		if settings.Enabled {
+			name = "✅ " + name
+		}
+
+		digestTime := "Час: " + formatSinceMidnight(settings.Time)
+
+		if settings.IsDefaultTime {
+			digestTime += " (за замовчуванням)"
+		}
+
+		kindId := strconv.Itoa(int(settings.Kind))
+
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(name, digestToggleCallbackId+"|"+kindId)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(digestTime+", змінити", digestTimeCallbackId+"|"+kindId)))
+	}
+
+	keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Готово", DoneCallbackId)))
+
+	return keys
+}
+
+func parseReminderOffsets(text string) ([]int, error) {
+	var offsets []int
+
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+		offset, err := strconv.Atoi(field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	return offsets, nil
+}
+
+func formatReminderSettings(settings dto.ReminderSettingsDto) string {
+	if !settings.Enabled {
+		return "вимкнено"
+	}
+
+	var parts []string
+
+	for _, offset := range settings.Offsets {
+		if offset == 0 {
+			parts = append(parts, "на початку пари")
+		} else {
+			parts = append(parts, fmt.Sprintf("за %d хв", offset))
+		}
+	}
+
+	text := strings.Join(parts, ", ")
+
+	if settings.IsDefault {
+		text += " (за замовчуванням)"
+	}
+
+	return text
+}
@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	globalSendRate  = 30.0      // messages per second for the whole bot
+	privateSendRate = 1.0       // messages per second to a private chat
+	groupSendRate   = 20.0 / 60 // messages per second to a group chat or a channel
+	chatSendBurst   = 3.0       // short bursts are allowed, e.g. a long answer split into parts
+	idleQueueWait   = time.Hour // nothing is waiting, the queue sleeps until a new job
+	minQueueWait    = time.Millisecond
+)
+
+// sendPriority orders the waiting messages, time-critical reminders go before the answers and the broadcasts
+type sendPriority int
+
+const (
+	priorityReminder sendPriority = 0
+	priorityAnswer   sendPriority = 1
+	priorityBulk     sendPriority = 2
+	priorityCount                 = 3
+)
+
+// tokenBucket allows rate messages per second on average with bursts up to the capacity
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, capacity float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+// wait returns how long to wait until a token is available
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) take(now time.Time) {
+	b.refill(now)
+	b.tokens--
+}
+
+// pause empties the bucket for the duration, telegram asked to wait with retry_after
+func (b *tokenBucket) pause(now time.Time, duration time.Duration) {
+	b.refill(now)
+	b.tokens = -duration.Seconds() * b.rate
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+
+		b.last = now
+	}
+}
+
+type sendJob struct {
+	chatId int64
+	ready  chan struct{}
+}
+
+// sendQueue lets the outgoing calls through one by one within the global and the per-chat limits of telegram,
+// the jobs of the same chat and priority keep their order
+type sendQueue struct {
+	mu     *sync.Mutex
+	jobs   [priorityCount][]*sendJob
+	global *tokenBucket
+	chats  map[int64]*tokenBucket
+	signal chan struct{}
+}
+
+func newSendQueue() *sendQueue {
+	q := &sendQueue{
+		mu:     &sync.Mutex{},
+		global: newTokenBucket(globalSendRate, globalSendRate, time.Now()),
+		chats:  map[int64]*tokenBucket{},
+		signal: make(chan struct{}, 1),
+	}
+
+	go q.run()
+
+	return q
+}
+
+// do waits for the turn of the job and performs the call, a chat id of 0 is limited only globally
+func (q *sendQueue) do(priority sendPriority, chatId int64, call func() error) error {
+	job := &sendJob{chatId: chatId, ready: make(chan struct{})}
+
+	q.mu.Lock()
+	q.jobs[priority] = append(q.jobs[priority], job)
+	q.mu.Unlock()
+
+	q.notify()
+	<-job.ready
+
+	return call()
+}
+
+// pause holds the messages to the chat, or all messages for the chat id of 0
+func (q *sendQueue) pause(chatId int64, duration time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	if chatId == 0 {
+		q.global.pause(now, duration)
+		return
+	}
+
+	q.chatBucket(chatId, now).pause(now, duration)
+}
+
+func (q *sendQueue) run() {
+	for {
+		wait := q.release(time.Now())
+
+		if wait < minQueueWait {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-q.signal:
+		case <-timer.C:
+		}
+
+		timer.Stop()
+	}
+}
+
+// release lets the next job through and returns how long to wait before the next attempt
+func (q *sendQueue) release(now time.Time) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if wait := q.global.wait(now); wait > 0 {
+		return wait
+	}
+
+	wait := idleQueueWait
+	blocked := map[int64]bool{}
+
+	for priority := range q.jobs {
+		for i, job := range q.jobs[priority] {
+			// the chat waits for its bucket, its other jobs are not checked in this round
+			if blocked[job.chatId] {
+				continue
+			}
+
+			var bucket *tokenBucket
+
+			if job.chatId != 0 {
+				bucket = q.chatBucket(job.chatId, now)
+
+				if chatWait := bucket.wait(now); chatWait > 0 {
+					blocked[job.chatId] = true
+
+					if chatWait < wait {
+						wait = chatWait
+					}
+					continue
+				}
+
+				bucket.take(now)
+			}
+
+			q.global.take(now)
+			q.jobs[priority] = append(q.jobs[priority][:i], q.jobs[priority][i+1:]...)
+			close(job.ready)
+
+			return 0
+		}
+	}
+
+	q.cleanup(now)
+
+	return wait
+}
+
+func (q *sendQueue) chatBucket(chatId int64, now time.Time) *tokenBucket {
+	bucket, exists := q.chats[chatId]
+
+	if !exists {
+		rate := privateSendRate
+
+		if chatId < 0 {
+			rate = groupSendRate
+		}
+
+		bucket = newTokenBucket(rate, chatSendBurst, now)
+		q.chats[chatId] = bucket
+	}
+
+	return bucket
+}
+
+// cleanup forgets the full buckets of the idle chats, a new bucket is full anyway
+func (q *sendQueue) cleanup(now time.Time) {
+	for chatId, bucket := range q.chats {
+		if bucket.wait(now) == 0 && bucket.tokens >= bucket.capacity {
+			delete(q.chats, chatId)
+		}
+	}
+}
+
+func (q *sendQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		rate  float64
+		taken int
+		pause time.Duration
+		after time.Duration
+		want  time.Duration
+	}{
+		{name: "full bucket", rate: 1, taken: 0, want: 0},
+		{name: "burst is allowed", rate: 1, taken: 2, want: 0},
+		{name: "empty bucket", rate: 1, taken: 3, want: time.Second},
+		{name: "partly refilled", rate: 1, taken: 3, after: 400 * time.Millisecond, want: 600 * time.Millisecond},
+		{name: "refilled", rate: 1, taken: 3, after: time.Second, want: 0},
+		{name: "slow group rate", rate: groupSendRate, taken: 3, want: 3 * time.Second},
+		{name: "pause", rate: 1, pause: 5 * time.Second, want: 6 * time.Second},
+		{name: "pause is over", rate: 1, pause: 5 * time.Second, after: 6 * time.Second, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := newTokenBucket(tt.rate, chatSendBurst, start)
+
+			for i := 0; i < tt.taken; i++ {
+				bucket.take(start)
+			}
+
+			if tt.pause > 0 {
+				bucket.pause(start, tt.pause)
+			}
+
+			got := bucket.wait(start.Add(tt.after))
+
+			if diff := got - tt.want; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("wait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucketCapacity(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bucket := newTokenBucket(1, chatSendBurst, start)
+
+	// a long idle time does not allow more than the burst
+	now := start.Add(time.Hour)
+
+	for i := 0; i < int(chatSendBurst); i++ {
+		if wait := bucket.wait(now); wait != 0 {
+			t.Fatalf("message %d waits %v, want 0", i, wait)
+		}
+
+		bucket.take(now)
+	}
+
+	if wait := bucket.wait(now); wait != time.Second {
+		t.Errorf("wait() after the burst = %v, want %v", wait, time.Second)
+	}
+}
+
+type queuedJob struct {
+	priority sendPriority
+	chatId   int64
+}
+
+func TestSendQueueRelease(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		jobs []queuedJob
+		want []int // the indexes of the jobs in the order of the release
+	}{
+		{
+			name: "priority goes first",
+			jobs: []queuedJob{{priorityBulk, 1}, {priorityAnswer, 2}, {priorityReminder, 3}},
+			want: []int{2, 1, 0},
+		},
+		{
+			name: "same priority keeps the order",
+			jobs: []queuedJob{{priorityBulk, 1}, {priorityBulk, 2}, {priorityBulk, 3}},
+			want: []int{0, 1, 2},
+		},
+		{
+			name: "busy chat does not block the others",
+			jobs: []queuedJob{{priorityBulk, 1}, {priorityBulk, 1}, {priorityBulk, 1}, {priorityBulk, 1}, {priorityBulk, 2}},
+			want: []int{0, 1, 2, 4},
+		},
+		{
+			name: "waiting chat keeps the order of its jobs",
+			jobs: []queuedJob{{priorityBulk, 1}, {priorityBulk, 1}, {priorityBulk, 1}, {priorityBulk, 1}, {priorityReminder, 1}},
+			want: []int{4, 0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &sendQueue{
+				mu:     &sync.Mutex{},
+				global: newTokenBucket(globalSendRate, globalSendRate, now),
+				chats:  map[int64]*tokenBucket{},
+			}
+
+			jobs := map[*sendJob]int{}
+
+			for i, val := range tt.jobs {
+				job := &sendJob{chatId: val.chatId, ready: make(chan struct{})}
+				jobs[job] = i
+				q.jobs[val.priority] = append(q.jobs[val.priority], job)
+			}
+
+			var got []int
+
+			for q.release(now) == 0 {
+				for job, i := range jobs {
+					select {
+					case <-job.ready:
+						got = append(got, i)
+						delete(jobs, job)
+					default:
+					}
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("released %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("released %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"time"
+)
+
+const (
+	quietSelfCallbackId         = "self"
+	quietHoursCallbackId        = "hours"
+	quietClearHoursCallbackId   = "clear_hours"
+	quietMuteHourCallbackId     = "mute_hour"
+	quietMuteMorningCallbackId  = "mute_morning"
+	quietMuteMondayCallbackId   = "mute_monday"
+	quietMuteDateCallbackId     = "mute_date"
+	quietUnmuteCallbackId       = "unmute"
+	quietBreakThroughCallbackId = "break_through"
+
+	quietHoursLayout = "15:04"
+	defaultMorning   = 8 * time.Hour // the end of "do not disturb until the morning" without quiet hours
+)
+
+var errQuietHours = errors.New("QuietHours")
+
+type quietAction int
+
+const (
+	quietActionSend  quietAction = 1
+	quietActionDefer quietAction = 2
+	quietActionDrop  quietAction = 3
+)
+
+// decideQuietAction tells what to do with a notification during the quiet time, the lesson reminders are useless
+// later, so they are dropped unless the recipient lets them break through, other notifications wait for the end
+func decideQuietAction(check dto.QuietCheckDto, isReminder bool) quietAction {
+	switch {
+	case !check.IsQuiet:
+		return quietActionSend
+	case !isReminder:
+		return quietActionDefer
+	case check.RemindersBreakThrough:
+		return quietActionSend
+	default:
+		return quietActionDrop
+	}
+}
+
+// sendReminder sends the lesson reminder, during the quiet time it is sent only when the recipient lets
+// the reminders break through
+func (a *Api) sendReminder(msg tgbotapi.MessageConfig) error {
+	if decideQuietAction(a.checkQuietHours(msg.ChatID), true) == quietActionDrop {
+		atomic.AddInt64(&a.metrics.dropped, 1)
+		return errQuietHours
+	}
+
+	return a.sendMessage(priorityReminder, msg)
+}
+
+// sendDeferrable sends the notifications which may wait, during the quiet time they are postponed until it ends.
+// The postponed messages are stored, so they survive a restart
+func (a *Api) sendDeferrable(priority sendPriority, msgs []tgbotapi.MessageConfig) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if check := a.checkQuietHours(msgs[0].ChatID); decideQuietAction(check, false) == quietActionDefer {
+		atomic.AddInt64(&a.metrics.deferred, 1)
+		a.deferMessages(priority, msgs, check.Until)
+		return nil
+	}
+
+	return a.sendMessages(priority, msgs)
+}
+
+// deferMessages stores the messages and sends them when the quiet time ends, they are kept in memory only
+// when the storage fails
+func (a *Api) deferMessages(priority sendPriority, msgs []tgbotapi.MessageConfig, until time.Time) {
+	model := dao.DeferredMessageModel{ChatId: msgs[0].ChatID, Priority: int(priority), Until: until}
+
+	for _, msg := range msgs {
+		model.Texts = append(model.Texts, msg.Text)
+	}
+
+	if a.deferred != nil {
+		id, err := a.deferred.SaveDeferred(model)
+
+		if err != nil {
+			logrus.Warnf("Failed to store the postponed messages to the chat %d: %s", model.ChatId, err)
+		}
+
+		model.Id = id
+	}
+
+	a.scheduleDeferred(model)
+}
+
+func (a *Api) scheduleDeferred(model dao.DeferredMessageModel) {
+	time.AfterFunc(time.Until(model.Until), func() {
+		a.sendPostponed(model)
+	})
+}
+
+// sendPostponed sends the stored messages or postpones them again when the quiet time was prolonged,
+// the stored copy is removed afterwards
+func (a *Api) sendPostponed(model dao.DeferredMessageModel) {
+	var msgs []tgbotapi.MessageConfig
+
+	for _, text := range model.Texts {
+		msgs = append(msgs, tgbotapi.NewMessage(model.ChatId, text))
+	}
+
+	_ = a.sendDeferrable(sendPriority(model.Priority), msgs)
+
+	if a.deferred == nil || model.Id == "" {
+		return
+	}
+
+	if err := a.deferred.DeleteDeferred(model.Id); err != nil {
+		logrus.Warnf("Failed to remove the sent postponed messages %s: %s", model.Id, err)
+	}
+}
+
+// FlushDeferred schedules the messages postponed before the restart, the overdue ones are sent at once
+func (a *Api) FlushDeferred() {
+	if a.deferred == nil {
+		return
+	}
+
+	models, err := a.deferred.GetDeferred()
+
+	if err != nil {
+		logrus.Errorf("Failed to load the postponed messages: %s", err)
+		return
+	}
+
+	for _, model := range models {
+		a.scheduleDeferred(model)
+	}
+}
+
+// deliverDeferrable postpones the messages like sendDeferrable, but reports the result only when they are
+// really sent, so the postponed messages are not taken as delivered. They are not stored, the caller keeps
+// them in the outbox until done is called
+func (a *Api) deliverDeferrable(priority sendPriority, msgs []tgbotapi.MessageConfig, done func(err error)) {
+	if len(msgs) == 0 {
+		done(nil)
+		return
+	}
+
+	if check := a.checkQuietHours(msgs[0].ChatID); decideQuietAction(check, false) == quietActionDefer {
+		atomic.AddInt64(&a.metrics.deferred, 1)
+		time.AfterFunc(time.Until(check.Until), func() {
+			a.deliverDeferrable(priority, msgs, done)
+		})
+		return
+	}
+
+	done(a.sendMessages(priority, msgs))
+}
+
+func (a *Api) sendMessages(priority sendPriority, msgs []tgbotapi.MessageConfig) error {
+	for _, msg := range msgs {
+		if err := a.sendMessage(priority, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Api) checkQuietHours(chatId int64) dto.QuietCheckDto {
+	if a.preferences == nil {
+		return dto.QuietCheckDto{}
+	}
+
+	return a.preferences.CheckQuietHours(chatId, time.Now())
+}
+
+func (h *Handler) handleQuietHoursCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	var targets []dto.PublicationTargetDto
+
+	if h.adminAuth(userId) {
+		targets, _ = h.groups.GetPublicationTargets(h.scope(userId).GroupId)
+	}
+
+	if len(targets) == 0 {
+		return []tgbotapi.MessageConfig{h.prepareQuietHoursMessage(userId, upd.Message.Chat.ID, int64(userId))}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.QuietHoursCommand,
+		Action:  actions.UserActionChooseTarget,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Для мене", quietSelfCallbackId)))
+
+	for _, target := range targets {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(target.Title, strconv.FormatInt(target.ChatId, 10))))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть, для кого налаштувати тихі години")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseTargetForQuietHours(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+	targetChatId := int64(userId)
+
+	if query.CallbackQuery.Data != quietSelfCallbackId {
+		target, err := h.getScopeTarget(userId, query.CallbackQuery.Data)
+
+		if err != nil {
+			h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+			return tgbotapi.CallbackConfig{
+				CallbackQueryID: query.CallbackQuery.ID,
+				Text:            "Помилка під час виконання запиту",
+			}
+		}
+
+		targetChatId = target.ChatId
+	}
+
+	go h.api.executeMessage(h.prepareQuietHoursMessage(userId, query.CallbackQuery.Message.Chat.ID, targetChatId))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+// prepareQuietHoursMessage remembers the chat being configured, it is the private chat of the user
+// or a chat or channel of the group
+func (h *Handler) prepareQuietHoursMessage(userId int, chatId int64, targetChatId int64) tgbotapi.MessageConfig {
+	h.quietHoursRequests[userId] = targetChatId
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.QuietHoursCommand,
+		Action:  actions.UserActionChooseQuietHours,
+	})
+
+	msg := tgbotapi.NewMessage(chatId, "У тихі години та в режимі \"не турбувати\" сповіщення відкладаються до їх завершення, "+
+		"а нагадування про пари надсилаються, лише якщо це дозволено. Оберіть налаштування та натисніть \"Готово\"")
+	msg.ReplyMarkup = prepareQuietHoursKeyboard(h.preferences.GetQuietHours(targetChatId))
+	return msg
+}
+
+func (h *Handler) handleChooseQuietHours(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+	targetChatId := h.quietHoursRequests[userId]
+
+	switch query.CallbackQuery.Data {
+	case DoneCallbackId:
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+		delete(h.quietHoursRequests, userId)
+
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Налаштування збережено",
+		}
+	case quietHoursCallbackId:
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+			Command: commands.QuietHoursCommand,
+			Action:  actions.UserActionInputQuietHours,
+		})
+
+		return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+	case quietMuteDateCallbackId:
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+			Command: commands.QuietHoursCommand,
+			Action:  actions.UserActionInputMutedUntil,
+		})
+
+		return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+	}
+
+	quiet := h.preferences.GetQuietHours(targetChatId)
+	now := time.Now()
+
+	switch query.CallbackQuery.Data {
+	case quietClearHoursCallbackId:
+		quiet.QuietFrom, quiet.QuietTo = 0, 0
+	case quietMuteHourCallbackId:
+		quiet.MutedUntil = now.Add(time.Hour)
+	case quietMuteMorningCallbackId:
+		quiet.MutedUntil = nextMorning(now, quiet)
+	case quietMuteMondayCallbackId:
+		quiet.MutedUntil = nextMonday(now, quiet)
+	case quietUnmuteCallbackId:
+		quiet.MutedUntil = time.Time{}
+	case quietBreakThroughCallbackId:
+		quiet.RemindersBreakThrough = !quiet.RemindersBreakThrough
+	}
+
+	if err := h.preferences.UpdateQuietHours(dto.UpdateQuietHoursRequest{ChatId: targetChatId, Quiet: quiet}); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	go h.api.executeEdit(tgbotapi.NewEditMessageReplyMarkup(
+		query.CallbackQuery.Message.Chat.ID,
+		query.CallbackQuery.Message.MessageID,
+		prepareQuietHoursKeyboard(quiet)))
+
+	return tgbotapi.CallbackConfig{CallbackQueryID: query.CallbackQuery.ID}
+}
+
+func (h *Handler) handleActionInputQuietHours(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	bounds := strings.FieldsFunc(upd.Message.Text, func(r rune) bool { return r == '-' || r == '–' || r == ' ' })
+
+	if len(bounds) != 2 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, введіть, наприклад: 22:00-08:00")}
+	}
+
+	from, errFrom := time.Parse(quietHoursLayout, bounds[0])
+	to, errTo := time.Parse(quietHoursLayout, bounds[1])
+
+	if errFrom != nil || errTo != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, введіть, наприклад: 22:00-08:00")}
+	}
+
+	targetChatId := h.quietHoursRequests[userId]
+	quiet := h.preferences.GetQuietHours(targetChatId)
+	quiet.QuietFrom = sinceMidnight(from)
+	quiet.QuietTo = sinceMidnight(to)
+
+	return h.saveQuietHours(userId, upd.Message.Chat.ID, targetChatId, quiet)
+}
+
+func (h *Handler) handleActionInputMutedUntil(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	until, err := time.ParseInLocation(examTimeLayout, strings.TrimSpace(upd.Message.Text), time.Local)
+
+	if err != nil || !until.After(time.Now()) {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			"Невірні дані, введіть майбутню дату та час у форматі 2024-01-31 08:00")}
+	}
+
+	targetChatId := h.quietHoursRequests[userId]
+	quiet := h.preferences.GetQuietHours(targetChatId)
+	quiet.MutedUntil = until
+
+	return h.saveQuietHours(userId, upd.Message.Chat.ID, targetChatId, quiet)
+}
+
+// saveQuietHours saves the text input and shows the settings again, so the user may continue
+func (h *Handler) saveQuietHours(userId int, chatId int64, targetChatId int64, quiet dto.QuietHoursDto) []tgbotapi.MessageConfig {
+	if err := h.preferences.UpdateQuietHours(dto.UpdateQuietHoursRequest{ChatId: targetChatId, Quiet: quiet}); err != nil {
+		h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+		delete(h.quietHoursRequests, userId)
+
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(chatId, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{h.prepareQuietHoursMessage(userId, chatId, targetChatId)}
+}
+
+// getScopeTarget returns the chat or channel of the active group of the user
+func (h *Handler) getScopeTarget(userId int, data string) (*dto.PublicationTargetDto, error) {
+	chatId, err := strconv.ParseInt(data, 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := h.groups.GetPublicationTargets(h.scope(userId).GroupId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		if target.ChatId == chatId {
+			return &target, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func prepareQuietHoursKeyboard(quiet dto.QuietHoursDto) tgbotapi.InlineKeyboardMarkup {
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	if quiet.QuietFrom != quiet.QuietTo {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("Тихі години: %s-%s, змінити", formatSinceMidnight(quiet.QuietFrom), formatSinceMidnight(quiet.QuietTo)),
+				quietHoursCallbackId)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Прибрати тихі години", quietClearHoursCallbackId)))
+	} else {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Встановити тихі години", quietHoursCallbackId)))
+	}
+
+	if time.Now().Before(quiet.MutedUntil) {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Не турбувати до "+quiet.MutedUntil.Format(examTimeLayout)+", скасувати",
+				quietUnmuteCallbackId)))
+	} else {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Не турбувати годину", quietMuteHourCallbackId)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Не турбувати до ранку", quietMuteMorningCallbackId)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Не турбувати до понеділка", quietMuteMondayCallbackId)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Не турбувати до дати", quietMuteDateCallbackId)))
+	}
+
+	breakThrough := "Нагадування про пари в тихий час"
+
+	if quiet.RemindersBreakThrough {
+		breakThrough = "✅ " + breakThrough
+	}
+
+	keys.InlineKeyboard = append(keys.InlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(breakThrough, quietBreakThroughCallbackId)),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Готово", DoneCallbackId)))
+
+	return keys
+}
+
+// nextMorning returns the next end of the quiet hours, or 8:00 without them
+func nextMorning(now time.Time, quiet dto.QuietHoursDto) time.Time {
+	morning := defaultMorning
+
+	if quiet.QuietFrom != quiet.QuietTo {
+		morning = quiet.QuietTo
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if result := midnight.Add(morning); result.After(now) {
+		return result
+	}
+
+	return midnight.AddDate(0, 0, 1).Add(morning)
+}
+
+// nextMonday returns the morning of the next monday
+func nextMonday(now time.Time, quiet dto.QuietHoursDto) time.Time {
+	days := (int(time.Monday) - int(now.Weekday()) + 7) % 7
+
+	if days == 0 {
+		days = 7
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+days-1, 0, 0, 0, 0, now.Location())
+
+	return nextMorning(midnight.Add(23*time.Hour), quiet)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+func formatSinceMidnight(duration time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(duration.Hours()), int(duration.Minutes())%60)
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"testing"
+	"time"
+)
+
+type fakeQuietPreferences struct {
+	abstractions.IPreferencesService
+	check dto.QuietCheckDto
+}
+
+func (f *fakeQuietPreferences) CheckQuietHours(chatId int64, at time.Time) dto.QuietCheckDto {
+	return f.check
+}
+
+type fakeDeferredProvider struct {
+	models map[string]dao.DeferredMessageModel
+	lastId int
+}
+
+func (f *fakeDeferredProvider) SaveDeferred(model dao.DeferredMessageModel) (string, error) {
+	f.lastId++
+	model.Id = strconv.Itoa(f.lastId)
+	f.models[model.Id] = model
+	return model.Id, nil
+}
+
+func (f *fakeDeferredProvider) GetDeferred() ([]dao.DeferredMessageModel, error) {
+	var result []dao.DeferredMessageModel
+
+	for _, model := range f.models {
+		result = append(result, model)
+	}
+
+	return result, nil
+}
+
+func (f *fakeDeferredProvider) DeleteDeferred(id string) error {
+	delete(f.models, id)
+	return nil
+}
+
+func TestDecideQuietAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      dto.QuietCheckDto
+		isReminder bool
+		want       quietAction
+	}{
+		{name: "notification out of the quiet time", want: quietActionSend},
+		{name: "reminder out of the quiet time", isReminder: true, want: quietActionSend},
+		{name: "notification in the quiet time", check: dto.QuietCheckDto{IsQuiet: true}, want: quietActionDefer},
+		{name: "notification ignores the break through", check: dto.QuietCheckDto{IsQuiet: true, RemindersBreakThrough: true}, want: quietActionDefer},
+		{name: "reminder in the quiet time", check: dto.QuietCheckDto{IsQuiet: true}, isReminder: true, want: quietActionDrop},
+		{name: "reminder breaks through", check: dto.QuietCheckDto{IsQuiet: true, RemindersBreakThrough: true}, isReminder: true, want: quietActionSend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideQuietAction(tt.check, tt.isReminder); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendDeferrable(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	deferred := &fakeDeferredProvider{models: map[string]dao.DeferredMessageModel{}}
+	api := &Api{
+		preferences: &fakeQuietPreferences{check: dto.QuietCheckDto{IsQuiet: true, Until: until}},
+		deferred:    deferred,
+		metrics:     &deliveryMetrics{},
+	}
+
+	msgs := prepareLongMessages(42, []string{strings.Repeat("a", 3000), strings.Repeat("b", 3000)})
+
+	if err := api.sendDeferrable(priorityBulk, msgs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deferred.models) != 1 || api.metrics.deferred != 1 {
+		t.Fatalf("expected the messages to be stored once, got %v", deferred.models)
+	}
+
+	model := deferred.models["1"]
+
+	if model.ChatId != 42 || len(model.Texts) != 2 || model.Texts[1] != msgs[1].Text ||
+		!model.Until.Equal(until) || sendPriority(model.Priority) != priorityBulk {
+		t.Errorf("unexpected stored messages %v", model)
+	}
+
+	// the quiet time is prolonged, so the messages wait again and the previous copy is removed
+	api.sendPostponed(model)
+
+	if _, ok := deferred.models["1"]; ok || len(deferred.models) != 1 || deferred.models["2"].Texts[0] != msgs[0].Text {
+		t.Errorf("expected the messages to be stored again, got %v", deferred.models)
+	}
+}
+
+func TestSendReminderDropped(t *testing.T) {
+	api := &Api{
+		preferences: &fakeQuietPreferences{check: dto.QuietCheckDto{IsQuiet: true, Until: time.Now().Add(time.Hour)}},
+		metrics:     &deliveryMetrics{},
+	}
+
+	if err := api.sendReminder(tgbotapi.NewMessage(42, "reminder")); err != errQuietHours || api.metrics.dropped != 1 {
+		t.Errorf("expected the reminder to be dropped, got %v", err)
+	}
+}
+
+func TestNextMorning(t *testing.T) {
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local) // wednesday
+	night := dto.QuietHoursDto{QuietFrom: 22 * time.Hour, QuietTo: 7 * time.Hour}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		quiet  dto.QuietHoursDto
+		monday bool
+		want   time.Time
+	}{
+		{name: "evening without quiet hours", now: day.Add(23 * time.Hour), want: day.AddDate(0, 0, 1).Add(defaultMorning)},
+		{name: "after midnight without quiet hours", now: day.Add(time.Hour), want: day.Add(defaultMorning)},
+		{name: "evening with quiet hours", now: day.Add(23 * time.Hour), quiet: night, want: day.AddDate(0, 0, 1).Add(7 * time.Hour)},
+		{name: "after midnight with quiet hours", now: day.Add(2 * time.Hour), quiet: night, want: day.Add(7 * time.Hour)},
+		{name: "morning is over", now: day.Add(9 * time.Hour), quiet: night, want: day.AddDate(0, 0, 1).Add(7 * time.Hour)},
+		{name: "monday from wednesday", now: day.Add(12 * time.Hour), monday: true, want: day.AddDate(0, 0, 5).Add(defaultMorning)},
+		{name: "monday from monday", now: day.AddDate(0, 0, -2).Add(12 * time.Hour), monday: true, want: day.AddDate(0, 0, 5).Add(defaultMorning)},
+		{name: "monday from sunday night", now: day.AddDate(0, 0, 4).Add(23 * time.Hour), quiet: night, monday: true, want: day.AddDate(0, 0, 5).Add(7 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextMorning(tt.now, tt.quiet)
+
+			if tt.monday {
+				got = nextMonday(tt.now, tt.quiet)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+)
+
+func (h *Handler) handleCommandSaveSnapshot(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.SaveScheduleSnapshotCommand,
+		Action:  actions.UserActionInputSnapshotName,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть назву знімку розкладу, наприклад \"Осінь 2024\"")}
+}
+
+func (h *Handler) handleActionInputSnapshotName(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if _, err := h.scope(userId).Schedule.SaveScheduleSnapshot(dto.CreateScheduleSnapshotRequest{Name: upd.Message.Text}); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час виконання запиту трапилась помилка "+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Знімок розкладу збережено")}
+}
+
+func (h *Handler) handleGetSnapshotsCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	snapshots, err := h.scope(userId).Schedule.GetScheduleSnapshots()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if len(snapshots.Snapshots) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Збережених знімків розкладу немає")}
+	}
+
+	parts := []string{"Знімки розкладу"}
+
+	for _, val := range snapshots.Snapshots {
+		parts = append(parts, fmt.Sprintf("\n %s \n Створено: %s \n Пар: %d, курсів: %d \n",
+			val.Name, val.CreatedAt.Format(examTimeLayout), val.SchedulesCount, val.CoursesCount))
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleCommandChooseSnapshot(userId int, upd tgbotapi.Update, command commands.CommandType) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	snapshots, err := h.scope(userId).Schedule.GetScheduleSnapshots()
+
+	if err != nil || len(snapshots.Snapshots) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Збережених знімків розкладу немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: command,
+		Action:  actions.UserActionChooseSnapshot,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, val := range snapshots.Snapshots {
+		keys.InlineKeyboard = append(keys.InlineKeyboard,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(val.Name, val.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть знімок розкладу")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseSnapshotForClone(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	h.cloneSnapshotRequests[query.CallbackQuery.From.ID] = dto.CloneScheduleSnapshotRequest{SnapshotId: query.CallbackQuery.Data}
+
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{
+		Command: commands.CloneScheduleSnapshotCommand,
+		Action:  actions.UserActionConfirm,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Знімок обрано",
+	}
+}
+
+func (h *Handler) handleConfirmCloneSnapshot(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	req := h.cloneSnapshotRequests[userId]
+	delete(h.cloneSnapshotRequests, userId)
+
+	if query.CallbackQuery.Data != ConfirmCallbackId {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Клонування відмінено",
+		}
+	}
+
+	if err := h.scope(userId).Schedule.CloneScheduleSnapshot(req); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Розклад нового семестру створено в чернетці",
+	}
+}
+
+func (h *Handler) handleChooseSnapshotForDelete(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	h.actions.SaveUserCurrentState(query.CallbackQuery.From.ID, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if err := h.scope(query.CallbackQuery.From.ID).Schedule.DeleteScheduleSnapshot(dto.DeleteScheduleSnapshotRequest{SnapshotId: query.CallbackQuery.Data}); err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Знімок видалено",
+	}
+}
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+)
+
+const (
+	wholeGroupSubgroupText = "Уся група"
+	noSubgroupText         = "-"
+)
+
+func (h *Handler) handleCommandCreateSubgroup(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CreateSubgroupCommand,
+		Action:  actions.UserActionInputSubgroupName,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Введіть назву підгрупи, наприклад A")}
+}
+
+func (h *Handler) handleCommandDeleteSubgroup(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	subgroups, err := h.scope(userId).Subgroups.GetSubgroups()
+
+	if err != nil || len(subgroups.Subgroups) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Підгруп немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.DeleteSubgroupCommand,
+		Action:  actions.UserActionInputSubgroupName,
+	})
+
+	markup := tgbotapi.NewReplyKeyboard()
+
+	for _, subgroup := range subgroups.Subgroups {
+		markup.Keyboard = append(markup.Keyboard,
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(subgroup.Name)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть підгрупу, студентів буде прибрано з неї")
+	msg.ReplyMarkup = markup
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleActionInputSubgroupName(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	var err error
+	text := "Підгрупу створено. Розподіліть студентів командою /" + string(commands.AssignSubgroupCommand)
+
+	if action.Command == commands.DeleteSubgroupCommand {
+		err = h.scope(userId).Subgroups.DeleteSubgroup(dto.DeleteSubgroupRequest{Name: upd.Message.Text})
+		text = "Підгрупу видалено"
+	} else {
+		err = h.scope(userId).Subgroups.CreateSubgroup(dto.CreateSubgroupRequest{Name: upd.Message.Text})
+	}
+
+	if err != nil && err.Error() == "SubgroupInUse" {
+		text = "Підгрупа використовується в розкладі, спочатку приберіть її пари"
+	} else if err != nil {
+		text = "Під час запиту сталася помилка" + err.Error()
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, text)
+	msg.ReplyMarkup = tgbotapi.ReplyKeyboardRemove{RemoveKeyboard: true}
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleGetSubgroupsCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	scope := h.scope(userId)
+	subgroups, err := scope.Subgroups.GetSubgroups()
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if len(subgroups.Subgroups) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Підгруп немає")}
+	}
+
+	own := scope.Subgroups.GetStudentSubgroup(userId)
+
+	if own == "" {
+		own = "не призначено"
+	}
+
+	parts := []string{"Ваша підгрупа: " + own + "\n"}
+
+	for _, subgroup := range subgroups.Subgroups {
+		parts = append(parts, fmt.Sprintf("\nПідгрупа %s (%d):", subgroup.Name, len(subgroup.Students)))
+
+		for _, student := range subgroup.Students {
+			parts = append(parts, "\n  "+formatStudent(student))
+		}
+
+		parts = append(parts, "\n")
+	}
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleCommandAssignSubgroup(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.adminAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.AssignSubgroupCommand,
+		Action:  actions.UserActionInputSubgroupMember,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Введіть id або username студента та назву підгрупи через пробіл, \""+noSubgroupText+"\" замість назви прибирає студента з підгрупи")}
+}
+
+func (h *Handler) handleActionInputSubgroupMember(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	fields := strings.Fields(upd.Message.Text)
+
+	if len(fields) != 2 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви ввели невірні значення")}
+	}
+
+	memberId, err := strconv.Atoi(fields[0])
+
+	if err != nil {
+		user, err := h.users.GetUserByUserName(fields[0])
+
+		if err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+				"Користувача не знайдено, він має спочатку написати боту, або введіть його id")}
+		}
+
+		memberId = user.Id
+	}
+
+	scope := h.scope(userId)
+
+	if !containsUserId(scope.MemberIds, memberId) {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Користувач не є учасником групи")}
+	}
+
+	subgroup := fields[1]
+
+	if subgroup == noSubgroupText {
+		subgroup = ""
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	if err = scope.Subgroups.AssignStudent(dto.AssignSubgroupRequest{UserId: memberId, Subgroup: subgroup}); err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Підгрупу студента збережено")}
+}
+
+// prepareChooseSubgroupMessage asks for the subgroup of the lesson, the step is skipped when the group has no subgroups
+func (h *Handler) prepareChooseSubgroupMessage(userId int, chatId int64) (tgbotapi.MessageConfig, bool) {
+	subgroups, err := h.scope(userId).Subgroups.GetSubgroups()
+
+	if err != nil || len(subgroups.Subgroups) == 0 {
+		return tgbotapi.MessageConfig{}, false
+	}
+
+	markup := tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(wholeGroupSubgroupText)))
+
+	for _, subgroup := range subgroups.Subgroups {
+		markup.Keyboard = append(markup.Keyboard,
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(subgroup.Name)))
+	}
+
+	msg := tgbotapi.NewMessage(chatId, "Оберіть підгрупу, для якої буде пара")
+	msg.ReplyMarkup = markup
+	return msg, true
+}
+
+func (h *Handler) handleActionInputSubgroup(action dto.UserActionDto, userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	subgroup := ""
+
+	if upd.Message.Text != wholeGroupSubgroupText {
+		subgroups, err := h.scope(userId).Subgroups.GetSubgroups()
+
+		if err != nil || !containsSubgroup(subgroups.Subgroups, upd.Message.Text) {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невірні дані, спробуйте ще раз")}
+		}
+
+		subgroup = upd.Message.Text
+	}
+
+	if action.Command == commands.CreateAdditionalScheduleCommand {
+		req := h.createAddScheduleRequests[userId]
+		req.Subgroup = subgroup
+		h.createAddScheduleRequests[userId] = req
+
+		return h.prepareInputReplacementDateMessages(userId, upd.Message.Chat.ID)
+	}
+
+	req := h.createScheduleRequests[userId]
+	req.Subgroup = subgroup
+	h.createScheduleRequests[userId] = req
+
+	return h.saveNewSchedule(userId, upd.Message.Chat.ID)
+}
+
+func containsSubgroup(subgroups []dto.SubgroupDto, name string) bool {
+	for _, subgroup := range subgroups {
+		if subgroup.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUserId(ids []int, id int) bool {
+	for _, val := range ids {
+		if val == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatSubgroup(subgroup string) string {
+	if subgroup == "" {
+		return ""
+	}
+
+	return " (підгрупа " + subgroup + ")"
+}
@@ -0,0 +1,328 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/actions"
+	"telegram-notification-bot-core/commands"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// teacherClassesDays is how many days from today /my_classes and /cancel_class cover
+const teacherClassesDays = 7
+
+// handleTeacherCommand serves the commands available to teachers, who may be not members of any group
+func (h *Handler) handleTeacherCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	if upd.Message.Command() == string(commands.CancelCommand) {
+		return h.handleCancelCommand(userId, upd)
+	}
+
+	if authenticated := h.teacherAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Команда доступна лише викладачам")}
+	}
+
+	switch upd.Message.Command() {
+	case string(commands.GetMyClassesCommand):
+		return h.handleGetMyClassesCommand(userId, upd)
+	case string(commands.CancelClassCommand):
+		return h.handleCommandCancelClass(userId, upd)
+	case string(commands.SetMeetLinkCommand):
+		return h.handleCommandSetMeetLink(userId, upd)
+	case string(commands.RemindersCommand):
+		return h.handleRemindersCommand(userId, upd)
+	case string(commands.QuietHoursCommand):
+		return h.handleQuietHoursCommand(userId, upd)
+	default:
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Невідома команда")}
+	}
+}
+
+func (h *Handler) handleCommandAddTeacher(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+
+	if authenticated := h.ownerAuth(userId); !authenticated {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви не маєте прав, зверніться до власника")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.AddTeacherCommand,
+		Action:  actions.UserActionInputTeacher,
+	})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Введіть id або username викладача та ім'я, як воно вказане в курсах, через пробіл")}
+}
+
+func (h *Handler) handleActionInputTeacher(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	fields := strings.Fields(upd.Message.Text)
+
+	if len(fields) < 2 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Ви ввели невірні значення")}
+	}
+
+	teacherUserId, err := strconv.Atoi(fields[0])
+	contact := ""
+
+	if err != nil {
+		user, err := h.users.GetUserByUserName(fields[0])
+
+		if err != nil {
+			return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+				"Користувача не знайдено, він має спочатку написати боту, або введіть його id")}
+		}
+
+		teacherUserId = user.Id
+		contact = "@" + user.UserName
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	_, err = h.teachers.RegisterTeacher(dto.RegisterTeacherRequest{
+		UserId:  teacherUserId,
+		Name:    strings.Join(fields[1:], " "),
+		Contact: contact,
+	})
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+		"Викладача додано, його пари: /"+string(commands.GetMyClassesCommand))}
+}
+
+func (h *Handler) handleGetMyClassesCommand(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	classes, err := h.getUpcomingClasses(userId)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	if len(classes.Lessons) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID,
+			fmt.Sprintf("Пар на найближчі %d днів немає", teacherClassesDays))}
+	}
+
+	parts := []string{fmt.Sprintf("Ваші пари на найближчі %d днів:\n", teacherClassesDays)}
+
+	for _, lesson := range classes.Lessons {
+		parts = append(parts, "\n "+formatTeacherLesson(lesson)+
+			fmt.Sprintf("\n  Посилання на зустріч: %s", lesson.CourseInfo.MeetLink))
+	}
+
+	parts = append(parts, fmt.Sprintf("\n\nСкасувати пару: /%s, змінити посилання: /%s",
+		commands.CancelClassCommand, commands.SetMeetLinkCommand))
+
+	return prepareLongMessages(upd.Message.Chat.ID, parts)
+}
+
+func (h *Handler) handleCommandCancelClass(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	classes, err := h.getUpcomingClasses(userId)
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+	electives := ""
+
+	for _, lesson := range classes.Lessons {
+		// optional slots are shared with other courses, they are cancelled by admins
+		if lesson.IsOptional {
+			electives += "\n " + formatTeacherLesson(lesson)
+			continue
+		}
+
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(formatTeacherLesson(lesson), fmt.Sprintf("%s|%d|%s",
+				lesson.Date.Format(dateLayout), lesson.Order, lesson.CourseInfo.Id))))
+	}
+
+	if electives != "" {
+		electives = "\n\nПари курсів за вибором спільні для кількох курсів, їх скасовує адміністратор групи:" + electives
+	}
+
+	if len(keys.InlineKeyboard) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Пар, які можна скасувати, немає"+electives)}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.CancelClassCommand,
+		Action:  actions.UserActionChooseClass,
+	})
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть пару, яку потрібно скасувати, студентів буде повідомлено"+electives)
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseClassForCancel(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{Action: actions.UserActionNone})
+
+	req, err := parseClassCallbackData(query.CallbackQuery.Data)
+
+	if err == nil {
+		req.UserId = userId
+	}
+
+	var result *dto.CancelClassResponse
+	var scope abstractions.GroupScope
+
+	if err == nil {
+		scope, err = h.getTeacherCourseScope(userId, req.CourseId)
+	}
+
+	if err == nil {
+		err = h.trackScheduleChanges(scope, func() error {
+			var err error
+			result, err = h.teachers.CancelClass(req)
+			return err
+		})
+	}
+
+	if err == exceptions.ElectiveNotCancellable {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Пари курсів за вибором скасовує адміністратор групи",
+		}
+	}
+
+	if err != nil {
+		return tgbotapi.CallbackConfig{
+			CallbackQueryID: query.CallbackQuery.ID,
+			Text:            "Помилка під час виконання запиту",
+		}
+	}
+
+	teacher, _ := h.teachers.GetTeacher(userId)
+	parts := []string{"Викладач " + teacher.Name + " скасував пари:"}
+
+	for _, lesson := range result.Lessons {
+		parts = append(parts, formatCancelledLesson(lesson))
+	}
+
+	h.broadcastChange(scope, cancelledLessonDates(result.Lessons), parts)
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Пару скасовано",
+	}
+}
+
+func (h *Handler) handleCommandSetMeetLink(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	courses, err := h.teachers.GetTeacherCourses(userId)
+
+	if err != nil || len(courses.Courses) == 0 {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Курсів немає")}
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.SetMeetLinkCommand,
+		Action:  actions.UserActionChooseCourse,
+	})
+
+	keys := tgbotapi.NewInlineKeyboardMarkup()
+
+	for _, course := range courses.Courses {
+		keys.InlineKeyboard = append(keys.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(course.CourseInfo.Name+", "+course.GroupName, course.CourseInfo.Id)))
+	}
+
+	msg := tgbotapi.NewMessage(upd.Message.Chat.ID, "Оберіть курс")
+	msg.ReplyMarkup = keys
+	return []tgbotapi.MessageConfig{msg}
+}
+
+func (h *Handler) handleChooseCourseForMeetLink(query tgbotapi.Update) tgbotapi.CallbackConfig {
+	userId := query.CallbackQuery.From.ID
+
+	h.teacherMeetLinkRequests[userId] = dto.UpdateTeacherMeetLinkRequest{
+		UserId:   userId,
+		CourseId: query.CallbackQuery.Data,
+	}
+
+	h.actions.SaveUserCurrentState(userId, dto.UserActionDto{
+		Command: commands.SetMeetLinkCommand,
+		Action:  actions.UserActionInputMeetLink,
+	})
+
+	return tgbotapi.CallbackConfig{
+		CallbackQueryID: query.CallbackQuery.ID,
+		Text:            "Курс обрано",
+	}
+}
+
+func (h *Handler) handleActionInputTeacherMeetLink(userId int, upd tgbotapi.Update) []tgbotapi.MessageConfig {
+	req := h.teacherMeetLinkRequests[userId]
+	req.MeetLink = strings.TrimSpace(upd.Message.Text)
+	delete(h.teacherMeetLinkRequests, userId)
+
+	var result *dto.UpdateTeacherMeetLinkResponse
+	scope, err := h.getTeacherCourseScope(userId, req.CourseId)
+
+	if err == nil {
+		err = h.trackScheduleChanges(scope, func() error {
+			var err error
+			result, err = h.teachers.UpdateMeetLink(req)
+			return err
+		})
+	}
+
+	if err != nil {
+		return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Під час запиту сталася помилка"+err.Error())}
+	}
+
+	h.broadcastToTargets(scope, broadcastAnnouncements, []string{fmt.Sprintf(
+		"Нове посилання на зустріч з курсу %s: %s", result.CourseInfo.Name, result.CourseInfo.MeetLink)})
+
+	return []tgbotapi.MessageConfig{tgbotapi.NewMessage(upd.Message.Chat.ID, "Посилання оновлено, студентів повідомлено")}
+}
+
+func (h *Handler) teacherAuth(userId int) bool {
+	_, err := h.teachers.GetTeacher(userId)
+	return err == nil
+}
+
+func (h *Handler) getUpcomingClasses(userId int) (*dto.GetTeacherClassesResponse, error) {
+	today := util.GetMidnightTime()
+	return h.teachers.GetTeacherClasses(userId, today, today.AddDate(0, 0, teacherClassesDays-1))
+}
+
+func parseClassCallbackData(data string) (dto.CancelClassRequest, error) {
+	fields := strings.SplitN(data, "|", 3)
+
+	if len(fields) != 3 {
+		return dto.CancelClassRequest{}, errors.New("InvalidData")
+	}
+
+	date, err := time.ParseInLocation(dateLayout, fields[0], time.Local)
+
+	if err != nil {
+		return dto.CancelClassRequest{}, err
+	}
+
+	order, err := strconv.Atoi(fields[1])
+
+	if err != nil {
+		return dto.CancelClassRequest{}, err
+	}
+
+	return dto.CancelClassRequest{CourseId: fields[2], Date: date, Order: order}, nil
+}
+
+func formatTeacherLesson(lesson dto.TeacherLessonDto) string {
+	return fmt.Sprintf("%s (%s) № %d. %s, %s",
+		lesson.Date.Format(dateLayout),
+		util.ConvertToHumanReadableWeek(lesson.Date.Weekday()),
+		lesson.Order,
+		lesson.CourseInfo.Name+formatSubgroup(lesson.Subgroup),
+		lesson.GroupName)
+}
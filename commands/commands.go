@@ -14,4 +14,50 @@ const (
 	CancelCommand                   CommandType = "cancel"
 	ClearScheduleCommand            CommandType = "clear_schedule"
 	LinkOptionalCourseCommand       CommandType = "link_optional_course"
+	CreateExamCommand               CommandType = "create_exam"
+	UpdateExamCommand               CommandType = "update_exam"
+	DeleteExamCommand               CommandType = "delete_exam"
+	GetExamsCommand                 CommandType = "exams"
+	CancelRangeCommand              CommandType = "cancel_range"
+	SaveScheduleSnapshotCommand     CommandType = "save_schedule_snapshot"
+	GetScheduleSnapshotsCommand     CommandType = "schedule_snapshots"
+	CloneScheduleSnapshotCommand    CommandType = "clone_schedule_snapshot"
+	DeleteScheduleSnapshotCommand   CommandType = "delete_schedule_snapshot"
+	GetDraftDiffCommand             CommandType = "draft_diff"
+	PublishDraftCommand             CommandType = "publish"
+	DiscardDraftCommand             CommandType = "discard_draft"
+	GetMyOptionalCoursesCommand     CommandType = "my_optional_courses"
+	CreateElectivePoolCommand       CommandType = "create_elective_pool"
+	GetElectivePoolsCommand         CommandType = "elective_pools"
+	DeleteElectivePoolCommand       CommandType = "delete_elective_pool"
+	SetCourseCapacityCommand        CommandType = "set_course_capacity"
+	OpenElectiveSelectionCommand    CommandType = "open_elective_selection"
+	AutoAssignElectivesCommand      CommandType = "auto_assign_electives"
+	ImportElectivesCommand          CommandType = "import_electives"
+	CreateGroupCommand              CommandType = "create_group"
+	GetGroupsCommand                CommandType = "groups"
+	SwitchGroupCommand              CommandType = "switch_group"
+	AddGroupMemberCommand           CommandType = "add_group_member"
+	RemoveGroupMemberCommand        CommandType = "remove_group_member"
+	BindChatCommand                 CommandType = "bind_chat"
+	UnbindChatCommand               CommandType = "unbind_chat"
+	ChatSettingsCommand             CommandType = "chat_settings"
+	CreateSubgroupCommand           CommandType = "create_subgroup"
+	DeleteSubgroupCommand           CommandType = "delete_subgroup"
+	GetSubgroupsCommand             CommandType = "subgroups"
+	AssignSubgroupCommand           CommandType = "assign_subgroup"
+	AddTeacherCommand               CommandType = "add_teacher"
+	GetMyClassesCommand             CommandType = "my_classes"
+	CancelClassCommand              CommandType = "cancel_class"
+	SetMeetLinkCommand              CommandType = "set_meet_link"
+	DeliveryStatsCommand            CommandType = "delivery_stats"
+	RemindersCommand                CommandType = "reminders"
+	QuietHoursCommand               CommandType = "quiet_hours"
+	DigestCommand                   CommandType = "digest"
+	MuteCommand                     CommandType = "mute"
+	AddChannelCommand               CommandType = "add_channel"
+	RemoveChannelCommand            CommandType = "remove_channel"
+	ChannelSettingsCommand          CommandType = "channel_settings"
+	GetElectiveRosterCommand        CommandType = "elective_roster"
+	ExportElectiveRosterCommand     CommandType = "elective_roster_csv"
 )
@@ -4,8 +4,8 @@ import "time"
 
 type Configuration struct {
 	Security struct {
-		AllowedAccountIds []int `yaml:"allowed-account-ids" env:"ALLOWED_ACCOUNT_IDS"` //todo: for allowed talks with bot and receiving pushes
-		TrustedAccountIds []int `yaml:"trusted-account-ids" env:"TRUSTED_ACCOUNT_IDS"` // for admin operations
+		AllowedAccountIds []int `yaml:"allowed-account-ids" env:"ALLOWED_ACCOUNT_IDS"` // members of the default group
+		TrustedAccountIds []int `yaml:"trusted-account-ids" env:"TRUSTED_ACCOUNT_IDS"` // admins of the default group, they also manage groups
 	} `envPrefix:"SECURITY_"`
 
 	ScheduleSettings struct {
@@ -15,8 +15,19 @@ type Configuration struct {
 		} `yaml:"time-slots-configuration" envPrefix:"TIMESLOTS_"`
 
 		ScheduleRefreshInterval time.Duration `yaml:"schedule-refresh-interval" env:"REFRESH_INTERVAL"`
-		ReminderIntervals       []int         `yaml:"reminder-intervals" env:"REMINDER_INTERVALS"` // in minutes
+		ReminderIntervals       []int         `yaml:"reminder-intervals" env:"REMINDER_INTERVALS"`             // in minutes
+		ExamReminderIntervals   []int         `yaml:"exam-reminder-intervals" env:"EXAM_REMINDER_INTERVALS"`   // in days
+		DailyScheduleTime       time.Duration `yaml:"daily-schedule-time" env:"DAILY_SCHEDULE_TIME"`           // since midnight, for chats and channels
+		DigestTime              time.Duration `yaml:"digest-time" env:"DIGEST_TIME"`                           // since midnight, the default time of the morning digest, 07:30 when not set
+		ChangeNotificationDays  int           `yaml:"change-notification-days" env:"CHANGE_NOTIFICATION_DAYS"` // the changes of the lessons of today and the following days are sent at once, 2 when not set
+		PreviewTime             time.Duration `yaml:"preview-time" env:"PREVIEW_TIME"`                         // since midnight, the default time of the evening preview, 20:00 when not set
+		Holidays                []string      `yaml:"holidays" env:"HOLIDAYS"`                                 // the dates without classes in the format 2006-01-02
 	} `yaml:"schedule-settings" envPrefix:"SCHEDULE_"`
 
+	ElectiveSettings struct {
+		WaitlistEnabled           bool          `yaml:"waitlist-enabled" env:"WAITLIST_ENABLED"` // otherwise students are refused when a course is full
+		SelectionReminderInterval time.Duration `yaml:"selection-reminder-interval" env:"SELECTION_REMINDER_INTERVAL"`
+	} `yaml:"elective-settings" envPrefix:"ELECTIVE_"`
+
 	TelegramTokenBot string `yaml:"telegram-token-bot"`
 }
@@ -8,4 +8,5 @@ type AdditionalScheduleModel struct {
 	Order          int
 	CourseId       string
 	IsEmpty        bool
+	Subgroup       string // empty for replacements of the whole group
 }
@@ -4,7 +4,9 @@ type CourseModel struct {
 	Id             string
 	Name           string
 	TeacherName    string
+	TeacherId      string // set when a registered teacher has the teacher name
 	TeacherContact string
 	MeetLink       string
 	IsOptional     bool
+	Capacity       int // seats of an optional course in every optional slot, zero means unlimited
 }
@@ -0,0 +1,24 @@
+package dao
+
+import "time"
+
+// ElectivePoolModel restricts which optional courses can be chosen for the optional schedule slots
+type ElectivePoolModel struct {
+	Id          string
+	Name        string
+	CourseIds   []string
+	ScheduleIds []string
+}
+
+// ElectiveWaitlistModel keeps the students waiting for a seat of the full course in the optional slot
+type ElectiveWaitlistModel struct {
+	ScheduleId string
+	CourseId   string
+	UserIds    []int
+}
+
+// ElectiveSelectionWindowModel limits the time students can choose optional courses themselves
+type ElectiveSelectionWindowModel struct {
+	Deadline       time.Time
+	LastReminderAt time.Time
+}
@@ -0,0 +1,14 @@
+package dao
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+type ExamModel struct {
+	Id        string
+	CourseId  string
+	Kind      util.ExamKind
+	StartTime time.Time
+	Room      string
+}
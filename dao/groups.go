@@ -0,0 +1,32 @@
+package dao
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// DefaultGroupId identifies the group which owns the data created before groups were introduced,
+// its members and admins are also taken from the configuration
+const DefaultGroupId = "default"
+
+// GroupModel is a student group which owns its courses, schedules and replacements
+type GroupModel struct {
+	Id        string
+	Name      string
+	MemberIds []int
+	AdminIds  []int
+}
+
+// PublicationTargetModel is a telegram group chat or channel where the bot posts for the student group
+type PublicationTargetModel struct {
+	ChatId              int64
+	Kind                util.TargetKind
+	Title               string
+	GroupId             string
+	Reminders           bool
+	DailySchedule       bool
+	Replacements        bool
+	Announcements       bool
+	LastDailyScheduleAt time.Time
+	Quiet               QuietHoursModel
+}
@@ -0,0 +1,22 @@
+package dao
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// OutboxEntryModel is a planned lesson reminder, the id consists of the recipient, the date, the slot and the offset,
+// so a reminder is delivered once even when the service is restarted. The exam reminders keep the last sent offset
+// of the exam for the whole group
+type OutboxEntryModel struct {
+	Id        string
+	Owner     string // the member, chat or teacher the reminder is planned for
+	ChatId    int64
+	Date      time.Time
+	Order     int
+	Offset    int // minutes before the lesson
+	DueAt     time.Time
+	State     util.OutboxState
+	Error     string
+	UpdatedAt time.Time
+}
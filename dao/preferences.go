@@ -0,0 +1,19 @@
+package dao
+
+import "time"
+
+// PreferencesModel keeps the notification settings of the user, the empty values fall back to the configuration
+type PreferencesModel struct {
+	UserId          int
+	ReminderOffsets []int // minutes before the lesson, 0 is the start of the lesson
+	RemindersOff    bool
+	Quiet           QuietHoursModel
+	Digest          bool           // the morning digest of the day's schedule
+	DigestTime      *time.Duration // since midnight, nil is the configured time
+	LastDigestAt    time.Time
+	Preview         bool           // the evening preview of tomorrow's schedule
+	PreviewTime     *time.Duration // since midnight, nil is the configured time
+	LastPreviewAt   time.Time
+	MutedCourseIds  []string // no reminders about the lessons of the courses
+	MutedSlotIds    []string // no reminders about the optional slots of the weekly schedule
+}
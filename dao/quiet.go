@@ -0,0 +1,21 @@
+package dao
+
+import "time"
+
+// QuietHoursModel is the time when the recipient does not want to be disturbed
+type QuietHoursModel struct {
+	QuietFrom             time.Duration // since midnight, equal bounds mean no quiet hours
+	QuietTo               time.Duration
+	MutedUntil            time.Time // do not disturb, e.g. until monday
+	RemindersBreakThrough bool      // the lesson reminders are sent during the quiet time, otherwise dropped
+}
+
+// DeferredMessageModel is a notification postponed until the end of the quiet time, it is kept until sent,
+// so the notifications postponed before a restart are sent after it
+type DeferredMessageModel struct {
+	Id       string
+	ChatId   int64
+	Texts    []string // the parts of a long notification are sent together
+	Priority int
+	Until    time.Time
+}
@@ -12,6 +12,7 @@ type ScheduleModel struct {
 	CourseId        string
 	Order           int
 	IsOptional      bool
+	Subgroup        string // empty for lessons of the whole group
 	OptCourseParams OptionalCourseSettings
 }
 
@@ -0,0 +1,11 @@
+package dao
+
+import "time"
+
+type ScheduleSnapshotModel struct {
+	Id        string
+	Name      string
+	CreatedAt time.Time
+	Schedules map[time.Weekday][]ScheduleModel
+	Courses   []CourseModel
+}
@@ -0,0 +1,7 @@
+package dao
+
+// SubgroupsModel keeps the subgroups of the student group and the subgroup of every assigned student
+type SubgroupsModel struct {
+	Names            []string
+	UserIdToSubgroup map[int]string
+}
@@ -0,0 +1,9 @@
+package dao
+
+// TeacherModel is a teacher using the bot, courses refer to the teacher by TeacherId
+type TeacherModel struct {
+	Id      string
+	UserId  int
+	Name    string // matched with the teacher name of the courses
+	Contact string
+}
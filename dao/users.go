@@ -0,0 +1,9 @@
+package dao
+
+type UserModel struct {
+	Id         int
+	UserName   string
+	FirstName  string
+	LastName   string
+	BlockedBot bool // messages are not sent until the user writes to the bot again
+}
@@ -15,6 +15,17 @@ type UpdateCourseInfoRequest struct {
 	TeacherContact string
 	MeetLink       string
 	IsOptional     bool
+	Capacity       int
+}
+
+type SetCourseCapacityRequest struct {
+	CourseId string
+	Capacity int
+}
+
+type UpdateMeetLinkRequest struct {
+	CourseId string
+	MeetLink string
 }
 
 type ArchiveCourseRequest struct {
@@ -29,7 +40,9 @@ type CourseDto struct {
 	Name           string
 	Id             string
 	TeacherName    string
+	TeacherId      string
 	TeacherContact string
 	MeetLink       string
 	IsOptional     bool
+	Capacity       int
 }
@@ -0,0 +1,91 @@
+package dto
+
+import "time"
+
+type CreateElectivePoolRequest struct {
+	Name        string
+	CourseIds   []string
+	ScheduleIds []string
+}
+
+type DeleteElectivePoolRequest struct {
+	PoolId string
+}
+
+type GetElectivePoolsResponse struct {
+	Pools []ElectivePoolDto
+}
+
+type ElectivePoolDto struct {
+	Id      string
+	Name    string
+	Courses []CourseDto
+	Slots   []OptionalSlotDto
+}
+
+type ElectiveRosterResponse struct {
+	Courses []RosterCourseDto
+	Pending []RosterPendingDto
+}
+
+type RosterCourseDto struct {
+	CourseInfo CourseDto
+	Count      int
+	Slots      []RosterSlotDto
+}
+
+type RosterSlotDto struct {
+	Slot       OptionalSlotDto
+	Students   []StudentDto
+	Waitlisted []StudentDto
+}
+
+// RosterPendingDto describes a student who has not chosen a course for some optional slots
+type RosterPendingDto struct {
+	Student      StudentDto
+	MissingSlots []OptionalSlotDto
+}
+
+type OpenSelectionWindowRequest struct {
+	Deadline time.Time
+}
+
+type SelectionWindowDto struct {
+	Deadline time.Time
+	IsLocked bool
+}
+
+// SelectionRemindersResponse lists the students to remind, it is empty when no reminder is due
+type SelectionRemindersResponse struct {
+	Deadline time.Time
+	Pending  []RosterPendingDto
+}
+
+type AutoAssignElectivesResponse struct {
+	Assigned   []AssignedStudentDto
+	Unassigned []RosterPendingDto
+}
+
+type AssignedStudentDto struct {
+	Student    StudentDto
+	Slot       OptionalSlotDto
+	CourseInfo CourseDto
+}
+
+type ImportElectivesResponse struct {
+	Applied  []ImportedLinkDto
+	Promoted []PromotedStudentDto
+	Errors   []ImportErrorDto
+}
+
+type ImportedLinkDto struct {
+	Line       int
+	Student    StudentDto
+	Slot       OptionalSlotDto
+	CourseInfo CourseDto
+}
+
+type ImportErrorDto struct {
+	Line   int
+	Reason string
+}
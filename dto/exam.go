@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+type CreateNewExamRequest struct {
+	CourseId  string
+	Kind      util.ExamKind
+	StartTime time.Time
+	Room      string
+}
+
+type UpdateExamRequest struct {
+	Id        string
+	CourseId  string
+	Kind      util.ExamKind
+	StartTime time.Time
+	Room      string
+}
+
+type DeleteExamRequest struct {
+	ExamId string
+}
+
+type GetExamsResponse struct {
+	Exams []ExamDto
+}
+
+type ExamDto struct {
+	Id         string
+	CourseInfo CourseDto
+	Kind       util.ExamKind
+	StartTime  time.Time
+	Room       string
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+type CreateGroupRequest struct {
+	Name    string
+	OwnerId int
+}
+
+type GroupMemberRequest struct {
+	GroupId string
+	UserId  int
+	IsAdmin bool
+}
+
+type SelectGroupRequest struct {
+	UserId  int
+	GroupId string
+}
+
+type GetGroupsResponse struct {
+	Groups []GroupDto
+}
+
+type GroupDto struct {
+	Id           string
+	Name         string
+	MembersCount int
+	IsActive     bool
+	IsAdmin      bool
+}
+
+type AddPublicationTargetRequest struct {
+	ChatId  int64
+	Kind    util.TargetKind
+	Title   string
+	GroupId string
+}
+
+type UpdatePublicationTargetRequest struct {
+	ChatId        int64
+	Reminders     bool
+	DailySchedule bool
+	Replacements  bool
+	Announcements bool
+}
+
+type PublicationTargetDto struct {
+	ChatId              int64
+	Kind                util.TargetKind
+	Title               string
+	GroupId             string
+	Reminders           bool
+	DailySchedule       bool
+	Replacements        bool
+	Announcements       bool
+	LastDailyScheduleAt time.Time
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+type ReminderSettingsDto struct {
+	Enabled   bool
+	IsDefault bool  // the configured intervals are used
+	Offsets   []int // minutes before the lesson in descending order, 0 is the start of the lesson
+}
+
+type UpdateReminderSettingsRequest struct {
+	UserId  int
+	Enabled bool
+	Offsets []int // nil resets to the configured intervals
+}
+
+type QuietHoursDto struct {
+	QuietFrom             time.Duration
+	QuietTo               time.Duration
+	MutedUntil            time.Time
+	RemindersBreakThrough bool
+}
+
+// UpdateQuietHoursRequest changes the quiet hours of a user, the id of the private chat is the id of the user,
+// or of a group chat or channel
+type UpdateQuietHoursRequest struct {
+	ChatId int64
+	Quiet  QuietHoursDto
+}
+
+// QuietCheckDto tells whether the recipient is in the quiet time now and when it ends
+type QuietCheckDto struct {
+	IsQuiet               bool
+	Until                 time.Time
+	RemindersBreakThrough bool
+}
+
+type DigestSettingsDto struct {
+	Kind          util.DigestKind
+	Enabled       bool
+	Time          time.Duration // since midnight
+	IsDefaultTime bool
+}
+
+type UpdateDigestSettingsRequest struct {
+	UserId  int
+	Kind    util.DigestKind
+	Enabled bool
+	Time    *time.Duration // since midnight, nil resets to the configured time
+}
+
+type MutesDto struct {
+	CourseIds []string
+	SlotIds   []string
+}
+
+// UpdateMuteRequest mutes or unmutes the reminders about the course or about the optional slot
+type UpdateMuteRequest struct {
+	UserId     int
+	CourseId   string
+	ScheduleId string
+	Muted      bool
+}
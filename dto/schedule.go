@@ -11,11 +11,40 @@ type CreateNewScheduleRequest struct {
 	WeekOrder  util.WeekOrder
 	Order      int
 	IsOptional bool
+	Subgroup   string
 }
 
 type LinkOptionalCourseToUserRequest struct {
-	UserId   int
-	CourseId string
+	UserId         int
+	ScheduleId     string
+	CourseId       string
+	IgnoreDeadline bool // admins can change the choice after the selection deadline
+}
+
+type LinkOptionalCourseToUserResponse struct {
+	IsWaitlisted bool
+	Position     int
+	Promoted     []PromotedStudentDto
+}
+
+// PromotedStudentDto describes a waitlisted student who got a seat of the course
+type PromotedStudentDto struct {
+	UserId     int
+	Slot       OptionalSlotDto
+	CourseInfo CourseDto
+}
+
+type GetOptionalSlotsResponse struct {
+	Slots []OptionalSlotDto
+}
+
+// OptionalSlotDto describes an optional lesson of the weekly schedule with the user's choice
+type OptionalSlotDto struct {
+	ScheduleId     string
+	Weekday        time.Weekday
+	WeekOrder      util.WeekOrder
+	Order          int
+	SelectedCourse *CourseDto
 }
 
 type CreateNewAdditionalScheduleRequest struct {
@@ -24,10 +53,30 @@ type CreateNewAdditionalScheduleRequest struct {
 	IsEmpty bool
 }
 
+type CancelRangeRequest struct {
+	From     time.Time
+	To       time.Time
+	CourseId string
+	Order    int
+}
+
+type CancelRangeResponse struct {
+	Lessons []CancelledLessonDto
+}
+
+type CancelledLessonDto struct {
+	Date       time.Time
+	Order      int
+	Subgroup   string
+	CourseInfo CourseDto
+}
+
 type GetScheduleResponse struct {
 	CurrentDate      time.Time
 	CurrentWeekOrder util.WeekOrder
 	Schedules        []ScheduleDto
+	Cancelled        []ScheduleDto // the lessons of the weekly schedule cancelled for the date
+	IsHoliday        bool          // the date is a holiday from the configuration
 }
 
 type GetCommonScheduleResponse struct {
@@ -39,7 +88,43 @@ type CommonScheduleDto struct {
 }
 
 type ScheduleDto struct {
+	CourseInfo    CourseDto
+	Order         int
+	WeekOrder     util.WeekOrder
+	Subgroup      string
+	GroupName     string // filled for the lessons of teachers, who may teach several groups
+	IsReplacement bool   // the lesson replaces the weekly schedule for the date
+	ScheduleId    string // the optional slot of the weekly schedule
+}
+
+type ScheduleDiffResponse struct {
+	AddedSchedules      []ScheduleChangeDto
+	RemovedSchedules    []ScheduleChangeDto
+	AddedReplacements   []ReplacementChangeDto
+	RemovedReplacements []ReplacementChangeDto
+}
+
+type ScheduleChangeDto struct {
+	Weekday    time.Weekday
+	WeekOrder  util.WeekOrder
+	Order      int
+	IsOptional bool
+	Subgroup   string
 	CourseInfo CourseDto
+}
+
+type ReplacementChangeDto struct {
+	Date       time.Time
 	Order      int
-	WeekOrder  util.WeekOrder
+	IsEmpty    bool
+	Subgroup   string
+	CourseInfo CourseDto
+}
+
+// LessonChangeDto shows a lesson of the user before and after an edit, the lesson is absent when nil
+type LessonChangeDto struct {
+	Date   time.Time
+	Order  int
+	Before *ScheduleDto
+	After  *ScheduleDto
 }
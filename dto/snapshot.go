@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type CreateScheduleSnapshotRequest struct {
+	Name string
+}
+
+type CloneScheduleSnapshotRequest struct {
+	SnapshotId string
+}
+
+type DeleteScheduleSnapshotRequest struct {
+	SnapshotId string
+}
+
+type GetScheduleSnapshotsResponse struct {
+	Snapshots []ScheduleSnapshotDto
+}
+
+type ScheduleSnapshotDto struct {
+	Id             string
+	Name           string
+	CreatedAt      time.Time
+	SchedulesCount int
+	CoursesCount   int
+}
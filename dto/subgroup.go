@@ -0,0 +1,24 @@
+package dto
+
+type CreateSubgroupRequest struct {
+	Name string
+}
+
+type DeleteSubgroupRequest struct {
+	Name string
+}
+
+// AssignSubgroupRequest moves the student to the subgroup, the empty subgroup removes the student from subgroups
+type AssignSubgroupRequest struct {
+	UserId   int
+	Subgroup string
+}
+
+type GetSubgroupsResponse struct {
+	Subgroups []SubgroupDto
+}
+
+type SubgroupDto struct {
+	Name     string
+	Students []StudentDto
+}
@@ -0,0 +1,64 @@
+package dto
+
+import "time"
+
+type RegisterTeacherRequest struct {
+	UserId  int
+	Name    string
+	Contact string
+}
+
+type TeacherDto struct {
+	Id      string
+	UserId  int
+	Name    string
+	Contact string
+}
+
+// TeacherLessonDto describes a lesson of the teacher in one of the groups
+type TeacherLessonDto struct {
+	GroupId    string
+	GroupName  string
+	Date       time.Time
+	Order      int
+	Subgroup   string
+	IsOptional bool
+	CourseInfo CourseDto
+}
+
+type GetTeacherClassesResponse struct {
+	Lessons []TeacherLessonDto
+}
+
+type TeacherCourseDto struct {
+	GroupId    string
+	GroupName  string
+	CourseInfo CourseDto
+}
+
+type GetTeacherCoursesResponse struct {
+	Courses []TeacherCourseDto
+}
+
+type CancelClassRequest struct {
+	UserId   int
+	CourseId string
+	Date     time.Time
+	Order    int
+}
+
+type CancelClassResponse struct {
+	GroupId string
+	Lessons []CancelledLessonDto
+}
+
+type UpdateTeacherMeetLinkRequest struct {
+	UserId   int
+	CourseId string
+	MeetLink string
+}
+
+type UpdateTeacherMeetLinkResponse struct {
+	GroupId    string
+	CourseInfo CourseDto
+}
@@ -15,3 +15,9 @@ type CalendarPositionDto struct {
 	Month time.Month
 	Year  int
 }
+
+type StudentDto struct {
+	UserId   int
+	UserName string
+	FullName string
+}
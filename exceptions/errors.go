@@ -4,3 +4,8 @@ import "errors"
 
 var NotFound = errors.New("NotFound")
 var OptionalCourseNotSelected = errors.New("OptionalCourseNotSelected")
+var CourseNotAllowed = errors.New("CourseNotAllowed")
+var CourseIsFull = errors.New("CourseIsFull")
+var SelectionLocked = errors.New("SelectionLocked")
+var SubgroupMismatch = errors.New("SubgroupMismatch")
+var ElectiveNotCancellable = errors.New("ElectiveNotCancellable")
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"telegram-notification-bot-core/abstractions"
 	"telegram-notification-bot-core/bot"
 	"telegram-notification-bot-core/configuration"
 	"telegram-notification-bot-core/providers"
@@ -32,22 +33,46 @@ func main() {
 	ctx := context.Background()
 	childCtx, cancel := context.WithCancel(ctx)
 	actionsProvider := providers.NewActionProvider()
-	coursesProvider := providers.NewCourseProvider()
-	schedulesProvider := providers.NewScheduleProvider()
 	chatProvider := providers.NewChatProvider()
+	usersProvider := providers.NewUserProvider()
+	groupsProvider := providers.NewGroupProvider()
+	targetsProvider := providers.NewPublicationTargetProvider()
+	teachersProvider := providers.NewTeacherProvider()
+	outboxProvider := providers.NewOutboxProvider()
+	preferencesProvider := providers.NewPreferencesProvider()
+	deferredProvider := providers.NewDeferredProvider()
 
 	actionsService := services.NewActionService(actionsProvider)
-	coursesService := services.NewCourseService(coursesProvider)
-	scheduleService := services.NewScheduleService(config, schedulesProvider, coursesProvider)
-	backgroundService := services.NewBackgroundService(scheduleService, chatProvider, config)
+	groupService := services.NewGroupService(config, groupsProvider, targetsProvider, func(groupId string) abstractions.GroupScope {
+		prefix := providers.GroupStoragePrefix(groupId)
+		coursesProvider := providers.NewCourseProvider(prefix)
+		schedulesProvider := providers.NewScheduleProvider(prefix)
+		draftSchedulesProvider := providers.NewScheduleProvider(prefix + "draft_")
+		examsProvider := providers.NewExamProvider(prefix)
+		snapshotsProvider := providers.NewSnapshotProvider(prefix)
+		electivesProvider := providers.NewElectiveProvider(prefix)
+		subgroupsProvider := providers.NewSubgroupProvider(prefix)
 
-	api, err := bot.NewApi(config)
+		return abstractions.GroupScope{
+			Course:    services.NewCourseService(coursesProvider, teachersProvider),
+			Schedule:  services.NewScheduleService(config, schedulesProvider, draftSchedulesProvider, coursesProvider, snapshotsProvider, subgroupsProvider, preferencesProvider),
+			Exams:     services.NewExamService(examsProvider, coursesProvider),
+			Electives: services.NewElectiveService(config, electivesProvider, schedulesProvider, draftSchedulesProvider, coursesProvider, usersProvider, subgroupsProvider),
+			Subgroups: services.NewSubgroupService(subgroupsProvider, schedulesProvider, draftSchedulesProvider, usersProvider),
+		}
+	})
+	teacherService := services.NewTeacherService(teachersProvider, groupService)
+	preferencesService := services.NewPreferencesService(config, preferencesProvider, targetsProvider)
+	backgroundService := services.NewBackgroundService(groupService, teacherService, preferencesService, chatProvider, outboxProvider, config)
+
+	api, err := bot.NewApi(config, usersProvider, preferencesService, deferredProvider)
 
 	if err != nil {
 		panic(err)
 	}
-	go backgroundService.Run(childCtx, api.SendNotification)
-	handler := bot.NewHandler(coursesService, actionsService, scheduleService, chatProvider, config, api)
+	api.FlushDeferred()
+	go backgroundService.Run(childCtx, api.SendNotification, api.SendMissedReminders, api.SendExamNotification, api.SendSelectionReminder, api.SendDailySchedule, api.SendDailyDigest)
+	handler := bot.NewHandler(actionsService, groupService, teacherService, preferencesService, chatProvider, usersProvider, config, api)
 	go api.StartServe()
 	go handler.Run(childCtx)
 
@@ -15,8 +15,8 @@ type CourseProvider struct {
 	mutex  *sync.RWMutex
 }
 
-func NewCourseProvider() *CourseProvider {
-	common := newCommonProvider("courses")
+func NewCourseProvider(prefix string) *CourseProvider {
+	common := newCommonProvider(prefix + "courses")
 
 	data, err := common.getAllDataFromStorage()
 
@@ -92,6 +92,36 @@ func (c *CourseProvider) UpdateCourse(model dao.CourseModel) (err error) {
 	return nil
 }
 
+// UpsertCourses creates or overwrites the courses by their ids in one write
+func (c *CourseProvider) UpsertCourses(models []dao.CourseModel) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	backup := map[string]dao.CourseModel{}
+
+	for key, val := range c.cache {
+		backup[key] = val
+	}
+
+	defer func() {
+		if err != nil {
+			c.cache = backup
+		}
+	}()
+
+	for _, model := range models {
+		c.cache[model.Id] = model
+	}
+
+	data, err := json.Marshal(c.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return c.common.saveAllDataToStorage(data)
+}
+
 func (c *CourseProvider) ArchiveCourse(id string) (err error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
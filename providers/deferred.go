@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"telegram-notification-bot-core/dao"
+)
+
+type DeferredProvider struct {
+	common *CommonProvider
+	cache  map[string]dao.DeferredMessageModel
+	mutex  *sync.RWMutex
+}
+
+func NewDeferredProvider() *DeferredProvider {
+	common := newCommonProvider("deferred")
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[string]dao.DeferredMessageModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[string]dao.DeferredMessageModel)
+		}
+	}
+
+	return &DeferredProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (d *DeferredProvider) SaveDeferred(model dao.DeferredMessageModel) (id string, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	model.Id = uuid.NewString()
+	d.cache[model.Id] = model
+
+	defer func() {
+		if err != nil {
+			delete(d.cache, model.Id)
+		}
+	}()
+
+	return model.Id, d.flush()
+}
+
+func (d *DeferredProvider) GetDeferred() ([]dao.DeferredMessageModel, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var result []dao.DeferredMessageModel
+
+	for _, val := range d.cache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+// DeleteDeferred removes the sent message, an unknown id is ignored
+func (d *DeferredProvider) DeleteDeferred(id string) (err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	backup, ok := d.cache[id]
+
+	if !ok {
+		return nil
+	}
+
+	delete(d.cache, id)
+
+	defer func() {
+		if err != nil {
+			d.cache[id] = backup
+		}
+	}()
+
+	return d.flush()
+}
+
+func (d *DeferredProvider) flush() error {
+	data, err := json.Marshal(d.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return d.common.saveAllDataToStorage(data)
+}
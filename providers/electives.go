@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type ElectiveProvider struct {
+	poolsCommon     *CommonProvider
+	waitlistsCommon *CommonProvider
+	windowCommon    *CommonProvider
+	poolsCache      map[string]dao.ElectivePoolModel
+	waitlistsCache  map[string]dao.ElectiveWaitlistModel
+	windowCache     *dao.ElectiveSelectionWindowModel
+	mutex           *sync.RWMutex
+}
+
+func NewElectiveProvider(prefix string) *ElectiveProvider {
+	poolsCommon := newCommonProvider(prefix + "elective_pools")
+	waitlistsCommon := newCommonProvider(prefix + "elective_waitlists")
+	windowCommon := newCommonProvider(prefix + "elective_selection_window")
+
+	data, err := poolsCommon.getAllDataFromStorage()
+
+	poolsCache := make(map[string]dao.ElectivePoolModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &poolsCache)
+
+		if err != nil {
+			poolsCache = make(map[string]dao.ElectivePoolModel)
+		}
+	}
+
+	data, err = waitlistsCommon.getAllDataFromStorage()
+
+	waitlistsCache := make(map[string]dao.ElectiveWaitlistModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &waitlistsCache)
+
+		if err != nil {
+			waitlistsCache = make(map[string]dao.ElectiveWaitlistModel)
+		}
+	}
+
+	data, err = windowCommon.getAllDataFromStorage()
+
+	var windowCache *dao.ElectiveSelectionWindowModel
+
+	if err == nil {
+		err = json.Unmarshal(data, &windowCache)
+
+		if err != nil {
+			windowCache = nil
+		}
+	}
+
+	return &ElectiveProvider{
+		poolsCommon:     poolsCommon,
+		waitlistsCommon: waitlistsCommon,
+		windowCommon:    windowCommon,
+		poolsCache:      poolsCache,
+		waitlistsCache:  waitlistsCache,
+		windowCache:     windowCache,
+		mutex:           &sync.RWMutex{},
+	}
+}
+
+func (e *ElectiveProvider) CreateNewPool(model dao.ElectivePoolModel) (str string, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	id := uuid.NewString()
+	model.Id = id
+
+	e.poolsCache[id] = model
+
+	defer func() {
+		if err != nil {
+			delete(e.poolsCache, id)
+		}
+	}()
+
+	if err = e.flushPools(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (e *ElectiveProvider) DeletePool(id string) (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	backup, ok := e.poolsCache[id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	delete(e.poolsCache, id)
+
+	defer func() {
+		if err != nil {
+			e.poolsCache[id] = backup
+		}
+	}()
+
+	return e.flushPools()
+}
+
+func (e *ElectiveProvider) GetPools() ([]dao.ElectivePoolModel, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	var result []dao.ElectivePoolModel
+
+	for _, val := range e.poolsCache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+// GetPoolByScheduleId returns the pool which the optional schedule slot is attached to
+func (e *ElectiveProvider) GetPoolByScheduleId(scheduleId string) (*dao.ElectivePoolModel, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	for _, val := range e.poolsCache {
+		for _, id := range val.ScheduleIds {
+			if id == scheduleId {
+				return &val, nil
+			}
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func (e *ElectiveProvider) GetWaitlist(scheduleId string, courseId string) ([]int, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return append([]int{}, e.waitlistsCache[scheduleId+"/"+courseId].UserIds...), nil
+}
+
+// GetWaitlistsBySchedule returns all waitlists of the optional slot keyed by the course id
+func (e *ElectiveProvider) GetWaitlistsBySchedule(scheduleId string) (map[string][]int, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	result := map[string][]int{}
+
+	for _, val := range e.waitlistsCache {
+		if val.ScheduleId == scheduleId && len(val.UserIds) > 0 {
+			result[val.CourseId] = append([]int{}, val.UserIds...)
+		}
+	}
+
+	return result, nil
+}
+
+func (e *ElectiveProvider) SaveWaitlist(scheduleId string, courseId string, userIds []int) (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key := scheduleId + "/" + courseId
+	backup, exists := e.waitlistsCache[key]
+
+	if len(userIds) == 0 {
+		delete(e.waitlistsCache, key)
+	} else {
+		e.waitlistsCache[key] = dao.ElectiveWaitlistModel{ScheduleId: scheduleId, CourseId: courseId, UserIds: userIds}
+	}
+
+	defer func() {
+		if err != nil && exists {
+			e.waitlistsCache[key] = backup
+		}
+		if err != nil && !exists {
+			delete(e.waitlistsCache, key)
+		}
+	}()
+
+	data, err := json.Marshal(e.waitlistsCache)
+
+	if err != nil {
+		return err
+	}
+
+	return e.waitlistsCommon.saveAllDataToStorage(data)
+}
+
+func (e *ElectiveProvider) GetSelectionWindow() (*dao.ElectiveSelectionWindowModel, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.windowCache == nil {
+		return nil, exceptions.NotFound
+	}
+
+	window := *e.windowCache
+
+	return &window, nil
+}
+
+func (e *ElectiveProvider) SaveSelectionWindow(model dao.ElectiveSelectionWindowModel) (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	backup := e.windowCache
+	e.windowCache = &model
+
+	defer func() {
+		if err != nil {
+			e.windowCache = backup
+		}
+	}()
+
+	data, err := json.Marshal(e.windowCache)
+
+	if err != nil {
+		return err
+	}
+
+	return e.windowCommon.saveAllDataToStorage(data)
+}
+
+func (e *ElectiveProvider) flushPools() error {
+	data, err := json.Marshal(e.poolsCache)
+
+	if err != nil {
+		return err
+	}
+
+	return e.poolsCommon.saveAllDataToStorage(data)
+}
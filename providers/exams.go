@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type ExamProvider struct {
+	common *CommonProvider
+	cache  map[string]dao.ExamModel
+	mutex  *sync.RWMutex
+}
+
+func NewExamProvider(prefix string) *ExamProvider {
+	common := newCommonProvider(prefix + "exams")
+
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[string]dao.ExamModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[string]dao.ExamModel)
+		}
+	}
+
+	return &ExamProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (e *ExamProvider) CreateNewExam(model dao.ExamModel) (str string, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	id := uuid.NewString()
+	model.Id = id
+
+	e.cache[id] = model
+
+	defer func() {
+		if err != nil {
+			delete(e.cache, id)
+		}
+	}()
+
+	if err = e.flush(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (e *ExamProvider) UpdateExam(model dao.ExamModel) (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	backup, ok := e.cache[model.Id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	e.cache[model.Id] = model
+
+	defer func() {
+		if err != nil {
+			e.cache[backup.Id] = backup
+		}
+	}()
+
+	return e.flush()
+}
+
+func (e *ExamProvider) DeleteExam(id string) (err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	backup, ok := e.cache[id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	delete(e.cache, id)
+
+	defer func() {
+		if err != nil {
+			e.cache[id] = backup
+		}
+	}()
+
+	return e.flush()
+}
+
+func (e *ExamProvider) GetExamById(id string) (*dao.ExamModel, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	data, ok := e.cache[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (e *ExamProvider) GetExams() ([]dao.ExamModel, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	var result []dao.ExamModel
+
+	for _, val := range e.cache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+func (e *ExamProvider) flush() error {
+	data, err := json.Marshal(e.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return e.common.saveAllDataToStorage(data)
+}
@@ -0,0 +1,181 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type GroupProvider struct {
+	groupsCommon     *CommonProvider
+	selectionsCommon *CommonProvider
+	groupsCache      map[string]dao.GroupModel
+	selectionsCache  map[int]string
+	mutex            *sync.RWMutex
+}
+
+// GroupStoragePrefix separates the storage of the group, the default group keeps the storage without a prefix
+func GroupStoragePrefix(groupId string) string {
+	if groupId == dao.DefaultGroupId {
+		return ""
+	}
+
+	return "group_" + groupId + "_"
+}
+
+func NewGroupProvider() *GroupProvider {
+	groupsCommon := newCommonProvider("groups")
+	selectionsCommon := newCommonProvider("group_selections")
+
+	data, err := groupsCommon.getAllDataFromStorage()
+
+	groupsCache := make(map[string]dao.GroupModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &groupsCache)
+
+		if err != nil {
+			groupsCache = make(map[string]dao.GroupModel)
+		}
+	}
+
+	data, err = selectionsCommon.getAllDataFromStorage()
+
+	selectionsCache := make(map[int]string)
+
+	if err == nil {
+		err = json.Unmarshal(data, &selectionsCache)
+
+		if err != nil {
+			selectionsCache = make(map[int]string)
+		}
+	}
+
+	return &GroupProvider{
+		groupsCommon:     groupsCommon,
+		selectionsCommon: selectionsCommon,
+		groupsCache:      groupsCache,
+		selectionsCache:  selectionsCache,
+		mutex:            &sync.RWMutex{},
+	}
+}
+
+func (g *GroupProvider) CreateNewGroup(model dao.GroupModel) (str string, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	id := uuid.NewString()
+	model.Id = id
+
+	g.groupsCache[id] = model
+
+	defer func() {
+		if err != nil {
+			delete(g.groupsCache, id)
+		}
+	}()
+
+	if err = g.flushGroups(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// SaveGroup creates or replaces the group with the given id
+func (g *GroupProvider) SaveGroup(model dao.GroupModel) (err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	backup, exists := g.groupsCache[model.Id]
+
+	g.groupsCache[model.Id] = model
+
+	defer func() {
+		if err != nil && exists {
+			g.groupsCache[model.Id] = backup
+		}
+		if err != nil && !exists {
+			delete(g.groupsCache, model.Id)
+		}
+	}()
+
+	return g.flushGroups()
+}
+
+func (g *GroupProvider) GetGroups() ([]dao.GroupModel, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	var result []dao.GroupModel
+
+	for _, val := range g.groupsCache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+func (g *GroupProvider) GetGroupById(id string) (*dao.GroupModel, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	data, ok := g.groupsCache[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (g *GroupProvider) GetActiveGroupId(userId int) (string, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	data, ok := g.selectionsCache[userId]
+
+	if !ok {
+		return "", exceptions.NotFound
+	}
+
+	return data, nil
+}
+
+func (g *GroupProvider) SaveActiveGroupId(userId int, groupId string) (err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	backup, exists := g.selectionsCache[userId]
+
+	g.selectionsCache[userId] = groupId
+
+	defer func() {
+		if err != nil && exists {
+			g.selectionsCache[userId] = backup
+		}
+		if err != nil && !exists {
+			delete(g.selectionsCache, userId)
+		}
+	}()
+
+	data, err := json.Marshal(g.selectionsCache)
+
+	if err != nil {
+		return err
+	}
+
+	return g.selectionsCommon.saveAllDataToStorage(data)
+}
+
+func (g *GroupProvider) flushGroups() error {
+	data, err := json.Marshal(g.groupsCache)
+
+	if err != nil {
+		return err
+	}
+
+	return g.groupsCommon.saveAllDataToStorage(data)
+}
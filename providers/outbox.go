@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"encoding/json"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+type OutboxProvider struct {
+	common *CommonProvider
+	cache  map[string]dao.OutboxEntryModel
+	mutex  *sync.RWMutex
+}
+
+func NewOutboxProvider() *OutboxProvider {
+	common := newCommonProvider("outbox")
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[string]dao.OutboxEntryModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[string]dao.OutboxEntryModel)
+		}
+	}
+
+	return &OutboxProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+// SaveEntries creates the new entries and replaces the existing ones with the same id
+func (o *OutboxProvider) SaveEntries(models []dao.OutboxEntryModel) (err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if len(models) == 0 {
+		return nil
+	}
+
+	backup := map[string]dao.OutboxEntryModel{}
+	var added []string
+
+	for _, model := range models {
+		if val, exists := o.cache[model.Id]; exists {
+			backup[model.Id] = val
+		} else {
+			added = append(added, model.Id)
+		}
+
+		o.cache[model.Id] = model
+	}
+
+	defer func() {
+		if err != nil {
+			for _, id := range added {
+				delete(o.cache, id)
+			}
+			for id, val := range backup {
+				o.cache[id] = val
+			}
+		}
+	}()
+
+	return o.flush()
+}
+
+func (o *OutboxProvider) GetEntry(id string) (*dao.OutboxEntryModel, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	data, ok := o.cache[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (o *OutboxProvider) SetEntryState(id string, state util.OutboxState, reason string) (err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backup, ok := o.cache[id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	model := backup
+	model.State = state
+	model.Error = reason
+	model.UpdatedAt = time.Now()
+	o.cache[id] = model
+
+	defer func() {
+		if err != nil {
+			o.cache[id] = backup
+		}
+	}()
+
+	return o.flush()
+}
+
+// DeleteEntries removes the entries of the cancelled reminders, unknown ids are ignored
+func (o *OutboxProvider) DeleteEntries(ids []string) (err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backup := map[string]dao.OutboxEntryModel{}
+
+	for _, id := range ids {
+		if val, ok := o.cache[id]; ok {
+			backup[id] = val
+			delete(o.cache, id)
+		}
+	}
+
+	if len(backup) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			for id, val := range backup {
+				o.cache[id] = val
+			}
+		}
+	}()
+
+	return o.flush()
+}
+
+// DeleteEntriesBefore removes the entries of the lessons before the date
+func (o *OutboxProvider) DeleteEntriesBefore(date time.Time) error {
+	o.mutex.RLock()
+	var ids []string
+
+	for id, val := range o.cache {
+		if val.Date.Before(date) {
+			ids = append(ids, id)
+		}
+	}
+
+	o.mutex.RUnlock()
+
+	return o.DeleteEntries(ids)
+}
+
+func (o *OutboxProvider) flush() error {
+	data, err := json.Marshal(o.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return o.common.saveAllDataToStorage(data)
+}
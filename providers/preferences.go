@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"encoding/json"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type PreferencesProvider struct {
+	common *CommonProvider
+	cache  map[int]dao.PreferencesModel
+	mutex  *sync.RWMutex
+}
+
+func NewPreferencesProvider() *PreferencesProvider {
+	common := newCommonProvider("preferences")
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[int]dao.PreferencesModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[int]dao.PreferencesModel)
+		}
+	}
+
+	return &PreferencesProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (p *PreferencesProvider) GetPreferences(userId int) (*dao.PreferencesModel, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	data, ok := p.cache[userId]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (p *PreferencesProvider) SavePreferences(model dao.PreferencesModel) (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	backup, exists := p.cache[model.UserId]
+
+	p.cache[model.UserId] = model
+
+	defer func() {
+		if err != nil && exists {
+			p.cache[model.UserId] = backup
+		}
+		if err != nil && !exists {
+			delete(p.cache, model.UserId)
+		}
+	}()
+
+	data, err := json.Marshal(p.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return p.common.saveAllDataToStorage(data)
+}
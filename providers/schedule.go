@@ -5,6 +5,7 @@ import (
 	"github.com/google/uuid"
 	"sync"
 	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
 	"telegram-notification-bot-core/util"
 	"time"
 )
@@ -15,6 +16,7 @@ type ScheduleProvider struct {
 	scheduleCache    map[time.Weekday][]dao.ScheduleModel
 	additionalCache  map[string][]dao.AdditionalScheduleModel
 	mutex            *sync.RWMutex
+	initialized      bool
 }
 
 func (s *ScheduleProvider) DropAllSchedules() error {
@@ -59,6 +61,55 @@ func (s *ScheduleProvider) DropAllSchedules() error {
 	return nil
 }
 
+// ReplaceCommonSchedule starts a new term with the given weekly schedule, replacements of the previous term are dropped
+func (s *ScheduleProvider) ReplaceCommonSchedule(schedules map[time.Weekday][]dao.ScheduleModel) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scheduleBackup := s.scheduleCache
+	additionalBackup := s.additionalCache
+
+	defer func() {
+		if err != nil {
+			s.scheduleCache = scheduleBackup
+			s.additionalCache = additionalBackup
+		}
+	}()
+
+	s.scheduleCache = map[time.Weekday][]dao.ScheduleModel{
+		time.Monday:    {},
+		time.Tuesday:   {},
+		time.Wednesday: {},
+		time.Thursday:  {},
+		time.Friday:    {},
+		time.Saturday:  {},
+		time.Sunday:    {},
+	}
+	s.additionalCache = map[string][]dao.AdditionalScheduleModel{}
+
+	for weekday, val := range schedules {
+		s.scheduleCache[weekday] = append([]dao.ScheduleModel{}, val...)
+	}
+
+	data, err := json.Marshal(s.scheduleCache)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.scheduleCommon.saveAllDataToStorage(data); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(s.additionalCache)
+
+	if err != nil {
+		return err
+	}
+
+	return s.additionalCommon.saveAllDataToStorage(data)
+}
+
 func (s *ScheduleProvider) CreateNewSchedule(model dao.ScheduleModel) error {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -89,18 +140,24 @@ func (s *ScheduleProvider) CreateNewSchedule(model dao.ScheduleModel) error {
 	return nil
 }
 
-func (s *ScheduleProvider) GetScheduleByDate(date time.Time) ([]dao.ScheduleModel, error) {
+// GetScheduleByDate returns the lessons of the date visible for the subgroup, the empty subgroup gets the lessons
+// of all subgroups
+func (s *ScheduleProvider) GetScheduleByDate(date time.Time, subgroup string) ([]dao.ScheduleModel, error) {
 
 	var schedules []dao.ScheduleModel
-	curWeekOrder := util.GetCurrentWeekOrder()
+	curWeekOrder := util.GetWeekOrderByDate(date)
 	curWeekday := date.Weekday()
 	additional := s.additionalCache[date.Format("2006-01-02")]
-	excludedOrders := map[int]struct{}{}
+	excludedOrders := map[int][]string{}
 
 	if additional != nil && len(additional) > 0 {
 
 		for _, val := range additional {
-			excludedOrders[val.Order] = struct{}{}
+			if !util.IsVisibleForSubgroup(val.Subgroup, subgroup) {
+				continue
+			}
+
+			excludedOrders[val.Order] = append(excludedOrders[val.Order], val.Subgroup)
 
 			// we exclude this schedule order, by not add info about additional
 			if val.IsEmpty {
@@ -113,6 +170,7 @@ func (s *ScheduleProvider) GetScheduleByDate(date time.Time) ([]dao.ScheduleMode
 				WeekOrder: curWeekOrder,
 				CourseId:  val.CourseId,
 				Order:     val.Order,
+				Subgroup:  val.Subgroup,
 			})
 		}
 
@@ -129,9 +187,11 @@ func (s *ScheduleProvider) GetScheduleByDate(date time.Time) ([]dao.ScheduleMode
 			continue
 		}
 
-		_, exclude := excludedOrders[val.Order]
+		if !util.IsVisibleForSubgroup(val.Subgroup, subgroup) {
+			continue
+		}
 
-		if exclude {
+		if isReplaced(val, excludedOrders[val.Order], subgroup) {
 			continue
 		}
 
@@ -141,6 +201,31 @@ func (s *ScheduleProvider) GetScheduleByDate(date time.Time) ([]dao.ScheduleMode
 	return schedules, nil
 }
 
+// isReplaced reports whether the replacements of the order hide the lesson, when the lessons of all subgroups
+// are requested the replacement of a subgroup hides only the lessons of that subgroup
+func isReplaced(lesson dao.ScheduleModel, replacementSubgroups []string, subgroup string) bool {
+	for _, val := range replacementSubgroups {
+		if subgroup != "" || val == "" || val == lesson.Subgroup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAdditionalSchedulesByDate returns the replacements and the cancellations of the date visible for the subgroup
+func (s *ScheduleProvider) GetAdditionalSchedulesByDate(date time.Time, subgroup string) []dao.AdditionalScheduleModel {
+	var result []dao.AdditionalScheduleModel
+
+	for _, val := range s.additionalCache[date.Format("2006-01-02")] {
+		if util.IsVisibleForSubgroup(val.Subgroup, subgroup) {
+			result = append(result, val)
+		}
+	}
+
+	return result
+}
+
 func (s *ScheduleProvider) CreateNewAdditionalSchedule(model dao.AdditionalScheduleModel) error {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -166,9 +251,86 @@ func (s *ScheduleProvider) CreateNewAdditionalSchedule(model dao.AdditionalSched
 	return s.additionalCommon.saveAllDataToStorage(data)
 }
 
-func NewScheduleProvider() *ScheduleProvider {
-	common := newCommonProvider("schedules")
-	addCommon := newCommonProvider("additionals")
+// ReplaceAdditionalSchedules stores all models at once, replacements which already exist
+// for the same date, order and subgroup are overwritten, a replacement of the whole group overwrites all of them
+func (s *ScheduleProvider) ReplaceAdditionalSchedules(models []dao.AdditionalScheduleModel) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backup := map[string][]dao.AdditionalScheduleModel{}
+
+	defer func() {
+		if err != nil {
+			for date, val := range backup {
+				s.additionalCache[date] = val
+			}
+		}
+	}()
+
+	for _, model := range models {
+		model.Id = uuid.NewString()
+		date := model.AdditionalTime.Format("2006-01-02")
+
+		courses := s.additionalCache[date]
+
+		if _, ok := backup[date]; !ok {
+			backup[date] = courses
+		}
+
+		var filtered []dao.AdditionalScheduleModel
+
+		for _, val := range courses {
+			if val.Order != model.Order || model.Subgroup != "" && val.Subgroup != model.Subgroup {
+				filtered = append(filtered, val)
+			}
+		}
+
+		s.additionalCache[date] = append(filtered, model)
+	}
+
+	data, err := json.Marshal(s.additionalCache)
+
+	if err != nil {
+		return err
+	}
+
+	return s.additionalCommon.saveAllDataToStorage(data)
+}
+
+// RestoreAdditionalSchedules puts back the replacements of the dates as they were, it rolls back a failed edit
+func (s *ScheduleProvider) RestoreAdditionalSchedules(additionals map[string][]dao.AdditionalScheduleModel) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backup := map[string][]dao.AdditionalScheduleModel{}
+
+	defer func() {
+		if err != nil {
+			for date, val := range backup {
+				s.additionalCache[date] = val
+			}
+		}
+	}()
+
+	for date, val := range additionals {
+		backup[date] = s.additionalCache[date]
+		s.additionalCache[date] = val
+	}
+
+	data, err := json.Marshal(s.additionalCache)
+
+	if err != nil {
+		return err
+	}
+
+	return s.additionalCommon.saveAllDataToStorage(data)
+}
+
+// NewScheduleProvider creates a schedule storage, the prefix separates independent copies of the schedule
+func NewScheduleProvider(prefix string) *ScheduleProvider {
+	common := newCommonProvider(prefix + "schedules")
+	addCommon := newCommonProvider(prefix + "additionals")
+	initialized := false
 
 	scheduleCache := map[time.Weekday][]dao.ScheduleModel{
 		time.Monday:    {},
@@ -185,6 +347,7 @@ func NewScheduleProvider() *ScheduleProvider {
 
 	if err == nil {
 		err = json.Unmarshal(data, &scheduleCache)
+		initialized = err == nil
 
 		if err != nil {
 			scheduleCache = map[time.Weekday][]dao.ScheduleModel{
@@ -214,10 +377,93 @@ func NewScheduleProvider() *ScheduleProvider {
 		additionalCommon: addCommon,
 		scheduleCache:    scheduleCache,
 		additionalCache:  addCache,
-		mutex:            &sync.RWMutex{}}
+		mutex:            &sync.RWMutex{},
+		initialized:      initialized}
+}
+
+// IsInitialized reports whether the schedule was restored from the storage
+func (s *ScheduleProvider) IsInitialized() bool {
+	return s.initialized
+}
+
+// Export returns a deep copy of the weekly schedule and replacements
+func (s *ScheduleProvider) Export() (map[time.Weekday][]dao.ScheduleModel, map[string][]dao.AdditionalScheduleModel) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	schedules := map[time.Weekday][]dao.ScheduleModel{}
+
+	for weekday, val := range s.scheduleCache {
+		schedules[weekday] = []dao.ScheduleModel{}
+
+		for _, v := range val {
+			if v.IsOptional {
+				userIdToCourseId := map[int]string{}
+
+				for userId, courseId := range v.OptCourseParams.UserIdToCourseId {
+					userIdToCourseId[userId] = courseId
+				}
+
+				v.OptCourseParams = dao.OptionalCourseSettings{UserIdToCourseId: userIdToCourseId}
+			}
+
+			schedules[weekday] = append(schedules[weekday], v)
+		}
+	}
+
+	additionals := map[string][]dao.AdditionalScheduleModel{}
+
+	for date, val := range s.additionalCache {
+		additionals[date] = append([]dao.AdditionalScheduleModel{}, val...)
+	}
+
+	return schedules, additionals
 }
 
-func (s *ScheduleProvider) ValidateScheduleCreation(weekday time.Weekday, order int, weekOrder util.WeekOrder) (bool, error) {
+// Import replaces the weekly schedule and replacements at once
+func (s *ScheduleProvider) Import(schedules map[time.Weekday][]dao.ScheduleModel, additionals map[string][]dao.AdditionalScheduleModel) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scheduleBackup := s.scheduleCache
+	additionalBackup := s.additionalCache
+
+	defer func() {
+		if err != nil {
+			s.scheduleCache = scheduleBackup
+			s.additionalCache = additionalBackup
+		}
+	}()
+
+	s.scheduleCache = schedules
+	s.additionalCache = additionals
+
+	data, err := json.Marshal(s.scheduleCache)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.scheduleCommon.saveAllDataToStorage(data); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(s.additionalCache)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.additionalCommon.saveAllDataToStorage(data); err != nil {
+		return err
+	}
+
+	s.initialized = true
+
+	return nil
+}
+
+func (s *ScheduleProvider) ValidateScheduleCreation(weekday time.Weekday, order int, weekOrder util.WeekOrder, subgroup string) (bool, error) {
 	schedule, ok := s.scheduleCache[weekday]
 
 	if !ok {
@@ -229,6 +475,10 @@ func (s *ScheduleProvider) ValidateScheduleCreation(weekday time.Weekday, order
 	}
 
 	for _, val := range schedule {
+		//if subgroups differ
+		if subgroup != "" && val.Subgroup != "" && subgroup != val.Subgroup {
+			continue
+		}
 		//if weekorder diff
 		if weekOrder > 0 && val.WeekOrder > 0 && weekOrder != val.WeekOrder {
 			continue
@@ -246,7 +496,7 @@ func (s *ScheduleProvider) ValidateScheduleCreation(weekday time.Weekday, order
 	return true, nil
 }
 
-func (s *ScheduleProvider) ValidateAddScheduleCreation(date time.Time, order int) (bool, error) {
+func (s *ScheduleProvider) ValidateAddScheduleCreation(date time.Time, order int, subgroup string) (bool, error) {
 	schedule, ok := s.additionalCache[date.Format("2006-01-02")]
 
 	if !ok {
@@ -259,8 +509,8 @@ func (s *ScheduleProvider) ValidateAddScheduleCreation(date time.Time, order int
 
 	for _, val := range schedule {
 
-		// if order equals
-		if val.Order == order {
+		// if order equals and subgroups overlap
+		if val.Order == order && (subgroup == "" || val.Subgroup == "" || val.Subgroup == subgroup) {
 			return false, nil
 		}
 	}
@@ -272,19 +522,75 @@ func (s *ScheduleProvider) GetCommonSchedule() map[time.Weekday][]dao.ScheduleMo
 	return s.scheduleCache
 }
 
-func (s *ScheduleProvider) LinkCourseToUser(userId int, courseId string) error {
-
+func (s *ScheduleProvider) GetScheduleById(id string) (*dao.ScheduleModel, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
 	for _, values := range s.scheduleCache {
 		for _, value := range values {
-			if value.IsOptional {
-				value.OptCourseParams.UserIdToCourseId[userId] = courseId
+			if value.Id == id {
+				return &value, nil
 			}
 		}
 	}
 
+	return nil, exceptions.NotFound
+}
+
+// LinkCoursesToUsers stores the choices of many users at once, the links are grouped by the schedule id and
+// an empty course id removes the choice. The slots which are not found are skipped, e.g. removed in the draft
+func (s *ScheduleProvider) LinkCoursesToUsers(links map[string]map[int]string) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backup := map[time.Weekday][]dao.ScheduleModel{}
+
+	defer func() {
+		if err != nil {
+			for weekday, val := range backup {
+				s.scheduleCache[weekday] = val
+			}
+		}
+	}()
+
+	for weekday, values := range s.scheduleCache {
+		var updated []dao.ScheduleModel
+
+		for _, val := range values {
+			userLinks, ok := links[val.Id]
+
+			if ok && val.IsOptional {
+				// the map is copied, so the backup keeps the previous choices
+				userIdToCourseId := map[int]string{}
+
+				for userId, courseId := range val.OptCourseParams.UserIdToCourseId {
+					userIdToCourseId[userId] = courseId
+				}
+
+				for userId, courseId := range userLinks {
+					if courseId == "" {
+						delete(userIdToCourseId, userId)
+						continue
+					}
+
+					userIdToCourseId[userId] = courseId
+				}
+
+				val.OptCourseParams.UserIdToCourseId = userIdToCourseId
+
+				if _, exists := backup[weekday]; !exists {
+					backup[weekday] = values
+				}
+			}
+
+			updated = append(updated, val)
+		}
+
+		if _, exists := backup[weekday]; exists {
+			s.scheduleCache[weekday] = updated
+		}
+	}
+
 	data, err := json.Marshal(s.scheduleCache)
 
 	if err != nil {
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type SnapshotProvider struct {
+	common *CommonProvider
+	cache  map[string]dao.ScheduleSnapshotModel
+	mutex  *sync.RWMutex
+}
+
+func NewSnapshotProvider(prefix string) *SnapshotProvider {
+	common := newCommonProvider(prefix + "snapshots")
+
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[string]dao.ScheduleSnapshotModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[string]dao.ScheduleSnapshotModel)
+		}
+	}
+
+	return &SnapshotProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (s *SnapshotProvider) CreateNewSnapshot(model dao.ScheduleSnapshotModel) (str string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := uuid.NewString()
+	model.Id = id
+
+	s.cache[id] = model
+
+	defer func() {
+		if err != nil {
+			delete(s.cache, id)
+		}
+	}()
+
+	if err = s.flush(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *SnapshotProvider) DeleteSnapshot(id string) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backup, ok := s.cache[id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	delete(s.cache, id)
+
+	defer func() {
+		if err != nil {
+			s.cache[id] = backup
+		}
+	}()
+
+	return s.flush()
+}
+
+func (s *SnapshotProvider) GetSnapshotById(id string) (*dao.ScheduleSnapshotModel, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, ok := s.cache[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (s *SnapshotProvider) GetSnapshots() ([]dao.ScheduleSnapshotModel, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result []dao.ScheduleSnapshotModel
+
+	for _, val := range s.cache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+func (s *SnapshotProvider) flush() error {
+	data, err := json.Marshal(s.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return s.common.saveAllDataToStorage(data)
+}
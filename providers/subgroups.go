@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"encoding/json"
+	"sync"
+	"telegram-notification-bot-core/dao"
+)
+
+type SubgroupProvider struct {
+	common *CommonProvider
+	cache  dao.SubgroupsModel
+	mutex  *sync.RWMutex
+}
+
+func NewSubgroupProvider(prefix string) *SubgroupProvider {
+	common := newCommonProvider(prefix + "subgroups")
+
+	cache := dao.SubgroupsModel{UserIdToSubgroup: map[int]string{}}
+
+	data, err := common.getAllDataFromStorage()
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil || cache.UserIdToSubgroup == nil {
+			cache = dao.SubgroupsModel{UserIdToSubgroup: map[int]string{}}
+		}
+	}
+
+	return &SubgroupProvider{
+		common: common,
+		cache:  cache,
+		mutex:  &sync.RWMutex{},
+	}
+}
+
+// GetSubgroups returns a copy of the subgroups
+func (s *SubgroupProvider) GetSubgroups() (dao.SubgroupsModel, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	model := dao.SubgroupsModel{
+		Names:            append([]string{}, s.cache.Names...),
+		UserIdToSubgroup: map[int]string{},
+	}
+
+	for userId, subgroup := range s.cache.UserIdToSubgroup {
+		model.UserIdToSubgroup[userId] = subgroup
+	}
+
+	return model, nil
+}
+
+func (s *SubgroupProvider) GetUserSubgroup(userId int) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.cache.UserIdToSubgroup[userId]
+}
+
+func (s *SubgroupProvider) SaveSubgroups(model dao.SubgroupsModel) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backup := s.cache
+	s.cache = model
+
+	defer func() {
+		if err != nil {
+			s.cache = backup
+		}
+	}()
+
+	data, err := json.Marshal(s.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return s.common.saveAllDataToStorage(data)
+}
@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"encoding/json"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type PublicationTargetProvider struct {
+	common *CommonProvider
+	cache  map[int64]dao.PublicationTargetModel
+	mutex  *sync.RWMutex
+}
+
+func NewPublicationTargetProvider() *PublicationTargetProvider {
+	common := newCommonProvider("publication_targets")
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[int64]dao.PublicationTargetModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[int64]dao.PublicationTargetModel)
+		}
+	}
+
+	return &PublicationTargetProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (t *PublicationTargetProvider) SaveTarget(model dao.PublicationTargetModel) (err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	backup, exists := t.cache[model.ChatId]
+
+	t.cache[model.ChatId] = model
+
+	defer func() {
+		if err != nil && exists {
+			t.cache[model.ChatId] = backup
+		}
+		if err != nil && !exists {
+			delete(t.cache, model.ChatId)
+		}
+	}()
+
+	return t.flush()
+}
+
+func (t *PublicationTargetProvider) DeleteTarget(chatId int64) (err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	backup, ok := t.cache[chatId]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	delete(t.cache, chatId)
+
+	defer func() {
+		if err != nil {
+			t.cache[chatId] = backup
+		}
+	}()
+
+	return t.flush()
+}
+
+func (t *PublicationTargetProvider) GetTarget(chatId int64) (*dao.PublicationTargetModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	data, ok := t.cache[chatId]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (t *PublicationTargetProvider) GetTargetsByGroupId(groupId string) ([]dao.PublicationTargetModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	var result []dao.PublicationTargetModel
+
+	for _, val := range t.cache {
+		if val.GroupId == groupId {
+			result = append(result, val)
+		}
+	}
+
+	return result, nil
+}
+
+func (t *PublicationTargetProvider) flush() error {
+	data, err := json.Marshal(t.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return t.common.saveAllDataToStorage(data)
+}
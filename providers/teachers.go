@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type TeacherProvider struct {
+	common *CommonProvider
+	cache  map[string]dao.TeacherModel
+	mutex  *sync.RWMutex
+}
+
+func NewTeacherProvider() *TeacherProvider {
+	common := newCommonProvider("teachers")
+
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[string]dao.TeacherModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[string]dao.TeacherModel)
+		}
+	}
+
+	return &TeacherProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+func (t *TeacherProvider) CreateNewTeacher(model dao.TeacherModel) (str string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	id := uuid.NewString()
+	model.Id = id
+
+	t.cache[id] = model
+
+	defer func() {
+		if err != nil {
+			delete(t.cache, id)
+		}
+	}()
+
+	if err = t.flush(); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (t *TeacherProvider) UpdateTeacher(model dao.TeacherModel) (err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	backup, exists := t.cache[model.Id]
+
+	if !exists {
+		return exceptions.NotFound
+	}
+
+	t.cache[model.Id] = model
+
+	defer func() {
+		if err != nil {
+			t.cache[model.Id] = backup
+		}
+	}()
+
+	return t.flush()
+}
+
+func (t *TeacherProvider) GetTeachers() ([]dao.TeacherModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	var result []dao.TeacherModel
+
+	for _, val := range t.cache {
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+func (t *TeacherProvider) GetTeacherById(id string) (*dao.TeacherModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	data, ok := t.cache[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+func (t *TeacherProvider) GetTeacherByUserId(userId int) (*dao.TeacherModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, val := range t.cache {
+		if val.UserId == userId {
+			return &val, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+// GetTeacherByName finds the teacher by the name written in the courses, the case and the spaces around are ignored
+func (t *TeacherProvider) GetTeacherByName(name string) (*dao.TeacherModel, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, val := range t.cache {
+		if strings.EqualFold(strings.TrimSpace(val.Name), strings.TrimSpace(name)) {
+			return &val, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func (t *TeacherProvider) flush() error {
+	data, err := json.Marshal(t.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return t.common.saveAllDataToStorage(data)
+}
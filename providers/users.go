@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type UserProvider struct {
+	common *CommonProvider
+	cache  map[int]dao.UserModel
+	mutex  *sync.RWMutex
+}
+
+func NewUserProvider() *UserProvider {
+	common := newCommonProvider("users")
+	data, err := common.getAllDataFromStorage()
+
+	cache := make(map[int]dao.UserModel)
+
+	if err == nil {
+		err = json.Unmarshal(data, &cache)
+
+		if err != nil {
+			cache = make(map[int]dao.UserModel)
+		}
+	}
+
+	return &UserProvider{common: common, cache: cache, mutex: &sync.RWMutex{}}
+}
+
+// SaveUser stores the telegram profile of the user, the storage is not touched when nothing changed
+func (u *UserProvider) SaveUser(model dao.UserModel) (err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	backup, exists := u.cache[model.Id]
+
+	if exists && backup == model {
+		return nil
+	}
+
+	u.cache[model.Id] = model
+
+	defer func() {
+		if err != nil && exists {
+			u.cache[model.Id] = backup
+		}
+		if err != nil && !exists {
+			delete(u.cache, model.Id)
+		}
+	}()
+
+	data, err := json.Marshal(u.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return u.common.saveAllDataToStorage(data)
+}
+
+func (u *UserProvider) GetUserById(userId int) (*dao.UserModel, error) {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	data, ok := u.cache[userId]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &data, nil
+}
+
+// GetUserByUserName searches the user by the telegram username, the leading @ is optional
+func (u *UserProvider) GetUserByUserName(userName string) (*dao.UserModel, error) {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	userName = strings.TrimPrefix(userName, "@")
+
+	for _, val := range u.cache {
+		if val.UserName != "" && strings.EqualFold(val.UserName, userName) {
+			return &val, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+// SetBlockedBot marks the user who blocked the bot, saving the profile on the next message clears the mark
+func (u *UserProvider) SetBlockedBot(userId int, blocked bool) (err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	backup, ok := u.cache[userId]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	if backup.BlockedBot == blocked {
+		return nil
+	}
+
+	model := backup
+	model.BlockedBot = blocked
+	u.cache[userId] = model
+
+	defer func() {
+		if err != nil {
+			u.cache[userId] = backup
+		}
+	}()
+
+	data, err := json.Marshal(u.cache)
+
+	if err != nil {
+		return err
+	}
+
+	return u.common.saveAllDataToStorage(data)
+}
@@ -2,163 +2,381 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"telegram-notification-bot-core/abstractions"
 	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
 	"telegram-notification-bot-core/dto"
 	"telegram-notification-bot-core/util"
 	"time"
 )
 
-type HandleFunc func(scheduleDto dto.ScheduleDto, startTime time.Time, recipient int64)
+type HandleFunc func(scheduleDto dto.ScheduleDto, startTime time.Time, recipient int64) error
+
+// CatchUpHandleFunc sends the summary of the missed reminders and calls done when it is delivered
+type CatchUpHandleFunc func(schedules []dto.ScheduleDto, recipient int64, done func(err error))
+
+type ExamHandleFunc func(examDto dto.ExamDto, recipient int64)
+
+type DailyScheduleHandleFunc func(schedules []dto.ScheduleDto, recipient int64)
+
+type SelectionHandleFunc func(pendingDto dto.RosterPendingDto, deadline time.Time, recipient int64)
+
+type DigestHandleFunc func(schedule dto.GetScheduleResponse, kind util.DigestKind, recipient int64)
 
 type BackgroundService struct {
-	scheduleService abstractions.IScheduleService
-	chatProvider    abstractions.IChatProvider
-	cfg             configuration.Configuration
-	handlers        map[int]map[int]<-chan struct{}
+	groupService       abstractions.IGroupService
+	teacherService     abstractions.ITeacherService
+	preferencesService abstractions.IPreferencesService
+	chatProvider       abstractions.IChatProvider
+	outboxProvider     abstractions.IOutboxProvider
+	cfg                configuration.Configuration
+	scheduler          *reminderScheduler
 }
 
-func NewBackgroundService(scheduleService abstractions.IScheduleService, chatProvider abstractions.IChatProvider, cfg configuration.Configuration) *BackgroundService {
-	return &BackgroundService{scheduleService: scheduleService, chatProvider: chatProvider, cfg: cfg, handlers: map[int]map[int]<-chan struct{}{}}
+func NewBackgroundService(
+	groupService abstractions.IGroupService,
+	teacherService abstractions.ITeacherService,
+	preferencesService abstractions.IPreferencesService,
+	chatProvider abstractions.IChatProvider,
+	outboxProvider abstractions.IOutboxProvider,
+	cfg configuration.Configuration) *BackgroundService {
+	return &BackgroundService{
+		groupService:       groupService,
+		teacherService:     teacherService,
+		preferencesService: preferencesService,
+		chatProvider:       chatProvider,
+		outboxProvider:     outboxProvider,
+		cfg:                cfg,
+		scheduler:          newReminderScheduler(outboxProvider),
+	}
 }
 
-func (b BackgroundService) Run(ctx context.Context, handleFunc HandleFunc) {
+func (b BackgroundService) Run(
+	ctx context.Context,
+	handleFunc HandleFunc,
+	catchUpHandleFunc CatchUpHandleFunc,
+	examHandleFunc ExamHandleFunc,
+	selectionHandleFunc SelectionHandleFunc,
+	dailyHandleFunc DailyScheduleHandleFunc,
+	digestHandleFunc DigestHandleFunc) {
 	ticker := time.NewTicker(b.cfg.ScheduleSettings.ScheduleRefreshInterval)
-	accounts := b.cfg.Security.AllowedAccountIds
-	schedules, err := b.scheduleService.PrepareSchedulesListForNotify(accounts)
 
-	if err == nil {
-		for _, accountId := range accounts {
-			b.handlers[accountId] = map[int]<-chan struct{}{}
-			b.doCycle(ctx, schedules, accountId, handleFunc)
-		}
-	}
+	go b.scheduler.Run(ctx, handleFunc)
+
+	b.doGroupsCycle(examHandleFunc, selectionHandleFunc, dailyHandleFunc, digestHandleFunc)
+	b.scheduler.CatchUp(catchUpHandleFunc)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			accounts = b.cfg.Security.AllowedAccountIds
+			b.doGroupsCycle(examHandleFunc, selectionHandleFunc, dailyHandleFunc, digestHandleFunc)
+		}
+	}
+}
+
+// doGroupsCycle plans the reminders of every group for its members and chats, the reminders of the owners
+// which are gone since the previous cycle are cancelled unless some schedule could not be read
+func (b BackgroundService) doGroupsCycle(
+	examHandleFunc ExamHandleFunc,
+	selectionHandleFunc SelectionHandleFunc,
+	dailyHandleFunc DailyScheduleHandleFunc,
+	digestHandleFunc DigestHandleFunc) {
+	owners := map[string]bool{}
+	complete := true
+
+	// only today's lessons are planned, the delivery history of the previous days is not needed
+	_ = b.outboxProvider.DeleteEntriesBefore(util.GetMidnightTime())
 
-			schedules, err = b.scheduleService.PrepareSchedulesListForNotify(accounts)
+	for _, scope := range b.groupService.GetScopes() {
+		b.doExamCycle(scope, examHandleFunc)
+		b.doSelectionCycle(scope, selectionHandleFunc)
+		b.doDigestCycle(scope, digestHandleFunc)
+
+		schedules, err := scope.Schedule.PrepareSchedulesListForNotify(scope.MemberIds)
+
+		if err != nil {
+			complete = false
+			continue
+		}
+
+		for _, accountId := range scope.MemberIds {
+			chatId, err := b.chatProvider.GetChatByUserId(accountId)
 
 			if err != nil {
 				continue
 			}
 
-			for _, accountId := range accounts {
-				_, exists := b.handlers[accountId]
+			key := fmt.Sprintf("%s/%d", scope.GroupId, accountId)
+			owners[key] = true
 
-				if !exists {
-					b.handlers[accountId] = map[int]<-chan struct{}{}
-				}
-
-				b.doCycle(ctx, schedules, accountId, handleFunc)
-			}
+			b.planReminders(schedules[accountId], b.getReminderOffsets(accountId), key, chatId)
 		}
+
+		complete = b.doTargetsCycle(scope, owners, dailyHandleFunc) && complete
+	}
+
+	complete = b.doTeachersCycle(owners) && complete
+
+	if complete {
+		b.scheduler.Retain(owners)
 	}
 }
 
-func (b BackgroundService) doCycle(
-	ctx context.Context,
-	schedules map[int][]dto.ScheduleDto,
-	accountId int,
-	handleFunc HandleFunc) {
-	filteredNotifications := b.filterOverdueNotifications(schedules[accountId])
+// doTeachersCycle plans the reminders about the lessons the teachers give in all groups
+func (b BackgroundService) doTeachersCycle(owners map[string]bool) bool {
+	schedules, err := b.teacherService.PrepareTeacherSchedulesForNotify()
 
-	for _, notification := range filteredNotifications {
+	if err != nil {
+		return false
+	}
 
-		_, exists := b.handlers[accountId][notification.Order]
+	for userId, teacherSchedules := range schedules {
+		chatId, err := b.chatProvider.GetChatByUserId(userId)
 
-		if exists {
+		if err != nil {
 			continue
 		}
 
-		b.handlers[accountId][notification.Order] = b.initHandler(ctx, handleFunc, notification, accountId)
+		key := fmt.Sprintf("teacher/%d", userId)
+		owners[key] = true
 
-		notification := notification
-		go func() {
-			for {
-				select {
-				case <-b.handlers[accountId][notification.Order]:
-					delete(b.handlers[accountId], notification.Order)
-				default:
-					time.Sleep(time.Second)
-				}
+		b.planReminders(teacherSchedules, b.getReminderOffsets(userId), key, chatId)
+	}
+
+	return true
+}
+
+// doTargetsCycle posts the lesson reminders and the daily schedule to the chats and channels of the group
+func (b BackgroundService) doTargetsCycle(
+	scope abstractions.GroupScope,
+	owners map[string]bool,
+	dailyHandleFunc DailyScheduleHandleFunc) bool {
+	targets, err := b.groupService.GetPublicationTargets(scope.GroupId)
+
+	if err != nil {
+		return false
+	}
+
+	if len(targets) == 0 {
+		return true
+	}
+
+	schedules, err := scope.Schedule.PrepareChatScheduleForNotify()
+
+	if err != nil {
+		return false
+	}
+
+	actualTime := time.Now()
+	dailyTime := util.GetMidnightTime().Add(b.cfg.ScheduleSettings.DailyScheduleTime)
+
+	for _, target := range targets {
+		if target.DailySchedule && !actualTime.Before(dailyTime) && target.LastDailyScheduleAt.Before(util.GetMidnightTime()) {
+			if err = b.groupService.MarkDailySchedulePosted(target.ChatId, actualTime); err == nil && len(schedules) > 0 {
+				dailyHandleFunc(schedules, target.ChatId)
 			}
-		}()
+		}
+
+		if !target.Reminders {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/chat%d", scope.GroupId, target.ChatId)
+		owners[key] = true
+
+		b.planReminders(schedules, b.preferencesService.GetDefaultReminderOffsets(), key, target.ChatId)
 	}
+
+	return true
 }
 
-func (b BackgroundService) filterOverdueNotifications(scheduleListDto []dto.ScheduleDto) []dto.ScheduleDto {
+// getReminderOffsets returns the personal reminder offsets of the user, none when the reminders are off
+func (b BackgroundService) getReminderOffsets(userId int) []int {
+	settings := b.preferencesService.GetReminderSettings(userId)
+
+	if !settings.Enabled {
+		return nil
+	}
+
+	return settings.Offsets
+}
+
+// planReminders plans a reminder for every offset before today's lessons, no offsets cancel the reminders.
+// The reminders which are already due are kept while the lesson lasts to be reported as missed after a restart
+func (b BackgroundService) planReminders(schedules []dto.ScheduleDto, offsets []int, owner string, chatId int64) {
 	actualTime := time.Now()
+	today := util.GetMidnightTime()
+	var reminders []*plannedReminder
 
-	var filteredSchedules []dto.ScheduleDto
+	for _, schedule := range schedules {
+		slot := b.cfg.ScheduleSettings.TimeSlotsConfiguration[schedule.Order]
+		startTime := today.Add(slot.StartTime)
+		endTime := today.Add(slot.EndTime)
 
-	for _, schedule := range scheduleListDto {
-		startTime := util.GetMidnightTime().Add(b.cfg.ScheduleSettings.TimeSlotsConfiguration[schedule.Order].StartTime)
+		if slot.EndTime < slot.StartTime {
+			endTime = startTime
+		}
 
-		if actualTime.After(startTime) {
+		if !actualTime.Before(endTime) {
 			continue
 		}
 
-		filteredSchedules = append(filteredSchedules, schedule)
+		for _, offset := range offsets {
+			reminders = append(reminders, &plannedReminder{
+				id:        reminderId(schedule, startTime, offset),
+				date:      today,
+				offset:    offset,
+				dueAt:     startTime.Add(-time.Duration(offset) * time.Minute),
+				startTime: startTime,
+				chatId:    chatId,
+				schedule:  schedule,
+			})
+		}
 	}
 
-	return filteredSchedules
+	b.scheduler.Plan(owner, reminders)
 }
 
-func (b BackgroundService) initHandler(
-	ctx context.Context,
-	handleFunc HandleFunc,
-	args dto.ScheduleDto,
-	accountId int) chan struct{} {
-	ticker := time.NewTicker(time.Second)
+// doExamCycle sends the nearest due exam reminder, reminders which became outdated
+// while the service was not running are skipped
+func (b BackgroundService) doExamCycle(scope abstractions.GroupScope, handleFunc ExamHandleFunc) {
+	exams, err := scope.Exams.GetUpcomingExams()
+
+	if err != nil {
+		return
+	}
 
-	startTime := util.GetMidnightTime().Add(b.cfg.ScheduleSettings.TimeSlotsConfiguration[args.Order].StartTime)
+	intervals := append([]int{}, b.cfg.ScheduleSettings.ExamReminderIntervals...)
+	sort.Sort(sort.Reverse(sort.IntSlice(intervals)))
 
-	reminderSlice := append(b.cfg.ScheduleSettings.ReminderIntervals, 0)
+	actualTime := time.Now()
+
+	for _, exam := range exams.Exams {
+		due := -1
+
+		for _, days := range intervals {
+			if actualTime.Before(exam.StartTime.AddDate(0, 0, -days)) {
+				break
+			}
+
+			due = days
+		}
+
+		if due < 0 {
+			continue
+		}
 
-	cancelChan := make(chan struct{})
+		// the last sent reminder is kept in the outbox until the exam, so it is not repeated after a restart
+		id := fmt.Sprintf("exam/%s/%s/%d", scope.GroupId, exam.Id, exam.StartTime.Unix())
+		offset := due * 24 * 60
 
-	go func() {
+		if entry, err := b.outboxProvider.GetEntry(id); err == nil && entry.Offset <= offset {
+			continue
+		}
 
-		chatId, err := b.chatProvider.GetChatByUserId(accountId)
+		err = b.outboxProvider.SaveEntries([]dao.OutboxEntryModel{{
+			Id:        id,
+			Owner:     scope.GroupId,
+			Date:      exam.StartTime,
+			Offset:    offset,
+			DueAt:     exam.StartTime.AddDate(0, 0, -due),
+			State:     util.OutboxStateSent,
+			UpdatedAt: actualTime,
+		}})
 
 		if err != nil {
-			return
+			continue
 		}
 
-		for {
-			select {
-			case <-ctx.Done():
-				cancelChan <- struct{}{}
-				return
-			case <-ticker.C:
-				actualTime := time.Now()
-
-				for _, rem := range reminderSlice {
-
-					if startTime.Minute()-actualTime.Minute() < rem {
-						reminderSlice = reminderSlice[1:]
-					}
-
-					if startTime.Minute()-actualTime.Minute() == rem {
-						reminderSlice = reminderSlice[1:]
-
-						handleFunc(args, startTime, chatId)
-					}
-				}
-			default:
-				if len(reminderSlice) == 0 {
-					cancelChan <- struct{}{}
-					return
-				}
-				time.Sleep(time.Millisecond)
+		for _, accountId := range scope.MemberIds {
+			chatId, err := b.chatProvider.GetChatByUserId(accountId)
+
+			if err != nil {
+				continue
 			}
+
+			handleFunc(exam, chatId)
+		}
+	}
+}
+
+// doDigestCycle sends the morning digest of today and the evening preview of tomorrow to the members
+// whose active group is the scope. The morning digest is skipped once the first lesson has started,
+// e.g. after a downtime, the lesson reminders cover the rest of the day
+func (b BackgroundService) doDigestCycle(scope abstractions.GroupScope, handleFunc DigestHandleFunc) {
+	actualTime := time.Now()
+	dates := map[util.DigestKind]time.Time{
+		util.DigestKindMorning: util.GetMidnightTime(),
+		util.DigestKindEvening: util.GetMidnightTime().AddDate(0, 0, 1),
+	}
+
+	for _, accountId := range scope.MemberIds {
+		if b.groupService.GetUserScope(accountId).GroupId != scope.GroupId {
+			continue
 		}
-	}()
 
-	return cancelChan
+		for kind, date := range dates {
+			if !b.preferencesService.IsDigestDue(accountId, kind, actualTime) {
+				continue
+			}
+
+			chatId, err := b.chatProvider.GetChatByUserId(accountId)
+
+			if err != nil {
+				continue
+			}
+
+			schedule, err := scope.Schedule.GetScheduleForDate(accountId, date)
+
+			if err != nil {
+				continue
+			}
+
+			if err = b.preferencesService.MarkDigestSent(accountId, kind, actualTime); err != nil {
+				continue
+			}
+
+			if kind == util.DigestKindMorning && b.isDigestLate(*schedule, actualTime) {
+				continue
+			}
+
+			handleFunc(*schedule, kind, chatId)
+		}
+	}
+}
+
+// isDigestLate tells whether the first lesson of the day has already started at the time
+func (b BackgroundService) isDigestLate(schedule dto.GetScheduleResponse, at time.Time) bool {
+	for _, val := range schedule.Schedules {
+		start := schedule.CurrentDate.Add(b.cfg.ScheduleSettings.TimeSlotsConfiguration[val.Order].StartTime)
+
+		if !at.Before(start) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// doSelectionCycle reminds the students who have not chosen optional courses before the selection deadline
+func (b BackgroundService) doSelectionCycle(scope abstractions.GroupScope, handleFunc SelectionHandleFunc) {
+	reminders, err := scope.Electives.PrepareSelectionReminders(scope.MemberIds)
+
+	if err != nil {
+		return
+	}
+
+	for _, pending := range reminders.Pending {
+		chatId, err := b.chatProvider.GetChatByUserId(pending.Student.UserId)
+
+		if err != nil {
+			continue
+		}
+
+		handleFunc(pending, reminders.Deadline, chatId)
+	}
 }
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"gopkg.in/yaml.v2"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dto"
+	"testing"
+	"time"
+)
+
+func TestIsDigestLate(t *testing.T) {
+	var config configuration.Configuration
+
+	err := yaml.Unmarshal([]byte(`
+schedule-settings:
+  time-slots-configuration:
+    1: {start-time: 8h, end-time: 9h30m}
+    2: {start-time: 10h, end-time: 11h30m}
+`), &config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)
+	service := BackgroundService{cfg: config}
+
+	tests := []struct {
+		name   string
+		orders []int
+		at     time.Duration
+		want   bool
+	}{
+		{name: "before the first lesson", orders: []int{1, 2}, at: 7 * time.Hour},
+		{name: "first lesson started", orders: []int{1, 2}, at: 8 * time.Hour, want: true},
+		{name: "day starts with the second lesson", orders: []int{2}, at: 9 * time.Hour},
+		{name: "second lesson started", orders: []int{2}, at: 10*time.Hour + time.Minute, want: true},
+		{name: "no lessons", at: 15 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := dto.GetScheduleResponse{CurrentDate: day}
+
+			for _, order := range tt.orders {
+				schedule.Schedules = append(schedule.Schedules, dto.ScheduleDto{Order: order})
+			}
+
+			if got := service.isDigestLate(schedule, day.Add(tt.at)); got != tt.want {
+				t.Errorf("isDigestLate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+const defaultChangeNotificationDays = 2 // today and tomorrow
+
+// GetUpcomingSchedules returns the lessons of every user for the days covered by the change notifications,
+// starting today
+func (s ScheduleService) GetUpcomingSchedules(userIds []int) (map[int][]dto.GetScheduleResponse, error) {
+	today := util.GetMidnightTime()
+	result := map[int][]dto.GetScheduleResponse{}
+
+	for _, userId := range userIds {
+		for day := 0; day < s.getChangeNotificationDays(); day++ {
+			schedule, err := s.GetScheduleForDate(userId, today.AddDate(0, 0, day))
+
+			if err != nil {
+				return nil, err
+			}
+
+			result[userId] = append(result[userId], *schedule)
+		}
+	}
+
+	return result, nil
+}
+
+// DiffUpcomingSchedules compares the lessons of every user before and after an edit, only the users whose
+// lessons changed are returned, the lessons which are already over are skipped
+func (s ScheduleService) DiffUpcomingSchedules(before, after map[int][]dto.GetScheduleResponse) map[int][]dto.LessonChangeDto {
+	actualTime := time.Now()
+	result := map[int][]dto.LessonChangeDto{}
+
+	for userId, schedules := range after {
+		previous := map[int64]dto.GetScheduleResponse{}
+
+		for _, schedule := range before[userId] {
+			previous[schedule.CurrentDate.Unix()] = schedule
+		}
+
+		for _, schedule := range schedules {
+			for _, change := range diffLessons(previous[schedule.CurrentDate.Unix()].Schedules, schedule.Schedules) {
+				slot := s.config.ScheduleSettings.TimeSlotsConfiguration[change.Order]
+
+				if !actualTime.Before(schedule.CurrentDate.Add(slot.EndTime)) {
+					continue
+				}
+
+				change.Date = schedule.CurrentDate
+				result[userId] = append(result[userId], change)
+			}
+		}
+	}
+
+	return result
+}
+
+// IsUpcomingDate tells whether the lessons of the date are covered by the change notifications
+func (s ScheduleService) IsUpcomingDate(date time.Time) bool {
+	return date.Before(util.GetMidnightTime().AddDate(0, 0, s.getChangeNotificationDays()))
+}
+
+func (s ScheduleService) getChangeNotificationDays() int {
+	if days := s.config.ScheduleSettings.ChangeNotificationDays; days > 0 {
+		return days
+	}
+
+	return defaultChangeNotificationDays
+}
+
+// diffLessons matches the lessons of a day by the order and the subgroup
+func diffLessons(before, after []dto.ScheduleDto) []dto.LessonChangeDto {
+	previous := map[string]dto.ScheduleDto{}
+	var keys []string
+
+	for _, val := range before {
+		key := lessonDiffKey(val)
+		previous[key] = val
+		keys = append(keys, key)
+	}
+
+	var changes []dto.LessonChangeDto
+
+	for _, val := range after {
+		key := lessonDiffKey(val)
+		old, exists := previous[key]
+		delete(previous, key)
+
+		if exists && old.CourseInfo == val.CourseInfo {
+			continue
+		}
+
+		lesson := val
+		change := dto.LessonChangeDto{Order: val.Order, After: &lesson}
+
+		if exists {
+			change.Before = &old
+		}
+
+		changes = append(changes, change)
+	}
+
+	for _, key := range keys {
+		if old, exists := previous[key]; exists {
+			changes = append(changes, dto.LessonChangeDto{Order: old.Order, Before: &old})
+			delete(previous, key)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Order < changes[j].Order
+	})
+
+	return changes
+}
+
+func lessonDiffKey(schedule dto.ScheduleDto) string {
+	return fmt.Sprintf("%d|%s", schedule.Order, schedule.Subgroup)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"gopkg.in/yaml.v2"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+func testLesson(order int, subgroup string, course string) dto.ScheduleDto {
+	return dto.ScheduleDto{Order: order, Subgroup: subgroup, CourseInfo: dto.CourseDto{Id: course, Name: course}}
+}
+
+// describeChanges turns the changes into "order:before>after" for the comparison, "-" is an absent lesson
+func describeChanges(changes []dto.LessonChangeDto) []string {
+	var result []string
+
+	for _, change := range changes {
+		before, after := "-", "-"
+
+		if change.Before != nil {
+			before = change.Before.CourseInfo.Id + change.Before.Subgroup
+		}
+
+		if change.After != nil {
+			after = change.After.CourseInfo.Id + change.After.Subgroup
+		}
+
+		result = append(result, before+">"+after)
+	}
+
+	return result
+}
+
+func TestDiffLessons(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []dto.ScheduleDto
+		after  []dto.ScheduleDto
+		want   []string
+	}{
+		{
+			name:   "nothing changed",
+			before: []dto.ScheduleDto{testLesson(1, "", "math"), testLesson(2, "", "art")},
+			after:  []dto.ScheduleDto{testLesson(2, "", "art"), testLesson(1, "", "math")},
+		},
+		{
+			name:   "course replaced",
+			before: []dto.ScheduleDto{testLesson(1, "", "math")},
+			after:  []dto.ScheduleDto{testLesson(1, "", "art")},
+			want:   []string{"math>art"},
+		},
+		{
+			name:   "lesson cancelled",
+			before: []dto.ScheduleDto{testLesson(1, "", "math"), testLesson(2, "", "art")},
+			after:  []dto.ScheduleDto{testLesson(2, "", "art")},
+			want:   []string{"math>-"},
+		},
+		{
+			name:  "lesson added",
+			after: []dto.ScheduleDto{testLesson(3, "", "art")},
+			want:  []string{"->art"},
+		},
+		{
+			name:   "another subgroup is another lesson",
+			before: []dto.ScheduleDto{testLesson(1, "a", "math")},
+			after:  []dto.ScheduleDto{testLesson(1, "b", "math")},
+			want:   []string{"->mathb", "matha>-"},
+		},
+		{
+			name:   "ordered by the lesson",
+			before: []dto.ScheduleDto{testLesson(3, "", "math"), testLesson(1, "", "art")},
+			after:  []dto.ScheduleDto{testLesson(2, "", "bio")},
+			want:   []string{"art>-", "->bio", "math>-"},
+		},
+		{
+			name:   "meet link changed",
+			before: []dto.ScheduleDto{testLesson(1, "", "math")},
+			after: []dto.ScheduleDto{{
+				Order:      1,
+				CourseInfo: dto.CourseDto{Id: "math", Name: "math", MeetLink: "https://meet"},
+			}},
+			want: []string{"math>math"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeChanges(diffLessons(tt.before, tt.after)); !equalStrings(got, tt.want) {
+				t.Errorf("diffLessons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffUpcomingSchedules(t *testing.T) {
+	var config configuration.Configuration
+
+	// the first lesson is over by now, the second one lasts until the end of the day
+	err := yaml.Unmarshal([]byte(`
+schedule-settings:
+  time-slots-configuration:
+    1: {start-time: 0s, end-time: 1ns}
+    2: {start-time: 1ns, end-time: 24h}
+`), &config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := ScheduleService{config: config}
+	today := util.GetMidnightTime()
+	tomorrow := today.AddDate(0, 0, 1)
+
+	day := func(date time.Time, lessons ...dto.ScheduleDto) dto.GetScheduleResponse {
+		return dto.GetScheduleResponse{CurrentDate: date, Schedules: lessons}
+	}
+
+	tests := []struct {
+		name      string
+		before    []dto.GetScheduleResponse
+		after     []dto.GetScheduleResponse
+		want      []string
+		wantDates []time.Time
+	}{
+		{
+			name:   "nothing changed",
+			before: []dto.GetScheduleResponse{day(today), day(tomorrow, testLesson(1, "", "math"))},
+			after:  []dto.GetScheduleResponse{day(today), day(tomorrow, testLesson(1, "", "math"))},
+		},
+		{
+			name:      "change of tomorrow",
+			before:    []dto.GetScheduleResponse{day(today), day(tomorrow, testLesson(1, "", "math"))},
+			after:     []dto.GetScheduleResponse{day(today), day(tomorrow, testLesson(1, "", "art"))},
+			want:      []string{"math>art"},
+			wantDates: []time.Time{tomorrow},
+		},
+		{
+			name:      "the lesson which is over is skipped",
+			before:    []dto.GetScheduleResponse{day(today, testLesson(1, "", "math"), testLesson(2, "", "math"))},
+			after:     []dto.GetScheduleResponse{day(today)},
+			want:      []string{"math>-"},
+			wantDates: []time.Time{today},
+		},
+		{
+			name:      "the changes of several days",
+			before:    []dto.GetScheduleResponse{day(today), day(tomorrow)},
+			after:     []dto.GetScheduleResponse{day(today, testLesson(2, "", "art")), day(tomorrow, testLesson(1, "", "bio"))},
+			want:      []string{"->art", "->bio"},
+			wantDates: []time.Time{today, tomorrow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := service.DiffUpcomingSchedules(
+				map[int][]dto.GetScheduleResponse{1: tt.before},
+				map[int][]dto.GetScheduleResponse{1: tt.after})
+
+			changes, exists := result[1]
+
+			if exists != (len(tt.want) > 0) {
+				t.Fatalf("the user is returned = %v, want %v", exists, len(tt.want) > 0)
+			}
+
+			if got := describeChanges(changes); !equalStrings(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+
+			for i, change := range changes {
+				if !change.Date.Equal(tt.wantDates[i]) {
+					t.Errorf("date of the change %d = %v, want %v", i, change.Date, tt.wantDates[i])
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"strings"
 	"telegram-notification-bot-core/abstractions"
 	"telegram-notification-bot-core/dao"
 	"telegram-notification-bot-core/dto"
@@ -9,11 +10,12 @@ import (
 )
 
 type CourseService struct {
-	provider abstractions.ICourseProvider
+	provider        abstractions.ICourseProvider
+	teacherProvider abstractions.ITeacherProvider
 }
 
-func NewCourseService(provider abstractions.ICourseProvider) *CourseService {
-	return &CourseService{provider: provider}
+func NewCourseService(provider abstractions.ICourseProvider, teacherProvider abstractions.ITeacherProvider) *CourseService {
+	return &CourseService{provider: provider, teacherProvider: teacherProvider}
 }
 
 func (c CourseService) CreateNewCourse(request dto.CreateNewCourseRequest) (string, error) {
@@ -23,6 +25,7 @@ func (c CourseService) CreateNewCourse(request dto.CreateNewCourseRequest) (stri
 		id, err := c.provider.CreateNewCourse(dao.CourseModel{
 			Name:           request.Name,
 			TeacherName:    request.TeacherName,
+			TeacherId:      c.findTeacherId(request.TeacherName),
 			TeacherContact: request.TeacherContact,
 			MeetLink:       request.MeetLink,
 			IsOptional:     request.IsOptional,
@@ -47,12 +50,79 @@ func (c CourseService) UpdateCourse(request dto.UpdateCourseInfoRequest) error {
 		Id:             request.Id,
 		Name:           request.Name,
 		TeacherName:    request.TeacherName,
+		TeacherId:      c.findTeacherId(request.TeacherName),
 		TeacherContact: request.TeacherContact,
 		MeetLink:       request.MeetLink,
 		IsOptional:     request.IsOptional,
+		Capacity:       request.Capacity,
 	})
 }
 
+func (c CourseService) UpdateMeetLink(request dto.UpdateMeetLinkRequest) error {
+	course, err := c.provider.GetCourseById(request.CourseId)
+
+	if err != nil {
+		return exceptions.NotFound
+	}
+
+	course.MeetLink = request.MeetLink
+
+	return c.provider.UpdateCourse(*course)
+}
+
+// LinkTeacher refers the courses with the teacher name to the teacher, courses which have
+// another teacher name now are unlinked
+func (c CourseService) LinkTeacher(teacherId string, teacherName string) error {
+	courses, err := c.provider.GetCourses()
+
+	if err != nil {
+		return err
+	}
+
+	var changed []dao.CourseModel
+
+	for _, course := range courses {
+		matches := strings.EqualFold(strings.TrimSpace(course.TeacherName), strings.TrimSpace(teacherName))
+
+		if matches && course.TeacherId != teacherId {
+			course.TeacherId = teacherId
+			changed = append(changed, course)
+		}
+
+		if !matches && course.TeacherId == teacherId {
+			course.TeacherId = ""
+			changed = append(changed, course)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return c.provider.UpsertCourses(changed)
+}
+
+// SetCourseCapacity limits the seats of the optional course, the limit applies to every optional slot separately
+func (c CourseService) SetCourseCapacity(request dto.SetCourseCapacityRequest) error {
+	if request.Capacity < 0 {
+		return errors.New("InvalidCapacity")
+	}
+
+	course, err := c.provider.GetCourseById(request.CourseId)
+
+	if err != nil {
+		return err
+	}
+
+	if !course.IsOptional {
+		return errors.New("InvalidCourse")
+	}
+
+	course.Capacity = request.Capacity
+
+	return c.provider.UpdateCourse(*course)
+}
+
 func (c CourseService) DeleteCourse(request dto.ArchiveCourseRequest) error {
 	return c.provider.ArchiveCourse(request.CourseId)
 }
@@ -75,8 +145,11 @@ func (c CourseService) GetOptionalCourses() (*dto.GetCoursesResponse, error) {
 			Name:           course.Name,
 			Id:             course.Id,
 			TeacherName:    course.TeacherName,
+			TeacherId:      course.TeacherId,
 			TeacherContact: course.TeacherContact,
 			MeetLink:       course.MeetLink,
+			IsOptional:     course.IsOptional,
+			Capacity:       course.Capacity,
 		})
 	}
 
@@ -99,8 +172,11 @@ func (c CourseService) GetCourses() (*dto.GetCoursesResponse, error) {
 			Name:           course.Name,
 			Id:             course.Id,
 			TeacherName:    course.TeacherName,
+			TeacherId:      course.TeacherId,
 			TeacherContact: course.TeacherContact,
 			MeetLink:       course.MeetLink,
+			IsOptional:     course.IsOptional,
+			Capacity:       course.Capacity,
 		})
 	}
 
@@ -121,9 +197,40 @@ func (c CourseService) GetCourseById(id string) (*dto.CourseDto, error) {
 		Name:           course.Name,
 		Id:             course.Id,
 		TeacherName:    course.TeacherName,
+		TeacherId:      course.TeacherId,
 		TeacherContact: course.TeacherContact,
 		MeetLink:       course.MeetLink,
 		IsOptional:     course.IsOptional,
+		Capacity:       course.Capacity,
 	}, nil
 
 }
+
+func getCourseDto(provider abstractions.ICourseProvider, courseId string) dto.CourseDto {
+	course, err := provider.GetCourseById(courseId)
+
+	if err != nil {
+		return dto.CourseDto{Id: courseId}
+	}
+
+	return dto.CourseDto{
+		Name:           course.Name,
+		Id:             course.Id,
+		TeacherName:    course.TeacherName,
+		TeacherId:      course.TeacherId,
+		TeacherContact: course.TeacherContact,
+		MeetLink:       course.MeetLink,
+		IsOptional:     course.IsOptional,
+		Capacity:       course.Capacity,
+	}
+}
+
+func (c CourseService) findTeacherId(teacherName string) string {
+	teacher, err := c.teacherProvider.GetTeacherByName(teacherName)
+
+	if err != nil {
+		return ""
+	}
+
+	return teacher.Id
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// GetDraftDiff compares the draft with the published schedule, replacements in the past are ignored
+func (s ScheduleService) GetDraftDiff() (*dto.ScheduleDiffResponse, error) {
+	publishedSchedules, publishedAdditionals := s.provider.Export()
+	draftSchedules, draftAdditionals := s.draft.Export()
+
+	result := dto.ScheduleDiffResponse{
+		AddedSchedules:      s.diffSchedules(draftSchedules, publishedSchedules),
+		RemovedSchedules:    s.diffSchedules(publishedSchedules, draftSchedules),
+		AddedReplacements:   s.diffAdditionals(draftAdditionals, publishedAdditionals),
+		RemovedReplacements: s.diffAdditionals(publishedAdditionals, draftAdditionals),
+	}
+
+	return &result, nil
+}
+
+// PublishDraft swaps the draft in as the published schedule and returns what was changed
+func (s ScheduleService) PublishDraft() (*dto.ScheduleDiffResponse, error) {
+	diff, err := s.GetDraftDiff()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.provider.Import(s.draft.Export()); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// DiscardDraft drops all unpublished edits
+func (s ScheduleService) DiscardDraft() error {
+	return s.draft.Import(s.provider.Export())
+}
+
+// diffSchedules returns lessons of the source which are absent in the target, lessons are compared by content
+func (s ScheduleService) diffSchedules(source, target map[time.Weekday][]dao.ScheduleModel) []dto.ScheduleChangeDto {
+	counts := map[string]int{}
+
+	for _, val := range target {
+		for _, v := range val {
+			counts[scheduleDiffKey(v)]++
+		}
+	}
+
+	var result []dto.ScheduleChangeDto
+
+	for weekday, val := range source {
+		for _, v := range val {
+			key := scheduleDiffKey(v)
+
+			if counts[key] > 0 {
+				counts[key]--
+				continue
+			}
+
+			change := dto.ScheduleChangeDto{
+				Weekday:    weekday,
+				WeekOrder:  v.WeekOrder,
+				Order:      v.Order,
+				IsOptional: v.IsOptional,
+				Subgroup:   v.Subgroup,
+				CourseInfo: dto.CourseDto{Name: "Опціональний курс"},
+			}
+
+			if !v.IsOptional {
+				change.CourseInfo = getCourseDto(s.courseProvider, v.CourseId)
+			}
+
+			result = append(result, change)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Weekday != result[j].Weekday {
+			return result[i].Weekday < result[j].Weekday
+		}
+		return result[i].Order < result[j].Order
+	})
+
+	return result
+}
+
+func (s ScheduleService) diffAdditionals(source, target map[string][]dao.AdditionalScheduleModel) []dto.ReplacementChangeDto {
+	counts := map[string]int{}
+
+	for _, val := range target {
+		for _, v := range val {
+			counts[additionalDiffKey(v)]++
+		}
+	}
+
+	today := util.GetMidnightTime()
+
+	var result []dto.ReplacementChangeDto
+
+	for _, val := range source {
+		for _, v := range val {
+			key := additionalDiffKey(v)
+
+			if counts[key] > 0 {
+				counts[key]--
+				continue
+			}
+
+			if v.AdditionalTime.Before(today) {
+				continue
+			}
+
+			change := dto.ReplacementChangeDto{
+				Date:     v.AdditionalTime,
+				Order:    v.Order,
+				IsEmpty:  v.IsEmpty,
+				Subgroup: v.Subgroup,
+			}
+
+			if !v.IsEmpty {
+				change.CourseInfo = getCourseDto(s.courseProvider, v.CourseId)
+			}
+
+			result = append(result, change)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.Before(result[j].Date)
+		}
+		return result[i].Order < result[j].Order
+	})
+
+	return result
+}
+
+func scheduleDiffKey(model dao.ScheduleModel) string {
+	return fmt.Sprintf("%d|%d|%d|%s|%t|%s", model.Weekday, model.WeekOrder, model.Order, model.CourseId, model.IsOptional, model.Subgroup)
+}
+
+func additionalDiffKey(model dao.AdditionalScheduleModel) string {
+	return fmt.Sprintf("%s|%d|%s|%t|%s", model.AdditionalTime.Format("2006-01-02"), model.Order, model.CourseId, model.IsEmpty, model.Subgroup)
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+func (f *fakeScheduleProvider) Export() (map[time.Weekday][]dao.ScheduleModel, map[string][]dao.AdditionalScheduleModel) {
+	additionals := map[string][]dao.AdditionalScheduleModel{}
+
+	for date, models := range f.additionals {
+		additionals[date] = append([]dao.AdditionalScheduleModel(nil), models...)
+	}
+
+	return f.GetCommonSchedule(), additionals
+}
+
+func (f *fakeScheduleProvider) Import(schedules map[time.Weekday][]dao.ScheduleModel, additionals map[string][]dao.AdditionalScheduleModel) error {
+	if f.replaceErr != nil {
+		return f.replaceErr
+	}
+
+	if err := f.ReplaceCommonSchedule(schedules); err != nil {
+		return err
+	}
+
+	f.additionals = additionals
+	return nil
+}
+
+// newTestDraft starts from the published schedule, drops History, adds Art on Fridays and has
+// a replacement in the future and one in the past
+func newTestDraft() *fakeScheduleProvider {
+	draft := newTestCancelSchedule()
+	delete(draft.slots, "m2")
+	draft.slots["f1"] = &dao.ScheduleModel{Id: "f1", Weekday: time.Friday, Order: 1, CourseId: "art"}
+
+	future := util.GetMidnightTime().AddDate(0, 0, 3)
+	past := util.GetMidnightTime().AddDate(0, 0, -3)
+
+	draft.additionals[future.Format("2006-01-02")] = []dao.AdditionalScheduleModel{
+		{Id: "a1", AdditionalTime: future, Order: 2, CourseId: "math"},
+	}
+	draft.additionals[past.Format("2006-01-02")] = []dao.AdditionalScheduleModel{
+		{Id: "a2", AdditionalTime: past, Order: 1, IsEmpty: true},
+	}
+
+	return draft
+}
+
+func TestGetDraftDiff(t *testing.T) {
+	provider := newTestCancelSchedule()
+	service := newTestCancelService(t, provider, newTestDraft())
+
+	// a cancellation published earlier and dropped in the draft
+	future := util.GetMidnightTime().AddDate(0, 0, 5)
+	provider.additionals[future.Format("2006-01-02")] = []dao.AdditionalScheduleModel{
+		{Id: "a3", AdditionalTime: future, Order: 1, IsEmpty: true},
+	}
+
+	diff, err := service.GetDraftDiff()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(diff.AddedSchedules) != 1 || diff.AddedSchedules[0].Weekday != time.Friday || diff.AddedSchedules[0].CourseInfo.Name != "Art" {
+		t.Errorf("added schedules = %+v, want Art on Friday", diff.AddedSchedules)
+	}
+
+	if len(diff.RemovedSchedules) != 1 || diff.RemovedSchedules[0].Order != 2 || diff.RemovedSchedules[0].CourseInfo.Name != "History" {
+		t.Errorf("removed schedules = %+v, want History in the second lesson", diff.RemovedSchedules)
+	}
+
+	// the past replacement of the draft is not shown
+	if len(diff.AddedReplacements) != 1 || diff.AddedReplacements[0].CourseInfo.Name != "Math" {
+		t.Errorf("added replacements = %+v, want the future Math only", diff.AddedReplacements)
+	}
+
+	if len(diff.RemovedReplacements) != 1 || !diff.RemovedReplacements[0].IsEmpty {
+		t.Errorf("removed replacements = %+v, want the cancellation", diff.RemovedReplacements)
+	}
+}
+
+func TestGetDraftDiffUnchanged(t *testing.T) {
+	service := newTestCancelService(t, newTestCancelSchedule(), newTestCancelSchedule())
+
+	diff, err := service.GetDraftDiff()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(diff.AddedSchedules)+len(diff.RemovedSchedules)+len(diff.AddedReplacements)+len(diff.RemovedReplacements) != 0 {
+		t.Errorf("diff = %+v, want no changes", diff)
+	}
+}
+
+func TestPublishDraft(t *testing.T) {
+	provider := newTestCancelSchedule()
+	draft := newTestDraft()
+	service := newTestCancelService(t, provider, draft)
+
+	diff, err := service.PublishDraft()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(diff.AddedSchedules) != 1 || len(diff.RemovedSchedules) != 1 {
+		t.Errorf("diff = %+v, want the changes being published", diff)
+	}
+
+	if _, ok := provider.slots["f1"]; !ok {
+		t.Errorf("the published schedule lacks the lesson added in the draft")
+	}
+
+	if _, ok := provider.slots["m2"]; ok {
+		t.Errorf("the published schedule keeps the lesson removed in the draft")
+	}
+
+	after, _ := service.GetDraftDiff()
+
+	if len(after.AddedSchedules)+len(after.RemovedSchedules)+len(after.AddedReplacements)+len(after.RemovedReplacements) != 0 {
+		t.Errorf("diff after publishing = %+v, want no changes", after)
+	}
+
+	// the draft is edited further without touching the published schedule
+	delete(draft.slots, "m1")
+
+	if _, ok := provider.slots["m1"]; !ok {
+		t.Errorf("the published schedule shares the lessons with the draft")
+	}
+}
+
+func TestPublishDraftFailed(t *testing.T) {
+	provider := newTestCancelSchedule()
+	provider.replaceErr = errors.New("WriteFailed")
+	service := newTestCancelService(t, provider, newTestDraft())
+
+	if _, err := service.PublishDraft(); err != provider.replaceErr {
+		t.Errorf("err = %v, want %v", err, provider.replaceErr)
+	}
+
+	if _, ok := provider.slots["m2"]; !ok {
+		t.Errorf("the published schedule changed despite the error")
+	}
+}
+
+func TestDiscardDraft(t *testing.T) {
+	provider := newTestCancelSchedule()
+	draft := newTestDraft()
+	service := newTestCancelService(t, provider, draft)
+
+	if err := service.DiscardDraft(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := draft.slots["f1"]; ok {
+		t.Errorf("the draft keeps the unpublished lesson")
+	}
+
+	if _, ok := draft.slots["m2"]; !ok {
+		t.Errorf("the draft lacks the published lesson")
+	}
+
+	if len(draft.additionals) != 0 {
+		t.Errorf("draft replacements = %v, want none", draft.additionals)
+	}
+}
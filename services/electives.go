@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+)
+
+// ElectiveService manages which optional courses students choose for the optional schedule slots,
+// the choices are written both to the published schedule and to the draft
+type ElectiveService struct {
+	config           configuration.Configuration
+	provider         abstractions.IElectiveProvider
+	scheduleProvider abstractions.IScheduleProvider
+	draftProvider    abstractions.IScheduleProvider
+	courseProvider   abstractions.ICourseProvider
+	userProvider     abstractions.IUserProvider
+	subgroupProvider abstractions.ISubgroupProvider
+	mutex            *sync.Mutex
+}
+
+func NewElectiveService(
+	config configuration.Configuration,
+	provider abstractions.IElectiveProvider,
+	scheduleProvider abstractions.IScheduleProvider,
+	draftProvider abstractions.IScheduleProvider,
+	courseProvider abstractions.ICourseProvider,
+	userProvider abstractions.IUserProvider,
+	subgroupProvider abstractions.ISubgroupProvider) *ElectiveService {
+	return &ElectiveService{
+		config:           config,
+		provider:         provider,
+		scheduleProvider: scheduleProvider,
+		draftProvider:    draftProvider,
+		courseProvider:   courseProvider,
+		userProvider:     userProvider,
+		subgroupProvider: subgroupProvider,
+		mutex:            &sync.Mutex{},
+	}
+}
+
+func (e ElectiveService) CreateElectivePool(request dto.CreateElectivePoolRequest) (string, error) {
+	if strings.TrimSpace(request.Name) == "" {
+		return "", errors.New("InvalidName")
+	}
+
+	if len(request.CourseIds) == 0 || len(request.ScheduleIds) == 0 {
+		return "", errors.New("EmptyPool")
+	}
+
+	for _, courseId := range request.CourseIds {
+		course, err := e.courseProvider.GetCourseById(courseId)
+
+		if err != nil || !course.IsOptional {
+			return "", errors.New("InvalidCourse")
+		}
+	}
+
+	for _, scheduleId := range request.ScheduleIds {
+		slot, err := e.scheduleProvider.GetScheduleById(scheduleId)
+
+		if err != nil || !slot.IsOptional {
+			return "", errors.New("InvalidSchedule")
+		}
+
+		if _, err = e.provider.GetPoolByScheduleId(scheduleId); err == nil {
+			return "", errors.New("ScheduleAlreadyInPool")
+		}
+	}
+
+	return e.provider.CreateNewPool(dao.ElectivePoolModel{
+		Name:        request.Name,
+		CourseIds:   request.CourseIds,
+		ScheduleIds: request.ScheduleIds,
+	})
+}
+
+func (e ElectiveService) DeleteElectivePool(request dto.DeleteElectivePoolRequest) error {
+	return e.provider.DeletePool(request.PoolId)
+}
+
+func (e ElectiveService) GetElectivePools() (*dto.GetElectivePoolsResponse, error) {
+	pools, err := e.provider.GetPools()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var poolsDto []dto.ElectivePoolDto
+
+	for _, pool := range pools {
+		poolDto := dto.ElectivePoolDto{Id: pool.Id, Name: pool.Name}
+
+		for _, courseId := range pool.CourseIds {
+			poolDto.Courses = append(poolDto.Courses, getCourseDto(e.courseProvider, courseId))
+		}
+
+		for _, scheduleId := range pool.ScheduleIds {
+			slot, err := e.scheduleProvider.GetScheduleById(scheduleId)
+
+			if err != nil {
+				continue
+			}
+
+			poolDto.Slots = append(poolDto.Slots, convertToOptionalSlotDto(*slot))
+		}
+
+		poolsDto = append(poolsDto, poolDto)
+	}
+
+	sort.Slice(poolsDto, func(i, j int) bool {
+		return poolsDto[i].Name < poolsDto[j].Name
+	})
+
+	return &dto.GetElectivePoolsResponse{Pools: poolsDto}, nil
+}
+
+// GetAllowedCourses returns courses of the pool the slot is attached to, any optional course
+// is allowed for slots without a pool
+func (e ElectiveService) GetAllowedCourses(scheduleId string) (*dto.GetCoursesResponse, error) {
+	pool, err := e.provider.GetPoolByScheduleId(scheduleId)
+
+	if err != nil && err != exceptions.NotFound {
+		return nil, err
+	}
+
+	courses, err := e.courseProvider.GetCourses()
+
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[string]struct{}{}
+
+	if pool != nil {
+		for _, courseId := range pool.CourseIds {
+			allowed[courseId] = struct{}{}
+		}
+	}
+
+	var coursesDto []dto.CourseDto
+
+	for _, course := range courses {
+		if !course.IsOptional {
+			continue
+		}
+
+		if _, ok := allowed[course.Id]; pool != nil && !ok {
+			continue
+		}
+
+		coursesDto = append(coursesDto, getCourseDto(e.courseProvider, course.Id))
+	}
+
+	sort.Slice(coursesDto, func(i, j int) bool {
+		return coursesDto[i].Name < coursesDto[j].Name
+	})
+
+	return &dto.GetCoursesResponse{Courses: coursesDto}, nil
+}
+
+// LinkOptionalCourseToUser links the course to the student for the optional slot, the student is put
+// to the waitlist when the course is full, a seat freed by switching away goes to the first waitlisted student.
+// The capacity is counted per slot, a course held in two slots has the seats in each of them
+func (e ElectiveService) LinkOptionalCourseToUser(request dto.LinkOptionalCourseToUserRequest) (*dto.LinkOptionalCourseToUserResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	slot, err := e.scheduleProvider.GetScheduleById(request.ScheduleId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !slot.IsOptional {
+		return nil, errors.New("InvalidSchedule")
+	}
+
+	if !e.isVisibleForStudent(*slot, request.UserId) {
+		return nil, exceptions.SubgroupMismatch
+	}
+
+	allowed, err := e.GetAllowedCourses(request.ScheduleId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var course *dto.CourseDto
+
+	for i, val := range allowed.Courses {
+		if val.Id == request.CourseId {
+			course = &allowed.Courses[i]
+		}
+	}
+
+	if course == nil {
+		return nil, exceptions.CourseNotAllowed
+	}
+
+	if !request.IgnoreDeadline && e.isSelectionLocked() {
+		return nil, exceptions.SelectionLocked
+	}
+
+	current, exists := slot.OptCourseParams.UserIdToCourseId[request.UserId]
+
+	if exists && current == request.CourseId {
+		return &dto.LinkOptionalCourseToUserResponse{}, nil
+	}
+
+	if course.Capacity > 0 && countEnrolled(*slot, course.Id) >= course.Capacity {
+		if !e.config.ElectiveSettings.WaitlistEnabled {
+			return nil, exceptions.CourseIsFull
+		}
+
+		position, err := e.addToWaitlist(slot.Id, course.Id, request.UserId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &dto.LinkOptionalCourseToUserResponse{IsWaitlisted: true, Position: position}, nil
+	}
+
+	stage := e.newStage()
+
+	if err = stage.link(request.UserId, slot.Id, course.Id); err != nil {
+		return nil, err
+	}
+
+	response := dto.LinkOptionalCourseToUserResponse{}
+
+	if exists {
+		if response.Promoted, err = stage.promote(slot.Id, current); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = stage.save(); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (e ElectiveService) addToWaitlist(scheduleId string, courseId string, userId int) (int, error) {
+	waitlist, err := e.provider.GetWaitlist(scheduleId, courseId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, val := range waitlist {
+		if val == userId {
+			return i + 1, nil
+		}
+	}
+
+	if err = e.provider.SaveWaitlist(scheduleId, courseId, append(waitlist, userId)); err != nil {
+		return 0, err
+	}
+
+	return len(waitlist) + 1, nil
+}
+
+// isVisibleForStudent tells whether the optional slot is held for the subgroup of the student
+func (e ElectiveService) isVisibleForStudent(slot dao.ScheduleModel, userId int) bool {
+	return util.IsVisibleForSubgroup(slot.Subgroup, e.subgroupProvider.GetUserSubgroup(userId))
+}
+
+// countEnrolled counts the students of the course in this slot only
+func countEnrolled(slot dao.ScheduleModel, courseId string) int {
+	count := 0
+
+	for _, val := range slot.OptCourseParams.UserIdToCourseId {
+		if val == courseId {
+			count++
+		}
+	}
+
+	return count
+}
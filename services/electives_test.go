@@ -0,0 +1,356 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"testing"
+	"time"
+)
+
+func TestLinkOptionalCourseToUserSubgroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		userId     int
+		scheduleId string
+		courseId   string
+		wantErr    error
+	}{
+		{name: "slot of the whole group", userId: 12, scheduleId: "s1", courseId: "math"},
+		{name: "slot of the subgroup", userId: 11, scheduleId: "s2", courseId: "art"},
+		{name: "student without a subgroup", userId: 10, scheduleId: "s2", courseId: "art"},
+		{name: "slot of another subgroup", userId: 12, scheduleId: "s2", courseId: "art", wantErr: exceptions.SubgroupMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestElectiveService().LinkOptionalCourseToUser(dto.LinkOptionalCourseToUserRequest{
+				UserId:         tt.userId,
+				ScheduleId:     tt.scheduleId,
+				CourseId:       tt.courseId,
+				IgnoreDeadline: true,
+			})
+
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAutoAssignElectivesSubgroup(t *testing.T) {
+	response, err := newTestElectiveService().AutoAssignElectives([]int{10, 11, 12})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var assigned []string
+
+	for _, val := range response.Assigned {
+		assigned = append(assigned, fmt.Sprintf("%d/%s/%s", val.Student.UserId, val.Slot.ScheduleId, val.CourseInfo.Id))
+	}
+
+	want := []string{"10/s1/math", "10/s2/art", "11/s1/math", "11/s2/art", "12/s1/math"}
+
+	if !equalStrings(assigned, want) {
+		t.Errorf("assigned = %v, want %v", assigned, want)
+	}
+
+	if len(response.Unassigned) != 0 {
+		t.Errorf("unassigned = %v, want none", response.Unassigned)
+	}
+}
+
+func (f *fakeElectiveProvider) CreateNewPool(model dao.ElectivePoolModel) (string, error) {
+	model.Id = fmt.Sprintf("p%d", len(f.pools)+1)
+	f.pools = append(f.pools, model)
+	return model.Id, nil
+}
+
+func TestCreateElectivePool(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.CreateElectivePoolRequest
+		wantErr error
+	}{
+		{name: "empty name", request: dto.CreateElectivePoolRequest{Name: " ", CourseIds: []string{"math"}, ScheduleIds: []string{"s3"}}, wantErr: errors.New("InvalidName")},
+		{name: "no courses", request: dto.CreateElectivePoolRequest{Name: "Pool", ScheduleIds: []string{"s3"}}, wantErr: errors.New("EmptyPool")},
+		{name: "course is not optional", request: dto.CreateElectivePoolRequest{Name: "Pool", CourseIds: []string{"history"}, ScheduleIds: []string{"s3"}}, wantErr: errors.New("InvalidCourse")},
+		{name: "unknown slot", request: dto.CreateElectivePoolRequest{Name: "Pool", CourseIds: []string{"math"}, ScheduleIds: []string{"s9"}}, wantErr: errors.New("InvalidSchedule")},
+		{name: "slot of another pool", request: dto.CreateElectivePoolRequest{Name: "Pool", CourseIds: []string{"math"}, ScheduleIds: []string{"s1"}}, wantErr: errors.New("ScheduleAlreadyInPool")},
+		{name: "new pool", request: dto.CreateElectivePoolRequest{Name: "Pool", CourseIds: []string{"math", "art"}, ScheduleIds: []string{"s3"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := newTestSlots()
+			slots.slots["s3"] = &dao.ScheduleModel{Id: "s3", Weekday: time.Friday, Order: 1, IsOptional: true,
+				OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{}}}
+
+			id, err := newTestElectiveServiceWith(slots, slots, &fakeElectiveProvider{}).CreateElectivePool(tt.request)
+
+			if fmt.Sprint(err) != fmt.Sprint(tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && id != "p3" {
+				t.Errorf("id = %s, want p3", id)
+			}
+		})
+	}
+}
+
+func TestGetAllowedCourses(t *testing.T) {
+	electives := &fakeElectiveProvider{waitlists: map[string]map[string][]int{}}
+	slots := newTestSlots()
+	service := newTestElectiveServiceWith(slots, slots, electives)
+
+	// the slot s2 is left without a pool, so every optional course is allowed there
+	electives.pools = []dao.ElectivePoolModel{{Id: "p1", CourseIds: []string{"math", "art"}, ScheduleIds: []string{"s1"}}}
+
+	tests := []struct {
+		scheduleId string
+		want       []string
+	}{
+		{scheduleId: "s1", want: []string{"art", "math"}},
+		{scheduleId: "s2", want: []string{"art", "math"}},
+	}
+
+	for _, tt := range tests {
+		allowed, err := service.GetAllowedCourses(tt.scheduleId)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+
+		for _, course := range allowed.Courses {
+			got = append(got, course.Id)
+		}
+
+		if !equalStrings(got, tt.want) {
+			t.Errorf("%s: allowed = %v, want %v", tt.scheduleId, got, tt.want)
+		}
+	}
+
+	electives.pools[0].CourseIds = []string{"art"}
+
+	if _, err := service.LinkOptionalCourseToUser(dto.LinkOptionalCourseToUserRequest{
+		UserId: 10, ScheduleId: "s1", CourseId: "math", IgnoreDeadline: true}); err != exceptions.CourseNotAllowed {
+		t.Errorf("error = %v, want %v", err, exceptions.CourseNotAllowed)
+	}
+
+	// the chosen course is linked rather than the last allowed one
+	electives.pools[0].CourseIds = []string{"math", "art"}
+
+	if _, err := service.LinkOptionalCourseToUser(dto.LinkOptionalCourseToUserRequest{
+		UserId: 10, ScheduleId: "s1", CourseId: "art", IgnoreDeadline: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := slots.slots["s1"].OptCourseParams.UserIdToCourseId[10]; got != "art" {
+		t.Errorf("linked = %s, want art", got)
+	}
+}
+
+// newTestWaitlistService has the slots s1 and s3 sharing the pool of Math and Art with one seat
+// and Music with two seats, the waitlists are enabled
+func newTestWaitlistService(links map[string]map[int]string, waitlists map[string][]int) (ElectiveService, *fakeScheduleProvider, *fakeElectiveProvider) {
+	slots := &fakeScheduleProvider{slots: map[string]*dao.ScheduleModel{}}
+
+	for _, id := range []string{"s1", "s3"} {
+		slot := &dao.ScheduleModel{Id: id, Weekday: time.Monday, Order: len(slots.slots) + 1, IsOptional: true,
+			OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{}}}
+
+		for userId, courseId := range links[id] {
+			slot.OptCourseParams.UserIdToCourseId[userId] = courseId
+		}
+
+		slots.slots[id] = slot
+	}
+
+	electives := &fakeElectiveProvider{
+		pools:     []dao.ElectivePoolModel{{Id: "p1", CourseIds: []string{"math", "art", "music"}, ScheduleIds: []string{"s1", "s3"}}},
+		waitlists: map[string]map[string][]int{"s1": waitlists},
+	}
+
+	var config configuration.Configuration
+	config.ElectiveSettings.WaitlistEnabled = true
+
+	return *NewElectiveService(
+		config,
+		electives,
+		slots,
+		slots,
+		&fakeCourseProvider{courses: []dao.CourseModel{
+			{Id: "math", Name: "Math", IsOptional: true, Capacity: 1},
+			{Id: "art", Name: "Art", IsOptional: true, Capacity: 1},
+			{Id: "music", Name: "Music", IsOptional: true, Capacity: 2},
+		}},
+		&fakeUserProvider{},
+		&fakeSubgroupProvider{}), slots, electives
+}
+
+func TestLinkOptionalCourseToUserWaitlist(t *testing.T) {
+	tests := []struct {
+		name         string
+		links        map[string]map[int]string
+		waitlists    map[string][]int
+		userId       int
+		scheduleId   string
+		courseId     string
+		wantPosition int
+		wantPromoted []string
+		wantLinks    []string
+		wantWaiting  []string
+	}{
+		{
+			name:       "full course puts the student to the waitlist",
+			links:      map[string]map[int]string{"s1": {10: "math"}},
+			waitlists:  map[string][]int{"math": {12}},
+			userId:     11,
+			scheduleId: "s1", courseId: "math",
+			wantPosition: 2,
+			wantLinks:    []string{"s1/10/math"},
+			wantWaiting:  []string{"math/12", "math/11"},
+		},
+		{
+			name:       "switching away promotes the first waitlisted student",
+			links:      map[string]map[int]string{"s1": {10: "math"}},
+			waitlists:  map[string][]int{"math": {11, 12}},
+			userId:     10,
+			scheduleId: "s1", courseId: "music",
+			wantPromoted: []string{"11/math"},
+			wantLinks:    []string{"s1/10/music", "s1/11/math"},
+			wantWaiting:  []string{"math/12"},
+		},
+		{
+			name:       "promotion frees the previous seat of the promoted student",
+			links:      map[string]map[int]string{"s1": {10: "math", 11: "art"}},
+			waitlists:  map[string][]int{"math": {11}, "art": {12}},
+			userId:     10,
+			scheduleId: "s1", courseId: "music",
+			wantPromoted: []string{"11/math", "12/art"},
+			wantLinks:    []string{"s1/10/music", "s1/11/math", "s1/12/art"},
+		},
+		{
+			name:       "exactly one free seat promotes one student",
+			links:      map[string]map[int]string{"s1": {10: "music", 11: "music"}},
+			waitlists:  map[string][]int{"music": {12, 13}},
+			userId:     10,
+			scheduleId: "s1", courseId: "math",
+			wantPromoted: []string{"12/music"},
+			wantLinks:    []string{"s1/10/math", "s1/11/music", "s1/12/music"},
+			wantWaiting:  []string{"music/13"},
+		},
+		{
+			name:       "linked student leaves the other waitlists",
+			links:      map[string]map[int]string{"s1": {11: "math"}},
+			waitlists:  map[string][]int{"math": {10, 12}},
+			userId:     10,
+			scheduleId: "s1", courseId: "art",
+			wantLinks:   []string{"s1/10/art", "s1/11/math"},
+			wantWaiting: []string{"math/12"},
+		},
+		{
+			name:       "capacity is counted per slot",
+			links:      map[string]map[int]string{"s3": {10: "math"}},
+			userId:     11,
+			scheduleId: "s1", courseId: "math",
+			wantLinks: []string{"s1/11/math", "s3/10/math"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, slots, electives := newTestWaitlistService(tt.links, tt.waitlists)
+
+			response, err := service.LinkOptionalCourseToUser(dto.LinkOptionalCourseToUserRequest{
+				UserId:         tt.userId,
+				ScheduleId:     tt.scheduleId,
+				CourseId:       tt.courseId,
+				IgnoreDeadline: true,
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.IsWaitlisted != (tt.wantPosition > 0) || response.Position != tt.wantPosition {
+				t.Errorf("position = %d, want %d", response.Position, tt.wantPosition)
+			}
+
+			var promoted, links, waiting []string
+
+			for _, val := range response.Promoted {
+				promoted = append(promoted, fmt.Sprintf("%d/%s", val.UserId, val.CourseInfo.Id))
+			}
+
+			for id, slot := range slots.slots {
+				for userId, courseId := range slot.OptCourseParams.UserIdToCourseId {
+					links = append(links, fmt.Sprintf("%s/%d/%s", id, userId, courseId))
+				}
+			}
+
+			for courseId, userIds := range electives.waitlists["s1"] {
+				for _, userId := range userIds {
+					waiting = append(waiting, fmt.Sprintf("%s/%d", courseId, userId))
+				}
+			}
+
+			sort.Strings(links)
+
+			if !equalStrings(promoted, tt.wantPromoted) {
+				t.Errorf("promoted = %v, want %v", promoted, tt.wantPromoted)
+			}
+
+			if !equalStrings(links, tt.wantLinks) {
+				t.Errorf("links = %v, want %v", links, tt.wantLinks)
+			}
+
+			if !equalStrings(waiting, tt.wantWaiting) {
+				t.Errorf("waitlists = %v, want %v", waiting, tt.wantWaiting)
+			}
+		})
+	}
+}
+
+func TestLinkOptionalCourseToUserPerSlot(t *testing.T) {
+	slots := newTestSlots()
+	electives := &fakeElectiveProvider{waitlists: map[string]map[string][]int{}}
+	service := newTestElectiveServiceWith(slots, slots, electives)
+	electives.pools = []dao.ElectivePoolModel{{Id: "p1", CourseIds: []string{"math", "art"}, ScheduleIds: []string{"s1", "s2"}}}
+
+	links := []dto.LinkOptionalCourseToUserRequest{
+		{UserId: 10, ScheduleId: "s1", CourseId: "math", IgnoreDeadline: true},
+		{UserId: 10, ScheduleId: "s2", CourseId: "art", IgnoreDeadline: true},
+		{UserId: 11, ScheduleId: "s2", CourseId: "math", IgnoreDeadline: true},
+		// the pick is changed in one slot only
+		{UserId: 10, ScheduleId: "s1", CourseId: "art", IgnoreDeadline: true},
+	}
+
+	for _, request := range links {
+		if _, err := service.LinkOptionalCourseToUser(request); err != nil {
+			t.Fatalf("link %+v: unexpected error: %s", request, err)
+		}
+	}
+
+	want := map[string]map[int]string{
+		"s1": {10: "art"},
+		"s2": {10: "art", 11: "math"},
+	}
+
+	for scheduleId, userLinks := range want {
+		got := slots.slots[scheduleId].OptCourseParams.UserIdToCourseId
+
+		if fmt.Sprint(got) != fmt.Sprint(userLinks) {
+			t.Errorf("links of %s = %v, want %v", scheduleId, got, userLinks)
+		}
+	}
+}
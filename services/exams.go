@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"time"
+)
+
+type ExamService struct {
+	provider       abstractions.IExamProvider
+	courseProvider abstractions.ICourseProvider
+}
+
+func NewExamService(provider abstractions.IExamProvider, courseProvider abstractions.ICourseProvider) *ExamService {
+	return &ExamService{provider: provider, courseProvider: courseProvider}
+}
+
+func (e ExamService) CreateNewExam(request dto.CreateNewExamRequest) (string, error) {
+	if err := e.validateExam(request.CourseId, request.StartTime); err != nil {
+		return "", err
+	}
+
+	return e.provider.CreateNewExam(dao.ExamModel{
+		CourseId:  request.CourseId,
+		Kind:      request.Kind,
+		StartTime: request.StartTime,
+		Room:      request.Room,
+	})
+}
+
+func (e ExamService) UpdateExam(request dto.UpdateExamRequest) error {
+	if err := e.validateExam(request.CourseId, request.StartTime); err != nil {
+		return err
+	}
+
+	return e.provider.UpdateExam(dao.ExamModel{
+		Id:        request.Id,
+		CourseId:  request.CourseId,
+		Kind:      request.Kind,
+		StartTime: request.StartTime,
+		Room:      request.Room,
+	})
+}
+
+func (e ExamService) DeleteExam(request dto.DeleteExamRequest) error {
+	return e.provider.DeleteExam(request.ExamId)
+}
+
+func (e ExamService) GetExamById(id string) (*dto.ExamDto, error) {
+	exam, err := e.provider.GetExamById(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := e.convertToDto(*exam)
+
+	return &result, nil
+}
+
+// GetUpcomingExams returns exams which are not started yet, the nearest first
+func (e ExamService) GetUpcomingExams() (*dto.GetExamsResponse, error) {
+	exams, err := e.provider.GetExams()
+
+	if err != nil {
+		return nil, err
+	}
+
+	actualTime := time.Now()
+
+	var examsDto []dto.ExamDto
+
+	for _, exam := range exams {
+		if actualTime.After(exam.StartTime) {
+			continue
+		}
+
+		examsDto = append(examsDto, e.convertToDto(exam))
+	}
+
+	sort.Slice(examsDto, func(i, j int) bool {
+		return examsDto[i].StartTime.Before(examsDto[j].StartTime)
+	})
+
+	return &dto.GetExamsResponse{Exams: examsDto}, nil
+}
+
+func (e ExamService) validateExam(courseId string, startTime time.Time) error {
+	if _, err := e.courseProvider.GetCourseById(courseId); err != nil {
+		return errors.New("InvalidCourse")
+	}
+
+	if time.Now().After(startTime) {
+		return errors.New("InvalidStartTime")
+	}
+
+	return nil
+}
+
+func (e ExamService) convertToDto(exam dao.ExamModel) dto.ExamDto {
+	result := dto.ExamDto{
+		Id:        exam.Id,
+		Kind:      exam.Kind,
+		StartTime: exam.StartTime,
+		Room:      exam.Room,
+	}
+
+	course, err := e.courseProvider.GetCourseById(exam.CourseId)
+
+	if err == nil {
+		result.CourseInfo = dto.CourseDto{
+			Name:           course.Name,
+			Id:             course.Id,
+			TeacherName:    course.TeacherName,
+			TeacherContact: course.TeacherContact,
+			MeetLink:       course.MeetLink,
+			IsOptional:     course.IsOptional,
+		}
+	}
+
+	return result
+}
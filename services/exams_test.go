@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+type fakeExamProvider struct {
+	abstractions.IExamProvider
+	exams []dao.ExamModel
+}
+
+func (f *fakeExamProvider) CreateNewExam(model dao.ExamModel) (string, error) {
+	model.Id = fmt.Sprintf("e%d", len(f.exams)+1)
+	f.exams = append(f.exams, model)
+	return model.Id, nil
+}
+
+func (f *fakeExamProvider) GetExams() ([]dao.ExamModel, error) {
+	return f.exams, nil
+}
+
+type fakeChatProvider struct {
+	abstractions.IChatProvider
+}
+
+// GetChatByUserId uses the user id as the id of the private chat, like telegram does
+func (f *fakeChatProvider) GetChatByUserId(userId int) (int64, error) {
+	return int64(userId), nil
+}
+
+func TestCreateNewExam(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1)
+
+	tests := []struct {
+		name    string
+		request dto.CreateNewExamRequest
+		wantErr string
+	}{
+		{name: "exam", request: dto.CreateNewExamRequest{CourseId: "math", Kind: util.ExamKindExam, StartTime: tomorrow, Room: "101"}},
+		{name: "unknown course", request: dto.CreateNewExamRequest{CourseId: "physics", StartTime: tomorrow}, wantErr: "InvalidCourse"},
+		{name: "past exam", request: dto.CreateNewExamRequest{CourseId: "math", StartTime: time.Now().Add(-time.Hour)}, wantErr: "InvalidStartTime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeExamProvider{}
+			service := NewExamService(provider, &fakeCourseProvider{courses: []dao.CourseModel{{Id: "math", Name: "Math"}}})
+
+			_, err := service.CreateNewExam(tt.request)
+
+			if (err == nil) != (tt.wantErr == "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			if created := len(provider.exams) == 1; created != (tt.wantErr == "") {
+				t.Errorf("created = %v", created)
+			}
+		})
+	}
+}
+
+func TestGetUpcomingExams(t *testing.T) {
+	now := time.Now()
+	service := NewExamService(&fakeExamProvider{exams: []dao.ExamModel{
+		{Id: "late", CourseId: "math", StartTime: now.AddDate(0, 0, 5)},
+		{Id: "past", CourseId: "math", StartTime: now.Add(-time.Hour)},
+		{Id: "soon", CourseId: "physics", StartTime: now.AddDate(0, 0, 1)},
+	}}, &fakeCourseProvider{courses: []dao.CourseModel{{Id: "math", Name: "Math"}}})
+
+	response, err := service.GetUpcomingExams()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	for _, exam := range response.Exams {
+		got = append(got, exam.Id+"/"+exam.CourseInfo.Name)
+	}
+
+	// the exam of a deleted course is shown without the course
+	if want := []string{"soon/", "late/Math"}; !equalStrings(got, want) {
+		t.Errorf("exams = %v, want %v", got, want)
+	}
+}
+
+func TestDoExamCycle(t *testing.T) {
+	now := time.Now()
+	exams := &fakeExamProvider{exams: []dao.ExamModel{
+		{Id: "week", CourseId: "math", StartTime: now.AddDate(0, 0, 3)},
+		{Id: "day", CourseId: "math", StartTime: now.Add(12 * time.Hour)},
+		{Id: "later", CourseId: "math", StartTime: now.AddDate(0, 0, 10)},
+	}}
+
+	// the reminder of a week before was sent for the exam of tomorrow
+	dayId := fmt.Sprintf("exam/g1/day/%d", exams.exams[1].StartTime.Unix())
+	outbox := newFakeOutbox(dao.OutboxEntryModel{Id: dayId, Offset: 7 * 24 * 60})
+
+	service := BackgroundService{chatProvider: &fakeChatProvider{}, outboxProvider: outbox}
+	service.cfg.ScheduleSettings.ExamReminderIntervals = []int{1, 7}
+
+	scope := abstractions.GroupScope{
+		GroupId:   "g1",
+		MemberIds: []int{10, 11},
+		Exams:     NewExamService(exams, &fakeCourseProvider{courses: []dao.CourseModel{{Id: "math", Name: "Math"}}}),
+	}
+
+	var sent []string
+	handle := func(exam dto.ExamDto, chatId int64) {
+		sent = append(sent, fmt.Sprintf("%s/%d", exam.Id, chatId))
+	}
+
+	service.doExamCycle(scope, handle)
+
+	if want := []string{"day/10", "day/11", "week/10", "week/11"}; !equalStrings(sent, want) {
+		t.Errorf("sent = %v, want %v", sent, want)
+	}
+
+	if entry, err := outbox.GetEntry(dayId); err != nil || entry.Offset != 24*60 {
+		t.Errorf("expected the reminder of a day before to be stored, got %v", entry)
+	}
+
+	// the reminders are not repeated, e.g. after a restart
+	sent = nil
+	service.doExamCycle(scope, handle)
+
+	if len(sent) != 0 {
+		t.Errorf("sent again = %v", sent)
+	}
+
+	if _, err := outbox.GetEntry(fmt.Sprintf("exam/g1/later/%d", exams.exams[2].StartTime.Unix())); err != exceptions.NotFound {
+		t.Errorf("expected no reminder for the exam in 10 days, got %v", err)
+	}
+}
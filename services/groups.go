@@ -0,0 +1,420 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// GroupScopeFactory creates the services of the group, it is called once per group
+type GroupScopeFactory func(groupId string) abstractions.GroupScope
+
+// GroupService keeps the student groups and routes users to the services of their active group
+type GroupService struct {
+	config         configuration.Configuration
+	provider       abstractions.IGroupProvider
+	targetProvider abstractions.IPublicationTargetProvider
+	factory        GroupScopeFactory
+	scopes         map[string]abstractions.GroupScope
+	mutex          *sync.Mutex
+}
+
+func NewGroupService(
+	config configuration.Configuration,
+	provider abstractions.IGroupProvider,
+	targetProvider abstractions.IPublicationTargetProvider,
+	factory GroupScopeFactory) *GroupService {
+	return &GroupService{
+		config:         config,
+		provider:       provider,
+		targetProvider: targetProvider,
+		factory:        factory,
+		scopes:         map[string]abstractions.GroupScope{},
+		mutex:          &sync.Mutex{},
+	}
+}
+
+func (g GroupService) CreateGroup(request dto.CreateGroupRequest) (string, error) {
+	if strings.TrimSpace(request.Name) == "" {
+		return "", errors.New("InvalidName")
+	}
+
+	id, err := g.provider.CreateNewGroup(dao.GroupModel{
+		Name:      request.Name,
+		MemberIds: []int{request.OwnerId},
+		AdminIds:  []int{request.OwnerId},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return id, g.provider.SaveActiveGroupId(request.OwnerId, id)
+}
+
+func (g GroupService) AddGroupMember(request dto.GroupMemberRequest) error {
+	group, err := g.getGroup(request.GroupId)
+
+	if err != nil {
+		return err
+	}
+
+	group.MemberIds = appendUnique(group.MemberIds, request.UserId)
+	group.AdminIds = removeId(group.AdminIds, request.UserId)
+
+	if request.IsAdmin {
+		group.AdminIds = append(group.AdminIds, request.UserId)
+	}
+
+	return g.provider.SaveGroup(*group)
+}
+
+// RemoveGroupMember removes the stored membership, members of the default group
+// listed in the configuration stay in it
+func (g GroupService) RemoveGroupMember(request dto.GroupMemberRequest) error {
+	group, err := g.getGroup(request.GroupId)
+
+	if err != nil {
+		return err
+	}
+
+	if !containsId(group.MemberIds, request.UserId) {
+		return exceptions.NotFound
+	}
+
+	group.MemberIds = removeId(group.MemberIds, request.UserId)
+	group.AdminIds = removeId(group.AdminIds, request.UserId)
+
+	return g.provider.SaveGroup(*group)
+}
+
+func (g GroupService) GetUserGroups(userId int) (*dto.GetGroupsResponse, error) {
+	groups, err := g.getGroups()
+
+	if err != nil {
+		return nil, err
+	}
+
+	active := g.getActiveGroupId(userId, groups)
+
+	var groupsDto []dto.GroupDto
+
+	for _, group := range groups {
+		if !containsId(group.MemberIds, userId) {
+			continue
+		}
+
+		groupsDto = append(groupsDto, dto.GroupDto{
+			Id:           group.Id,
+			Name:         group.Name,
+			MembersCount: len(group.MemberIds),
+			IsActive:     group.Id == active,
+			IsAdmin:      containsId(group.AdminIds, userId),
+		})
+	}
+
+	return &dto.GetGroupsResponse{Groups: groupsDto}, nil
+}
+
+func (g GroupService) SelectGroup(request dto.SelectGroupRequest) error {
+	group, err := g.getGroup(request.GroupId)
+
+	if err != nil {
+		return err
+	}
+
+	if !containsId(group.MemberIds, request.UserId) {
+		return exceptions.NotFound
+	}
+
+	return g.provider.SaveActiveGroupId(request.UserId, request.GroupId)
+}
+
+// GetUserScope returns the services of the active group of the user,
+// users without groups get the default group
+func (g GroupService) GetUserScope(userId int) abstractions.GroupScope {
+	groups, err := g.getGroups()
+
+	if err != nil {
+		return g.getScope(g.getDefaultGroup())
+	}
+
+	active := g.getActiveGroupId(userId, groups)
+
+	for _, group := range groups {
+		if group.Id == active {
+			return g.getScope(group)
+		}
+	}
+
+	return g.getScope(g.getDefaultGroup())
+}
+
+func (g GroupService) GetScope(groupId string) (abstractions.GroupScope, error) {
+	groups, err := g.getGroups()
+
+	if err != nil {
+		return abstractions.GroupScope{}, err
+	}
+
+	for _, group := range groups {
+		if group.Id == groupId {
+			return g.getScope(group), nil
+		}
+	}
+
+	return abstractions.GroupScope{}, exceptions.NotFound
+}
+
+func (g GroupService) GetScopes() []abstractions.GroupScope {
+	groups, err := g.getGroups()
+
+	if err != nil {
+		return []abstractions.GroupScope{g.getScope(g.getDefaultGroup())}
+	}
+
+	var scopes []abstractions.GroupScope
+
+	for _, group := range groups {
+		scopes = append(scopes, g.getScope(group))
+	}
+
+	return scopes
+}
+
+// AddPublicationTarget makes the bot post for the group to the chat or channel, everything except
+// lesson reminders in channels is enabled for a new target, settings are kept when the target is added again
+func (g GroupService) AddPublicationTarget(request dto.AddPublicationTargetRequest) error {
+	if _, err := g.getGroup(request.GroupId); err != nil {
+		return err
+	}
+
+	model := dao.PublicationTargetModel{
+		ChatId:        request.ChatId,
+		Kind:          request.Kind,
+		Title:         request.Title,
+		GroupId:       request.GroupId,
+		Reminders:     request.Kind != util.TargetKindChannel,
+		DailySchedule: true,
+		Replacements:  true,
+		Announcements: true,
+	}
+
+	// a chat bound to another group has to be unbound by the admin of that group first
+	if target, err := g.targetProvider.GetTarget(request.ChatId); err == nil {
+		if target.GroupId != request.GroupId {
+			return errors.New("TargetOfAnotherGroup")
+		}
+
+		model = *target
+		model.Title = request.Title
+	}
+
+	return g.targetProvider.SaveTarget(model)
+}
+
+func (g GroupService) RemovePublicationTarget(chatId int64) error {
+	return g.targetProvider.DeleteTarget(chatId)
+}
+
+func (g GroupService) GetPublicationTarget(chatId int64) (*dto.PublicationTargetDto, error) {
+	chat, err := g.targetProvider.GetTarget(chatId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chatDto := convertToPublicationTargetDto(*chat)
+
+	return &chatDto, nil
+}
+
+func (g GroupService) GetPublicationTargets(groupId string) ([]dto.PublicationTargetDto, error) {
+	chats, err := g.targetProvider.GetTargetsByGroupId(groupId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var chatsDto []dto.PublicationTargetDto
+
+	for _, chat := range chats {
+		chatsDto = append(chatsDto, convertToPublicationTargetDto(chat))
+	}
+
+	return chatsDto, nil
+}
+
+func (g GroupService) UpdatePublicationTarget(request dto.UpdatePublicationTargetRequest) error {
+	chat, err := g.targetProvider.GetTarget(request.ChatId)
+
+	if err != nil {
+		return err
+	}
+
+	chat.Reminders = request.Reminders
+	chat.DailySchedule = request.DailySchedule
+	chat.Replacements = request.Replacements
+	chat.Announcements = request.Announcements
+
+	return g.targetProvider.SaveTarget(*chat)
+}
+
+func (g GroupService) MarkDailySchedulePosted(chatId int64, postedAt time.Time) error {
+	chat, err := g.targetProvider.GetTarget(chatId)
+
+	if err != nil {
+		return err
+	}
+
+	chat.LastDailyScheduleAt = postedAt
+
+	return g.targetProvider.SaveTarget(*chat)
+}
+
+func (g GroupService) getScope(group dao.GroupModel) abstractions.GroupScope {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	scope, exists := g.scopes[group.Id]
+
+	if !exists {
+		scope = g.factory(group.Id)
+		g.scopes[group.Id] = scope
+	}
+
+	scope.GroupId = group.Id
+	scope.Name = group.Name
+	scope.MemberIds = group.MemberIds
+	scope.AdminIds = group.AdminIds
+
+	return scope
+}
+
+// getActiveGroupId returns the chosen group when the user is still its member, otherwise the first group of the user
+func (g GroupService) getActiveGroupId(userId int, groups []dao.GroupModel) string {
+	active, err := g.provider.GetActiveGroupId(userId)
+
+	for _, group := range groups {
+		if err == nil && group.Id == active && containsId(group.MemberIds, userId) {
+			return active
+		}
+	}
+
+	for _, group := range groups {
+		if containsId(group.MemberIds, userId) {
+			return group.Id
+		}
+	}
+
+	return dao.DefaultGroupId
+}
+
+// getGroups returns the default group first and the other groups sorted by name
+func (g GroupService) getGroups() ([]dao.GroupModel, error) {
+	groups, err := g.provider.GetGroups()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []dao.GroupModel
+
+	for _, group := range groups {
+		if group.Id != dao.DefaultGroupId {
+			result = append(result, group)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return append([]dao.GroupModel{g.getDefaultGroup()}, result...), nil
+}
+
+func (g GroupService) getGroup(groupId string) (*dao.GroupModel, error) {
+	if groupId == dao.DefaultGroupId {
+		group := g.getStoredDefaultGroup()
+		return &group, nil
+	}
+
+	return g.provider.GetGroupById(groupId)
+}
+
+// getDefaultGroup merges the stored default group with the accounts from the configuration
+func (g GroupService) getDefaultGroup() dao.GroupModel {
+	group := g.getStoredDefaultGroup()
+
+	for _, userId := range g.config.Security.AllowedAccountIds {
+		group.MemberIds = appendUnique(group.MemberIds, userId)
+	}
+
+	for _, userId := range g.config.Security.TrustedAccountIds {
+		group.AdminIds = appendUnique(group.AdminIds, userId)
+	}
+
+	return group
+}
+
+func (g GroupService) getStoredDefaultGroup() dao.GroupModel {
+	group, err := g.provider.GetGroupById(dao.DefaultGroupId)
+
+	if err != nil {
+		return dao.GroupModel{Id: dao.DefaultGroupId, Name: "Основна група"}
+	}
+
+	return *group
+}
+
+func convertToPublicationTargetDto(target dao.PublicationTargetModel) dto.PublicationTargetDto {
+	return dto.PublicationTargetDto{
+		ChatId:              target.ChatId,
+		Kind:                target.Kind,
+		Title:               target.Title,
+		GroupId:             target.GroupId,
+		Reminders:           target.Reminders,
+		DailySchedule:       target.DailySchedule,
+		Replacements:        target.Replacements,
+		Announcements:       target.Announcements,
+		LastDailyScheduleAt: target.LastDailyScheduleAt,
+	}
+}
+
+func containsId(ids []int, id int) bool {
+	for _, val := range ids {
+		if val == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendUnique(ids []int, id int) []int {
+	if containsId(ids, id) {
+		return ids
+	}
+
+	return append(ids, id)
+}
+
+func removeId(ids []int, id int) []int {
+	var result []int
+
+	for _, val := range ids {
+		if val != id {
+			result = append(result, val)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"fmt"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+type fakeGroupProvider struct {
+	abstractions.IGroupProvider
+	groups []dao.GroupModel
+	active map[int]string
+}
+
+func (f *fakeGroupProvider) GetGroupById(id string) (*dao.GroupModel, error) {
+	for _, group := range f.groups {
+		if group.Id == id {
+			return &group, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+type fakeTargetProvider struct {
+	abstractions.IPublicationTargetProvider
+	targets map[int64]dao.PublicationTargetModel
+}
+
+func (f *fakeTargetProvider) SaveTarget(model dao.PublicationTargetModel) error {
+	f.targets[model.ChatId] = model
+	return nil
+}
+
+func (f *fakeTargetProvider) DeleteTarget(chatId int64) error {
+	if _, ok := f.targets[chatId]; !ok {
+		return exceptions.NotFound
+	}
+
+	delete(f.targets, chatId)
+	return nil
+}
+
+func (f *fakeTargetProvider) GetTarget(chatId int64) (*dao.PublicationTargetModel, error) {
+	target, ok := f.targets[chatId]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &target, nil
+}
+
+func (f *fakeTargetProvider) GetTargetsByGroupId(groupId string) ([]dao.PublicationTargetModel, error) {
+	var result []dao.PublicationTargetModel
+
+	for _, target := range f.targets {
+		if target.GroupId == groupId {
+			result = append(result, target)
+		}
+	}
+
+	return result, nil
+}
+
+func TestAddPublicationTarget(t *testing.T) {
+	bound := dao.PublicationTargetModel{ChatId: -100, Kind: util.TargetKindGroupChat, Title: "Old title", GroupId: "g1", DailySchedule: true}
+
+	tests := []struct {
+		name    string
+		request dto.AddPublicationTargetRequest
+		wantErr string
+		want    dao.PublicationTargetModel
+	}{
+		{
+			name:    "new chat",
+			request: dto.AddPublicationTargetRequest{ChatId: -200, Kind: util.TargetKindGroupChat, Title: "Chat", GroupId: "g1"},
+			want: dao.PublicationTargetModel{ChatId: -200, Kind: util.TargetKindGroupChat, Title: "Chat", GroupId: "g1",
+				Reminders: true, DailySchedule: true, Replacements: true, Announcements: true},
+		},
+		{
+			name:    "new channel gets no reminders",
+			request: dto.AddPublicationTargetRequest{ChatId: -300, Kind: util.TargetKindChannel, Title: "Channel", GroupId: "g1"},
+			want: dao.PublicationTargetModel{ChatId: -300, Kind: util.TargetKindChannel, Title: "Channel", GroupId: "g1",
+				DailySchedule: true, Replacements: true, Announcements: true},
+		},
+		{
+			name:    "binding again keeps the settings",
+			request: dto.AddPublicationTargetRequest{ChatId: -100, Kind: util.TargetKindGroupChat, Title: "New title", GroupId: "g1"},
+			want:    dao.PublicationTargetModel{ChatId: -100, Kind: util.TargetKindGroupChat, Title: "New title", GroupId: "g1", DailySchedule: true},
+		},
+		{
+			name:    "chat of another group",
+			request: dto.AddPublicationTargetRequest{ChatId: -100, Kind: util.TargetKindGroupChat, Title: "Chat", GroupId: "g2"},
+			wantErr: "TargetOfAnotherGroup",
+			want:    bound,
+		},
+		{
+			name:    "unknown group",
+			request: dto.AddPublicationTargetRequest{ChatId: -200, Kind: util.TargetKindGroupChat, Title: "Chat", GroupId: "g3"},
+			wantErr: exceptions.NotFound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := &fakeTargetProvider{targets: map[int64]dao.PublicationTargetModel{bound.ChatId: bound}}
+			service := NewGroupService(configuration.Configuration{},
+				&fakeGroupProvider{groups: []dao.GroupModel{{Id: "g1"}, {Id: "g2"}}}, targets, nil)
+
+			err := service.AddPublicationTarget(tt.request)
+
+			if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			if got := targets.targets[tt.request.ChatId]; got != tt.want {
+				t.Errorf("target = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovePublicationTarget(t *testing.T) {
+	targets := &fakeTargetProvider{targets: map[int64]dao.PublicationTargetModel{-100: {ChatId: -100, GroupId: "g1"}}}
+	service := NewGroupService(configuration.Configuration{}, &fakeGroupProvider{}, targets, nil)
+
+	if err := service.RemovePublicationTarget(-100); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.GetPublicationTarget(-100); err != exceptions.NotFound {
+		t.Errorf("expected the chat to be unbound, got %v", err)
+	}
+
+	if err := service.RemovePublicationTarget(-100); err != exceptions.NotFound {
+		t.Errorf("expected an unbound chat to be reported, got %v", err)
+	}
+}
+
+func TestUpdatePublicationTarget(t *testing.T) {
+	channel := dao.PublicationTargetModel{ChatId: -300, Kind: util.TargetKindChannel, Title: "Channel", GroupId: "g1",
+		DailySchedule: true, Replacements: true, Announcements: true}
+	targets := &fakeTargetProvider{targets: map[int64]dao.PublicationTargetModel{
+		channel.ChatId: channel,
+		-100:           {ChatId: -100, Kind: util.TargetKindGroupChat, GroupId: "g2"},
+	}}
+	service := NewGroupService(configuration.Configuration{}, &fakeGroupProvider{}, targets, nil)
+
+	err := service.UpdatePublicationTarget(dto.UpdatePublicationTargetRequest{ChatId: -300, Reminders: true, Announcements: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channels, err := service.GetPublicationTargets("g1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := dto.PublicationTargetDto{ChatId: -300, Kind: util.TargetKindChannel, Title: "Channel", GroupId: "g1",
+		Reminders: true, Announcements: true}
+
+	if len(channels) != 1 || channels[0] != want {
+		t.Errorf("targets = %+v, want %+v", channels, want)
+	}
+
+	if err = service.UpdatePublicationTarget(dto.UpdatePublicationTargetRequest{ChatId: -400}); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+}
+
+func TestMarkDailySchedulePosted(t *testing.T) {
+	targets := &fakeTargetProvider{targets: map[int64]dao.PublicationTargetModel{-300: {ChatId: -300, Kind: util.TargetKindChannel}}}
+	service := NewGroupService(configuration.Configuration{}, &fakeGroupProvider{}, targets, nil)
+	postedAt := time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local)
+
+	if err := service.MarkDailySchedulePosted(-300, postedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, _ := service.GetPublicationTarget(-300); !target.LastDailyScheduleAt.Equal(postedAt) {
+		t.Errorf("posted at = %s, want %s", target.LastDailyScheduleAt, postedAt)
+	}
+
+	if err := service.MarkDailySchedulePosted(-400, postedAt); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+}
+
+func (f *fakeGroupProvider) CreateNewGroup(model dao.GroupModel) (string, error) {
+	model.Id = fmt.Sprintf("g%d", len(f.groups)+1)
+	f.groups = append(f.groups, model)
+	return model.Id, nil
+}
+
+func (f *fakeGroupProvider) SaveGroup(model dao.GroupModel) error {
+	for i, group := range f.groups {
+		if group.Id == model.Id {
+			f.groups[i] = model
+			return nil
+		}
+	}
+
+	f.groups = append(f.groups, model)
+	return nil
+}
+
+func (f *fakeGroupProvider) GetGroups() ([]dao.GroupModel, error) {
+	return f.groups, nil
+}
+
+func (f *fakeGroupProvider) GetActiveGroupId(userId int) (string, error) {
+	groupId, ok := f.active[userId]
+
+	if !ok {
+		return "", exceptions.NotFound
+	}
+
+	return groupId, nil
+}
+
+func (f *fakeGroupProvider) SaveActiveGroupId(userId int, groupId string) error {
+	f.active[userId] = groupId
+	return nil
+}
+
+// newTestGroupService has the accounts 1 and 2 in the default group from the configuration, 1 is its admin,
+// the group "Beta" has the members 2 and 3 and the group "Alpha" has the member 3
+func newTestGroupService() (*GroupService, *fakeGroupProvider, map[string]int) {
+	var config configuration.Configuration
+	config.Security.AllowedAccountIds = []int{1, 2}
+	config.Security.TrustedAccountIds = []int{1}
+
+	groups := &fakeGroupProvider{
+		groups: []dao.GroupModel{
+			{Id: "g1", Name: "Beta", MemberIds: []int{2, 3}, AdminIds: []int{3}},
+			{Id: "g2", Name: "Alpha", MemberIds: []int{3}},
+		},
+		active: map[int]string{},
+	}
+
+	created := map[string]int{}
+
+	return NewGroupService(config, groups, &fakeTargetProvider{}, func(groupId string) abstractions.GroupScope {
+		created[groupId]++
+		return abstractions.GroupScope{}
+	}), groups, created
+}
+
+func TestCreateGroup(t *testing.T) {
+	service, groups, _ := newTestGroupService()
+
+	if _, err := service.CreateGroup(dto.CreateGroupRequest{Name: " ", OwnerId: 4}); err == nil || err.Error() != "InvalidName" {
+		t.Errorf("error = %v, want InvalidName", err)
+	}
+
+	id, err := service.CreateGroup(dto.CreateGroupRequest{Name: "Gamma", OwnerId: 4})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group, _ := groups.GetGroupById(id)
+
+	if !containsId(group.MemberIds, 4) || !containsId(group.AdminIds, 4) {
+		t.Errorf("group = %+v, want the owner as the admin", group)
+	}
+
+	// the owner switches to the new group
+	if scope := service.GetUserScope(4); scope.GroupId != id {
+		t.Errorf("active group = %s, want %s", scope.GroupId, id)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	service, groups, _ := newTestGroupService()
+
+	if err := service.AddGroupMember(dto.GroupMemberRequest{GroupId: "g2", UserId: 4, IsAdmin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// adding again changes the role only
+	if err := service.AddGroupMember(dto.GroupMemberRequest{GroupId: "g2", UserId: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	group, _ := groups.GetGroupById("g2")
+
+	if fmt.Sprint(group.MemberIds) != "[3 4]" || containsId(group.AdminIds, 4) {
+		t.Errorf("group = %+v, want the member 4 without the admin rights", group)
+	}
+
+	if err := service.RemoveGroupMember(dto.GroupMemberRequest{GroupId: "g2", UserId: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.RemoveGroupMember(dto.GroupMemberRequest{GroupId: "g2", UserId: 4}); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+
+	if err := service.AddGroupMember(dto.GroupMemberRequest{GroupId: "g3", UserId: 4}); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+
+	// the members listed in the configuration stay in the default group
+	if err := service.RemoveGroupMember(dto.GroupMemberRequest{GroupId: dao.DefaultGroupId, UserId: 1}); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+
+	if scope := service.GetUserScope(1); scope.GroupId != dao.DefaultGroupId || !containsId(scope.MemberIds, 1) {
+		t.Errorf("scope = %+v, want the default group with the member 1", scope)
+	}
+}
+
+func TestGetUserGroups(t *testing.T) {
+	service, _, _ := newTestGroupService()
+
+	tests := []struct {
+		userId int
+		want   []string
+	}{
+		{userId: 1, want: []string{"default active admin"}},
+		{userId: 2, want: []string{"default active", "g1"}},
+		{userId: 3, want: []string{"g2 active", "g1 admin"}},
+		{userId: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.userId), func(t *testing.T) {
+			response, err := service.GetUserGroups(tt.userId)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, group := range response.Groups {
+				line := group.Id
+
+				if group.IsActive {
+					line += " active"
+				}
+
+				if group.IsAdmin {
+					line += " admin"
+				}
+
+				got = append(got, line)
+			}
+
+			if !equalStrings(got, tt.want) {
+				t.Errorf("groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectGroup(t *testing.T) {
+	service, groups, created := newTestGroupService()
+
+	if err := service.SelectGroup(dto.SelectGroupRequest{UserId: 2, GroupId: "g2"}); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+
+	if err := service.SelectGroup(dto.SelectGroupRequest{UserId: 2, GroupId: "g1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	scope := service.GetUserScope(2)
+
+	if scope.GroupId != "g1" || scope.Name != "Beta" || fmt.Sprint(scope.MemberIds) != "[2 3]" {
+		t.Errorf("scope = %+v, want the group Beta", scope)
+	}
+
+	// the user removed from the chosen group falls back to the first remaining group
+	groups.groups[0].MemberIds = []int{3}
+
+	if scope = service.GetUserScope(2); scope.GroupId != dao.DefaultGroupId {
+		t.Errorf("active group = %s, want %s", scope.GroupId, dao.DefaultGroupId)
+	}
+
+	// the services of a group are created once
+	service.GetUserScope(3)
+	service.GetUserScope(3)
+
+	if fmt.Sprint(created) != "map[default:1 g1:1 g2:1]" {
+		t.Errorf("created scopes = %v, want one per group", created)
+	}
+}
+
+func TestGetScopes(t *testing.T) {
+	service, _, _ := newTestGroupService()
+
+	var ids []string
+
+	for _, scope := range service.GetScopes() {
+		ids = append(ids, scope.GroupId)
+	}
+
+	// the default group goes first and the others are sorted by name
+	if want := []string{dao.DefaultGroupId, "g2", "g1"}; !equalStrings(ids, want) {
+		t.Errorf("scopes = %v, want %v", ids, want)
+	}
+
+	if _, err := service.GetScope("g3"); err != exceptions.NotFound {
+		t.Errorf("error = %v, want %v", err, exceptions.NotFound)
+	}
+}
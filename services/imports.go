@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+)
+
+type importedLink struct {
+	line       int
+	student    dto.StudentDto
+	slot       dao.ScheduleModel
+	courseInfo dto.CourseDto
+}
+
+// ImportElectivesCsv links students to optional courses from the CSV document with the lines
+// "user id or username, course name" for the given students, the course is linked for every optional slot where it is allowed.
+// Every line is validated first, the valid lines are applied at once ignoring the deadline and the capacity,
+// nothing is applied when the schedule cannot be written
+func (e ElectiveService) ImportElectivesCsv(data []byte, studentIds []int) (*dto.ImportElectivesResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	slots := e.getOptionalSlots()
+	response := dto.ImportElectivesResponse{}
+	linked := map[string]int{}
+
+	var links []importedLink
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			response.Errors = append(response.Errors, dto.ImportErrorDto{Line: line, Reason: "InvalidCsv"})
+			continue
+		}
+
+		if line == 1 && e.isCsvHeader(record, studentIds) {
+			continue
+		}
+
+		if len(record) < 2 {
+			response.Errors = append(response.Errors, dto.ImportErrorDto{Line: line, Reason: "InvalidRow"})
+			continue
+		}
+
+		student, ok := e.findStudent(record[0], studentIds)
+
+		if !ok {
+			response.Errors = append(response.Errors, dto.ImportErrorDto{Line: line, Reason: "UnknownUser"})
+			continue
+		}
+
+		course, ok := e.findOptionalCourse(record[1])
+
+		if !ok {
+			response.Errors = append(response.Errors, dto.ImportErrorDto{Line: line, Reason: "UnknownCourse"})
+			continue
+		}
+
+		var lineLinks []importedLink
+		reason := "NoSlotForCourse"
+
+		for _, slot := range slots {
+			allowed, err := e.GetAllowedCourses(slot.Id)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !containsCourse(allowed.Courses, course.Id) {
+				continue
+			}
+
+			if !e.isVisibleForStudent(slot, student.UserId) {
+				reason = exceptions.SubgroupMismatch.Error()
+				continue
+			}
+
+			if previous, exists := linked[slot.Id+"/"+strconv.Itoa(student.UserId)]; exists {
+				reason = "ConflictWithLine" + strconv.Itoa(previous)
+				lineLinks = nil
+				break
+			}
+
+			lineLinks = append(lineLinks, importedLink{line: line, student: student, slot: slot, courseInfo: course})
+		}
+
+		if len(lineLinks) == 0 {
+			response.Errors = append(response.Errors, dto.ImportErrorDto{Line: line, Reason: reason})
+			continue
+		}
+
+		for _, link := range lineLinks {
+			linked[link.slot.Id+"/"+strconv.Itoa(student.UserId)] = line
+		}
+
+		links = append(links, lineLinks...)
+	}
+
+	stage := e.newStage()
+
+	for _, link := range links {
+		// promotions of the previous lines may have changed the slot
+		slot, err := stage.slot(link.slot.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		previous, exists := slot.OptCourseParams.UserIdToCourseId[link.student.UserId]
+
+		if err = stage.link(link.student.UserId, link.slot.Id, link.courseInfo.Id); err != nil {
+			return nil, err
+		}
+
+		response.Applied = append(response.Applied, dto.ImportedLinkDto{
+			Line:       link.line,
+			Student:    link.student,
+			Slot:       convertToOptionalSlotDto(link.slot),
+			CourseInfo: link.courseInfo,
+		})
+
+		if !exists || previous == link.courseInfo.Id {
+			continue
+		}
+
+		promoted, err := stage.promote(link.slot.Id, previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		response.Promoted = append(response.Promoted, promoted...)
+	}
+
+	if err := stage.save(); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// isCsvHeader tells whether the first record is a header like "username,course" or "Студент,Курс",
+// it is a header when neither the student nor the course is recognized
+func (e ElectiveService) isCsvHeader(record []string, studentIds []int) bool {
+	if len(record) < 2 {
+		return false
+	}
+
+	if _, ok := e.findStudent(record[0], studentIds); ok {
+		return false
+	}
+
+	_, ok := e.findOptionalCourse(record[1])
+	return !ok
+}
+
+// findStudent resolves the numeric telegram user id or the username of one of the students
+func (e ElectiveService) findStudent(value string, studentIds []int) (dto.StudentDto, bool) {
+	// spreadsheets put the byte order mark before the first cell, usernames are often written with "@"
+	value = strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(value, "\ufeff")), "@")
+	userId, err := strconv.Atoi(value)
+
+	if err != nil {
+		user, err := e.userProvider.GetUserByUserName(value)
+
+		if err != nil {
+			return dto.StudentDto{}, false
+		}
+
+		userId = user.Id
+	}
+
+	if !containsId(studentIds, userId) {
+		return dto.StudentDto{}, false
+	}
+
+	return getStudentDto(e.userProvider, userId), true
+}
+
+func (e ElectiveService) findOptionalCourse(name string) (dto.CourseDto, bool) {
+	courses, err := e.courseProvider.GetCourses()
+
+	if err != nil {
+		return dto.CourseDto{}, false
+	}
+
+	for _, course := range courses {
+		if course.IsOptional && strings.EqualFold(course.Name, strings.TrimSpace(name)) {
+			return getCourseDto(e.courseProvider, course.Id), true
+		}
+	}
+
+	return dto.CourseDto{}, false
+}
+
+func containsCourse(courses []dto.CourseDto, courseId string) bool {
+	for _, val := range courses {
+		if val.Id == courseId {
+			return true
+		}
+	}
+
+	return false
+}
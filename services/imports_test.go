@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/exceptions"
+	"testing"
+	"time"
+)
+
+// the fakes implement only the methods used by the import, the others panic through the nil interface
+
+type fakeScheduleProvider struct {
+	abstractions.IScheduleProvider
+	slots       map[string]*dao.ScheduleModel
+	additionals map[string][]dao.AdditionalScheduleModel
+	linkErr     error
+	replaceErr  error
+}
+
+func (f *fakeScheduleProvider) GetCommonSchedule() map[time.Weekday][]dao.ScheduleModel {
+	result := map[time.Weekday][]dao.ScheduleModel{}
+
+	for _, slot := range f.slots {
+		result[slot.Weekday] = append(result[slot.Weekday], *slot)
+	}
+
+	return result
+}
+
+func (f *fakeScheduleProvider) GetScheduleById(id string) (*dao.ScheduleModel, error) {
+	slot, ok := f.slots[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	result := *slot
+	return &result, nil
+}
+
+func (f *fakeScheduleProvider) LinkCoursesToUsers(links map[string]map[int]string) error {
+	if f.linkErr != nil {
+		return f.linkErr
+	}
+
+	for scheduleId, userLinks := range links {
+		slot, ok := f.slots[scheduleId]
+
+		if !ok {
+			continue
+		}
+
+		for userId, courseId := range userLinks {
+			if courseId == "" {
+				delete(slot.OptCourseParams.UserIdToCourseId, userId)
+				continue
+			}
+
+			slot.OptCourseParams.UserIdToCourseId[userId] = courseId
+		}
+	}
+
+	return nil
+}
+
+type fakeElectiveProvider struct {
+	abstractions.IElectiveProvider
+	pools     []dao.ElectivePoolModel
+	waitlists map[string]map[string][]int
+	window    *dao.ElectiveSelectionWindowModel
+}
+
+func (f *fakeElectiveProvider) GetPoolByScheduleId(scheduleId string) (*dao.ElectivePoolModel, error) {
+	for _, pool := range f.pools {
+		for _, id := range pool.ScheduleIds {
+			if id == scheduleId {
+				return &pool, nil
+			}
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func (f *fakeElectiveProvider) GetWaitlist(scheduleId string, courseId string) ([]int, error) {
+	return f.waitlists[scheduleId][courseId], nil
+}
+
+func (f *fakeElectiveProvider) GetWaitlistsBySchedule(scheduleId string) (map[string][]int, error) {
+	return f.waitlists[scheduleId], nil
+}
+
+func (f *fakeElectiveProvider) SaveWaitlist(scheduleId string, courseId string, userIds []int) error {
+	if f.waitlists[scheduleId] == nil {
+		f.waitlists[scheduleId] = map[string][]int{}
+	}
+
+	f.waitlists[scheduleId][courseId] = userIds
+	return nil
+}
+
+type fakeCourseProvider struct {
+	abstractions.ICourseProvider
+	courses []dao.CourseModel
+}
+
+func (f *fakeCourseProvider) GetCourses() ([]dao.CourseModel, error) {
+	return f.courses, nil
+}
+
+func (f *fakeCourseProvider) GetCourseById(id string) (*dao.CourseModel, error) {
+	for _, course := range f.courses {
+		if course.Id == id {
+			return &course, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+type fakeUserProvider struct {
+	abstractions.IUserProvider
+	users []dao.UserModel
+}
+
+func (f *fakeUserProvider) GetUserById(userId int) (*dao.UserModel, error) {
+	for _, user := range f.users {
+		if user.Id == userId {
+			return &user, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func (f *fakeUserProvider) GetUserByUserName(userName string) (*dao.UserModel, error) {
+	for _, user := range f.users {
+		if strings.EqualFold(user.UserName, userName) {
+			return &user, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type fakeSubgroupProvider struct {
+	abstractions.ISubgroupProvider
+	userSubgroups map[int]string
+}
+
+func (f *fakeSubgroupProvider) GetUserSubgroup(userId int) string {
+	return f.userSubgroups[userId]
+}
+
+// newTestSlots has the optional slot of the whole group and the slot of the subgroup "b"
+func newTestSlots() *fakeScheduleProvider {
+	return &fakeScheduleProvider{slots: map[string]*dao.ScheduleModel{
+		"s1": {Id: "s1", Weekday: time.Monday, Order: 1, IsOptional: true,
+			OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{}}},
+		"s2": {Id: "s2", Weekday: time.Tuesday, Order: 2, IsOptional: true, Subgroup: "b",
+			OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{}}},
+	}}
+}
+
+// newTestElectiveService has Math for the slot s1 and Art for the slot s2,
+// alice has no subgroup, bob is in "b" and carol is in "a"
+func newTestElectiveService() ElectiveService {
+	slots := newTestSlots()
+	return newTestElectiveServiceWith(slots, slots, &fakeElectiveProvider{waitlists: map[string]map[string][]int{}})
+}
+
+func newTestElectiveServiceWith(slots *fakeScheduleProvider, draft *fakeScheduleProvider, electives *fakeElectiveProvider) ElectiveService {
+	electives.pools = []dao.ElectivePoolModel{
+		{Id: "p1", CourseIds: []string{"math"}, ScheduleIds: []string{"s1"}},
+		{Id: "p2", CourseIds: []string{"art"}, ScheduleIds: []string{"s2"}},
+	}
+
+	return *NewElectiveService(
+		configuration.Configuration{},
+		electives,
+		slots,
+		draft,
+		&fakeCourseProvider{courses: []dao.CourseModel{
+			{Id: "math", Name: "Math", IsOptional: true},
+			{Id: "art", Name: "Art", IsOptional: true},
+			{Id: "history", Name: "History"},
+		}},
+		&fakeUserProvider{users: []dao.UserModel{
+			{Id: 10, UserName: "alice", FirstName: "Alice"},
+			{Id: 11, UserName: "bob", FirstName: "Bob"},
+			{Id: 12, UserName: "carol", FirstName: "Carol"},
+			{Id: 13, UserName: "dave", FirstName: "Dave"},
+		}},
+		&fakeSubgroupProvider{userSubgroups: map[int]string{11: "b", 12: "a"}})
+}
+
+func TestImportElectivesCsv(t *testing.T) {
+	students := []int{10, 11, 12}
+
+	tests := []struct {
+		name        string
+		data        string
+		wantApplied []string // user/slot/course
+		wantErrors  []string // line:reason
+	}{
+		{
+			name:        "user id and username",
+			data:        "10,Math\nbob, art\n",
+			wantApplied: []string{"10/s1/math", "11/s2/art"},
+		},
+		{
+			name:        "username with @",
+			data:        "@bob,Art\n",
+			wantApplied: []string{"11/s2/art"},
+		},
+		{
+			name:        "header is skipped",
+			data:        "user,course\n10,Math\n",
+			wantApplied: []string{"10/s1/math"},
+		},
+		{
+			name:        "header of a spreadsheet",
+			data:        "\ufeffСтудент, Назва курсу\n@alice,math\n",
+			wantApplied: []string{"10/s1/math"},
+		},
+		{
+			name:       "first line with a known course is not a header",
+			data:       "student,Math\n",
+			wantErrors: []string{"1:UnknownUser"},
+		},
+		{
+			name:       "unknown user",
+			data:       "dave,Math\n99,Math\n",
+			wantErrors: []string{"1:UnknownUser", "2:UnknownUser"},
+		},
+		{
+			name:       "unknown or mandatory course",
+			data:       "10,Physics\n10,History\n",
+			wantErrors: []string{"1:UnknownCourse", "2:UnknownCourse"},
+		},
+		{
+			name:       "missing course",
+			data:       "10\n",
+			wantErrors: []string{"1:InvalidRow"},
+		},
+		{
+			name:       "broken quotes",
+			data:       "10,\"Math\n",
+			wantErrors: []string{"1:InvalidCsv"},
+		},
+		{
+			name:        "slot of another subgroup",
+			data:        "carol,Art\ncarol,Math\n",
+			wantApplied: []string{"12/s1/math"},
+			wantErrors:  []string{"1:SubgroupMismatch"},
+		},
+		{
+			name:        "second line for the same slot",
+			data:        "10,Math\n10,Math\n",
+			wantApplied: []string{"10/s1/math"},
+			wantErrors:  []string{"2:ConflictWithLine1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := newTestElectiveService().ImportElectivesCsv([]byte(tt.data), students)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var applied, reasons []string
+
+			for _, val := range response.Applied {
+				applied = append(applied, fmt.Sprintf("%d/%s/%s", val.Student.UserId, val.Slot.ScheduleId, val.CourseInfo.Id))
+
+				if val.Line == 0 {
+					t.Errorf("the line of %d is not reported", val.Student.UserId)
+				}
+			}
+
+			for _, val := range response.Errors {
+				reasons = append(reasons, fmt.Sprintf("%d:%s", val.Line, val.Reason))
+			}
+
+			if !equalStrings(applied, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", applied, tt.wantApplied)
+			}
+
+			if !equalStrings(reasons, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", reasons, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestImportElectivesCsvWriteFailure(t *testing.T) {
+	failure := errors.New("StorageError")
+
+	tests := []struct {
+		name     string
+		slotsErr error
+		draftErr error
+	}{
+		{name: "published schedule", slotsErr: failure},
+		{name: "draft", draftErr: failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots, draft := newTestSlots(), newTestSlots()
+			slots.linkErr, draft.linkErr = tt.slotsErr, tt.draftErr
+			slots.slots["s1"].OptCourseParams.UserIdToCourseId[11] = "math"
+
+			electives := &fakeElectiveProvider{waitlists: map[string]map[string][]int{"s2": {"art": {12, 10}}}}
+			service := newTestElectiveServiceWith(slots, draft, electives)
+
+			if _, err := service.ImportElectivesCsv([]byte("10,Math\n10,Art\nbob,Art\n"), []int{10, 11, 12}); err != failure {
+				t.Fatalf("error = %v, want %v", err, failure)
+			}
+
+			if links := slots.slots["s1"].OptCourseParams.UserIdToCourseId; len(links) != 1 || links[11] != "math" {
+				t.Errorf("links of s1 = %v, want only the previous one", links)
+			}
+
+			if links := slots.slots["s2"].OptCourseParams.UserIdToCourseId; len(links) != 0 {
+				t.Errorf("links of s2 = %v, want none", links)
+			}
+
+			if waitlist := electives.waitlists["s2"]["art"]; len(waitlist) != 2 {
+				t.Errorf("waitlist = %v, want it unchanged", waitlist)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+const (
+	maxReminderOffsets = 5
+	maxReminderOffset  = 24 * 60 // minutes
+	defaultDigestTime  = 7*time.Hour + 30*time.Minute
+	defaultPreviewTime = 20 * time.Hour
+)
+
+// PreferencesService keeps the personal notification settings, users without settings get the configured ones.
+// The quiet hours of group chats and channels are kept with the publication targets. The settings are changed
+// by the users and by the background service, so every change reads and writes them under the mutex
+type PreferencesService struct {
+	config         configuration.Configuration
+	provider       abstractions.IPreferencesProvider
+	targetProvider abstractions.IPublicationTargetProvider
+	mutex          *sync.Mutex
+}
+
+func NewPreferencesService(
+	config configuration.Configuration,
+	provider abstractions.IPreferencesProvider,
+	targetProvider abstractions.IPublicationTargetProvider) *PreferencesService {
+	return &PreferencesService{config: config, provider: provider, targetProvider: targetProvider, mutex: &sync.Mutex{}}
+}
+
+func (p PreferencesService) GetReminderSettings(userId int) dto.ReminderSettingsDto {
+	model, err := p.provider.GetPreferences(userId)
+
+	if err != nil {
+		return dto.ReminderSettingsDto{Enabled: true, IsDefault: true, Offsets: p.GetDefaultReminderOffsets()}
+	}
+
+	settings := dto.ReminderSettingsDto{Enabled: !model.RemindersOff, Offsets: model.ReminderOffsets}
+
+	if len(settings.Offsets) == 0 {
+		settings.IsDefault = true
+		settings.Offsets = p.GetDefaultReminderOffsets()
+	}
+
+	return settings
+}
+
+func (p PreferencesService) UpdateReminderSettings(request dto.UpdateReminderSettingsRequest) error {
+	offsets, err := normalizeReminderOffsets(request.Offsets)
+
+	if err != nil {
+		return err
+	}
+
+	if len(offsets) > maxReminderOffsets {
+		return errors.New("TooManyOffsets")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	model := p.getPreferences(request.UserId)
+	model.RemindersOff = !request.Enabled
+
+	// the offsets are kept when reminders are turned off, so turning them on restores the choice
+	if request.Enabled {
+		model.ReminderOffsets = offsets
+	}
+
+	return p.provider.SavePreferences(model)
+}
+
+// GetDefaultReminderOffsets returns the configured reminder intervals and the start of the lesson
+func (p PreferencesService) GetDefaultReminderOffsets() []int {
+	offsets := []int{0}
+
+	for _, interval := range p.config.ScheduleSettings.ReminderIntervals {
+		if interval > 0 && !containsId(offsets, interval) {
+			offsets = append(offsets, interval)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+
+	return offsets
+}
+
+func (p PreferencesService) GetQuietHours(chatId int64) dto.QuietHoursDto {
+	return convertToQuietHoursDto(p.getQuietHoursModel(chatId))
+}
+
+func (p PreferencesService) UpdateQuietHours(request dto.UpdateQuietHoursRequest) error {
+	if request.Quiet.QuietFrom < 0 || request.Quiet.QuietFrom >= 24*time.Hour ||
+		request.Quiet.QuietTo < 0 || request.Quiet.QuietTo >= 24*time.Hour {
+		return errors.New("InvalidQuietHours")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	quiet := dao.QuietHoursModel{
+		QuietFrom:             request.Quiet.QuietFrom,
+		QuietTo:               request.Quiet.QuietTo,
+		MutedUntil:            request.Quiet.MutedUntil,
+		RemindersBreakThrough: request.Quiet.RemindersBreakThrough,
+	}
+
+	if request.ChatId < 0 {
+		target, err := p.targetProvider.GetTarget(request.ChatId)
+
+		if err != nil {
+			return err
+		}
+
+		target.Quiet = quiet
+		return p.targetProvider.SaveTarget(*target)
+	}
+
+	model := p.getPreferences(int(request.ChatId))
+	model.Quiet = quiet
+
+	return p.provider.SavePreferences(model)
+}
+
+// CheckQuietHours tells whether the chat is in the quiet hours or muted at the time, the end of the quiet time
+// is the moment to check again, the quiet hours may start right after a mute
+func (p PreferencesService) CheckQuietHours(chatId int64, at time.Time) dto.QuietCheckDto {
+	quiet := p.getQuietHoursModel(chatId)
+	check := dto.QuietCheckDto{RemindersBreakThrough: quiet.RemindersBreakThrough}
+
+	if at.Before(quiet.MutedUntil) {
+		check.IsQuiet = true
+		check.Until = quiet.MutedUntil
+		return check
+	}
+
+	if quiet.QuietFrom == quiet.QuietTo {
+		return check
+	}
+
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	sinceMidnight := at.Sub(midnight)
+
+	switch {
+	case quiet.QuietFrom < quiet.QuietTo && sinceMidnight >= quiet.QuietFrom && sinceMidnight < quiet.QuietTo:
+		check.IsQuiet, check.Until = true, midnight.Add(quiet.QuietTo)
+	case quiet.QuietFrom > quiet.QuietTo && sinceMidnight >= quiet.QuietFrom:
+		check.IsQuiet, check.Until = true, midnight.AddDate(0, 0, 1).Add(quiet.QuietTo)
+	case quiet.QuietFrom > quiet.QuietTo && sinceMidnight < quiet.QuietTo:
+		check.IsQuiet, check.Until = true, midnight.Add(quiet.QuietTo)
+	}
+
+	return check
+}
+
+func (p PreferencesService) GetDigestSettings(userId int, kind util.DigestKind) dto.DigestSettingsDto {
+	model := p.getPreferences(userId)
+	enabled, digestTime, _ := digestFields(&model, kind)
+	settings := dto.DigestSettingsDto{Kind: kind, Enabled: *enabled}
+
+	if *digestTime != nil {
+		settings.Time = **digestTime
+	} else {
+		settings.IsDefaultTime = true
+		settings.Time = p.getDefaultDigestTime(kind)
+	}
+
+	return settings
+}
+
+func (p PreferencesService) UpdateDigestSettings(request dto.UpdateDigestSettingsRequest) error {
+	if request.Time != nil && (*request.Time < 0 || *request.Time >= 24*time.Hour) {
+		return errors.New("InvalidDigestTime")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	model := p.getPreferences(request.UserId)
+	enabled, digestTime, _ := digestFields(&model, request.Kind)
+	*enabled = request.Enabled
+	*digestTime = request.Time
+
+	return p.provider.SavePreferences(model)
+}
+
+// IsDigestDue tells whether the digest of the kind should be sent to the user, it is sent once a day
+// after the chosen time
+func (p PreferencesService) IsDigestDue(userId int, kind util.DigestKind, at time.Time) bool {
+	settings := p.GetDigestSettings(userId, kind)
+
+	if !settings.Enabled {
+		return false
+	}
+
+	model := p.getPreferences(userId)
+	_, _, lastSentAt := digestFields(&model, kind)
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+
+	return !at.Before(midnight.Add(settings.Time)) && lastSentAt.Before(midnight)
+}
+
+func (p PreferencesService) MarkDigestSent(userId int, kind util.DigestKind, sentAt time.Time) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	model := p.getPreferences(userId)
+	_, _, lastSentAt := digestFields(&model, kind)
+	*lastSentAt = sentAt
+
+	return p.provider.SavePreferences(model)
+}
+
+func (p PreferencesService) getDefaultDigestTime(kind util.DigestKind) time.Duration {
+	digestTime, defaultTime := p.config.ScheduleSettings.DigestTime, defaultDigestTime
+
+	if kind == util.DigestKindEvening {
+		digestTime, defaultTime = p.config.ScheduleSettings.PreviewTime, defaultPreviewTime
+	}
+
+	if digestTime > 0 && digestTime < 24*time.Hour {
+		return digestTime
+	}
+
+	return defaultTime
+}
+
+func (p PreferencesService) GetMutes(userId int) dto.MutesDto {
+	model := p.getPreferences(userId)
+
+	return dto.MutesDto{CourseIds: model.MutedCourseIds, SlotIds: model.MutedSlotIds}
+}
+
+func (p PreferencesService) UpdateMute(request dto.UpdateMuteRequest) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	model := p.getPreferences(request.UserId)
+
+	switch {
+	case request.CourseId != "":
+		model.MutedCourseIds = setMuted(model.MutedCourseIds, request.CourseId, request.Muted)
+	case request.ScheduleId != "":
+		model.MutedSlotIds = setMuted(model.MutedSlotIds, request.ScheduleId, request.Muted)
+	default:
+		return errors.New("EmptyMute")
+	}
+
+	return p.provider.SavePreferences(model)
+}
+
+func (p PreferencesService) getQuietHoursModel(chatId int64) dao.QuietHoursModel {
+	if chatId < 0 {
+		target, err := p.targetProvider.GetTarget(chatId)
+
+		if err != nil {
+			return dao.QuietHoursModel{}
+		}
+
+		return target.Quiet
+	}
+
+	return p.getPreferences(int(chatId)).Quiet
+}
+
+func (p PreferencesService) getPreferences(userId int) dao.PreferencesModel {
+	model, err := p.provider.GetPreferences(userId)
+
+	if err != nil {
+		return dao.PreferencesModel{UserId: userId}
+	}
+
+	return *model
+}
+
+// normalizeReminderOffsets removes the duplicates and sorts the offsets from the earliest reminder
+func normalizeReminderOffsets(offsets []int) ([]int, error) {
+	var result []int
+
+	for _, offset := range offsets {
+		if offset < 0 || offset > maxReminderOffset {
+			return nil, errors.New("InvalidOffset")
+		}
+
+		if !containsId(result, offset) {
+			result = append(result, offset)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+
+	return result, nil
+}
+
+func setMuted(ids []string, id string, muted bool) []string {
+	var result []string
+
+	for _, val := range ids {
+		if val != id {
+			result = append(result, val)
+		}
+	}
+
+	if muted {
+		result = append(result, id)
+	}
+
+	return result
+}
+
+// digestFields returns the settings of the morning digest or of the evening preview
+func digestFields(model *dao.PreferencesModel, kind util.DigestKind) (*bool, **time.Duration, *time.Time) {
+	if kind == util.DigestKindEvening {
+		return &model.Preview, &model.PreviewTime, &model.LastPreviewAt
+	}
+
+	return &model.Digest, &model.DigestTime, &model.LastDigestAt
+}
+
+func convertToQuietHoursDto(model dao.QuietHoursModel) dto.QuietHoursDto {
+	return dto.QuietHoursDto{
+		QuietFrom:             model.QuietFrom,
+		QuietTo:               model.QuietTo,
+		MutedUntil:            model.MutedUntil,
+		RemindersBreakThrough: model.RemindersBreakThrough,
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"fmt"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+type fakePreferencesProvider struct {
+	models map[int]dao.PreferencesModel
+}
+
+func (f *fakePreferencesProvider) GetPreferences(userId int) (*dao.PreferencesModel, error) {
+	model, ok := f.models[userId]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &model, nil
+}
+
+func (f *fakePreferencesProvider) SavePreferences(model dao.PreferencesModel) error {
+	f.models[model.UserId] = model
+	return nil
+}
+
+func TestCheckQuietHours(t *testing.T) {
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	night := dao.QuietHoursModel{QuietFrom: 22 * time.Hour, QuietTo: 8 * time.Hour}
+	lunch := dao.QuietHoursModel{QuietFrom: 13 * time.Hour, QuietTo: 14 * time.Hour}
+
+	tests := []struct {
+		name      string
+		quiet     dao.QuietHoursModel
+		at        time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{name: "no quiet hours", at: at(23, 0)},
+		{name: "before the night", quiet: night, at: at(21, 59)},
+		{name: "start of the night", quiet: night, at: at(22, 0), wantQuiet: true, wantUntil: day.AddDate(0, 0, 1).Add(8 * time.Hour)},
+		{name: "before midnight", quiet: night, at: at(23, 59), wantQuiet: true, wantUntil: day.AddDate(0, 0, 1).Add(8 * time.Hour)},
+		{name: "after midnight", quiet: night, at: at(0, 30), wantQuiet: true, wantUntil: at(8, 0)},
+		{name: "end of the night", quiet: night, at: at(8, 0)},
+		{name: "day", quiet: night, at: at(12, 0)},
+		{name: "within the day window", quiet: lunch, at: at(13, 30), wantQuiet: true, wantUntil: at(14, 0)},
+		{name: "after the day window", quiet: lunch, at: at(14, 0)},
+		{
+			name:      "muted",
+			quiet:     dao.QuietHoursModel{MutedUntil: at(15, 0)},
+			at:        at(12, 0),
+			wantQuiet: true,
+			wantUntil: at(15, 0),
+		},
+		{
+			name:  "mute is over",
+			quiet: dao.QuietHoursModel{MutedUntil: at(11, 0)},
+			at:    at(12, 0),
+		},
+		{
+			name:      "mute goes before the quiet hours",
+			quiet:     dao.QuietHoursModel{QuietFrom: 22 * time.Hour, QuietTo: 8 * time.Hour, MutedUntil: at(23, 0)},
+			at:        at(21, 0),
+			wantQuiet: true,
+			wantUntil: at(23, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{1: {UserId: 1, Quiet: tt.quiet}}}
+			service := NewPreferencesService(configuration.Configuration{}, provider, nil)
+
+			check := service.CheckQuietHours(1, tt.at)
+
+			if check.IsQuiet != tt.wantQuiet {
+				t.Fatalf("IsQuiet = %v, want %v", check.IsQuiet, tt.wantQuiet)
+			}
+
+			if tt.wantQuiet && !check.Until.Equal(tt.wantUntil) {
+				t.Errorf("Until = %v, want %v", check.Until, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestIsDigestDue(t *testing.T) {
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)
+	midnight := time.Duration(0)
+	nine := 9 * time.Hour
+
+	tests := []struct {
+		name  string
+		model dao.PreferencesModel
+		kind  util.DigestKind
+		at    time.Time
+		want  bool
+	}{
+		{name: "disabled", model: dao.PreferencesModel{}, at: day.Add(12 * time.Hour)},
+		{name: "before the configured time", model: dao.PreferencesModel{Digest: true}, at: day.Add(7 * time.Hour)},
+		{name: "after the configured time", model: dao.PreferencesModel{Digest: true}, at: day.Add(8 * time.Hour), want: true},
+		{name: "own time", model: dao.PreferencesModel{Digest: true, DigestTime: &nine}, at: day.Add(8 * time.Hour)},
+		{name: "midnight", model: dao.PreferencesModel{Digest: true, DigestTime: &midnight}, at: day, want: true},
+		{name: "sent today", model: dao.PreferencesModel{Digest: true, LastDigestAt: day.Add(8 * time.Hour)}, at: day.Add(9 * time.Hour)},
+		{name: "sent yesterday", model: dao.PreferencesModel{Digest: true, LastDigestAt: day.Add(-time.Hour)}, at: day.Add(9 * time.Hour), want: true},
+		{name: "preview is separate", model: dao.PreferencesModel{Digest: true, Preview: true, LastDigestAt: day.Add(8 * time.Hour)},
+			kind: util.DigestKindEvening, at: day.Add(21 * time.Hour), want: true},
+		{name: "preview before the time", model: dao.PreferencesModel{Preview: true}, kind: util.DigestKindEvening, at: day.Add(19 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.kind == 0 {
+				tt.kind = util.DigestKindMorning
+			}
+
+			tt.model.UserId = 1
+			provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{1: tt.model}}
+			service := NewPreferencesService(configuration.Configuration{}, provider, nil)
+
+			if got := service.IsDigestDue(1, tt.kind, tt.at); got != tt.want {
+				t.Errorf("IsDigestDue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkDigestSent(t *testing.T) {
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)
+	provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{1: {UserId: 1, Digest: true, Preview: true}}}
+	service := NewPreferencesService(configuration.Configuration{}, provider, nil)
+
+	if err := service.MarkDigestSent(1, util.DigestKindMorning, day.Add(8*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if service.IsDigestDue(1, util.DigestKindMorning, day.Add(10*time.Hour)) {
+		t.Errorf("expected the digest to be sent once a day")
+	}
+
+	if !service.IsDigestDue(1, util.DigestKindMorning, day.AddDate(0, 0, 1).Add(8*time.Hour)) {
+		t.Errorf("expected the digest to be due the next day")
+	}
+
+	if !service.IsDigestDue(1, util.DigestKindEvening, day.Add(21*time.Hour)) {
+		t.Errorf("expected the preview to stay due")
+	}
+
+	if model := provider.models[1]; !model.LastDigestAt.Equal(day.Add(8*time.Hour)) || !model.LastPreviewAt.IsZero() {
+		t.Errorf("unexpected stored times %v, %v", model.LastDigestAt, model.LastPreviewAt)
+	}
+}
+
+func TestGetReminderSettings(t *testing.T) {
+	var config configuration.Configuration
+	config.ScheduleSettings.ReminderIntervals = []int{5, 15, 5, 0}
+
+	provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{
+		1: {UserId: 1, ReminderOffsets: []int{30, 5}},
+		2: {UserId: 2, RemindersOff: true, ReminderOffsets: []int{10}},
+		3: {UserId: 3, Digest: true},
+	}}
+	service := NewPreferencesService(config, provider, nil)
+
+	tests := []struct {
+		userId int
+		want   string
+	}{
+		{userId: 1, want: "{Enabled:true IsDefault:false Offsets:[30 5]}"},
+		{userId: 2, want: "{Enabled:false IsDefault:false Offsets:[10]}"},
+		{userId: 3, want: "{Enabled:true IsDefault:true Offsets:[15 5 0]}"},
+		{userId: 4, want: "{Enabled:true IsDefault:true Offsets:[15 5 0]}"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%+v", service.GetReminderSettings(tt.userId)); got != tt.want {
+			t.Errorf("settings of %d = %s, want %s", tt.userId, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateReminderSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		request dto.UpdateReminderSettingsRequest
+		wantErr string
+		want    string
+	}{
+		{
+			name:    "offsets are sorted without duplicates",
+			request: dto.UpdateReminderSettingsRequest{Enabled: true, Offsets: []int{5, 30, 5, 0}},
+			want:    "{Enabled:true IsDefault:false Offsets:[30 5 0]}",
+		},
+		{
+			name:    "reset to the configured intervals",
+			request: dto.UpdateReminderSettingsRequest{Enabled: true},
+			want:    "{Enabled:true IsDefault:true Offsets:[10 0]}",
+		},
+		{
+			name:    "turning off keeps the offsets",
+			request: dto.UpdateReminderSettingsRequest{Offsets: []int{1}},
+			want:    "{Enabled:false IsDefault:false Offsets:[60]}",
+		},
+		{
+			name:    "negative offset",
+			request: dto.UpdateReminderSettingsRequest{Enabled: true, Offsets: []int{-5}},
+			wantErr: "InvalidOffset",
+			want:    "{Enabled:true IsDefault:false Offsets:[60]}",
+		},
+		{
+			name:    "offset over a day",
+			request: dto.UpdateReminderSettingsRequest{Enabled: true, Offsets: []int{24*60 + 1}},
+			wantErr: "InvalidOffset",
+			want:    "{Enabled:true IsDefault:false Offsets:[60]}",
+		},
+		{
+			name:    "too many offsets",
+			request: dto.UpdateReminderSettingsRequest{Enabled: true, Offsets: []int{1, 2, 3, 4, 5, 6}},
+			wantErr: "TooManyOffsets",
+			want:    "{Enabled:true IsDefault:false Offsets:[60]}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config configuration.Configuration
+			config.ScheduleSettings.ReminderIntervals = []int{10}
+
+			provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{1: {UserId: 1, ReminderOffsets: []int{60}}}}
+			service := NewPreferencesService(config, provider, nil)
+
+			tt.request.UserId = 1
+			err := service.UpdateReminderSettings(tt.request)
+
+			if (err == nil) != (tt.wantErr == "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			if got := fmt.Sprintf("%+v", service.GetReminderSettings(1)); got != tt.want {
+				t.Errorf("settings = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReminderOffsets(t *testing.T) {
+	provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{
+		1: {UserId: 1, ReminderOffsets: []int{30}},
+		2: {UserId: 2, RemindersOff: true},
+	}}
+	service := BackgroundService{preferencesService: NewPreferencesService(configuration.Configuration{}, provider, nil)}
+
+	if got := service.getReminderOffsets(1); fmt.Sprint(got) != "[30]" {
+		t.Errorf("offsets = %v, want the own offsets", got)
+	}
+
+	// the reminders which are off are not planned at all
+	if got := service.getReminderOffsets(2); got != nil {
+		t.Errorf("offsets = %v, want none", got)
+	}
+
+	if got := service.getReminderOffsets(3); fmt.Sprint(got) != "[0]" {
+		t.Errorf("offsets = %v, want the start of the lesson", got)
+	}
+}
+
+func TestUpdateMute(t *testing.T) {
+	provider := &fakePreferencesProvider{models: map[int]dao.PreferencesModel{1: {UserId: 1, Digest: true}}}
+	service := NewPreferencesService(configuration.Configuration{}, provider, nil)
+
+	requests := []dto.UpdateMuteRequest{
+		{UserId: 1, CourseId: "math", Muted: true},
+		{UserId: 1, CourseId: "art", Muted: true},
+		{UserId: 1, CourseId: "math", Muted: true},
+		{UserId: 1, ScheduleId: "s1", Muted: true},
+		{UserId: 1, CourseId: "art"},
+	}
+
+	for _, request := range requests {
+		if err := service.UpdateMute(request); err != nil {
+			t.Fatalf("mute %+v: unexpected error: %s", request, err)
+		}
+	}
+
+	if err := service.UpdateMute(dto.UpdateMuteRequest{UserId: 1, Muted: true}); err == nil || err.Error() != "EmptyMute" {
+		t.Errorf("error = %v, want EmptyMute", err)
+	}
+
+	if got := fmt.Sprintf("%+v", service.GetMutes(1)); got != "{CourseIds:[math] SlotIds:[s1]}" {
+		t.Errorf("mutes = %s, want math and s1", got)
+	}
+
+	// the other settings are kept
+	if !provider.models[1].Digest {
+		t.Errorf("the digest was turned off by the mute")
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// plannedReminder is a single notification about a lesson, due at the exact time
+type plannedReminder struct {
+	id        string
+	owner     string
+	date      time.Time
+	offset    int
+	dueAt     time.Time
+	startTime time.Time
+	chatId    int64
+	schedule  dto.ScheduleDto
+	index     int
+}
+
+// reminderHeap keeps the planned reminders ordered by the due time
+type reminderHeap []*plannedReminder
+
+func (h reminderHeap) Len() int { return len(h) }
+
+func (h reminderHeap) Less(i, j int) bool { return h[i].dueAt.Before(h[j].dueAt) }
+
+func (h reminderHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *reminderHeap) Push(x any) {
+	reminder := x.(*plannedReminder)
+	reminder.index = len(*h)
+	*h = append(*h, reminder)
+}
+
+func (h *reminderHeap) Pop() any {
+	old := *h
+	n := len(old)
+	reminder := old[n-1]
+	old[n-1] = nil
+	reminder.index = -1
+	*h = old[:n-1]
+	return reminder
+}
+
+// reminderScheduler sends the planned reminders from a single goroutine, it sleeps until the nearest due time
+// and is woken up when the plan changes. Every reminder is recorded in the outbox, the delivered ones are not
+// planned again after a restart and the ones missed while the service was stopped are collected for a summary
+type reminderScheduler struct {
+	outbox  abstractions.IOutboxProvider
+	mu      *sync.Mutex
+	queue   *reminderHeap
+	planned map[string]*plannedReminder
+	catchUp bool
+	missed  map[int64][]*plannedReminder
+	wake    chan struct{}
+}
+
+func newReminderScheduler(outbox abstractions.IOutboxProvider) *reminderScheduler {
+	return &reminderScheduler{
+		outbox:  outbox,
+		mu:      &sync.Mutex{},
+		queue:   &reminderHeap{},
+		planned: map[string]*plannedReminder{},
+		catchUp: true,
+		missed:  map[int64][]*plannedReminder{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Plan replaces the pending reminders of the owner, the reminders which are not in the new plan are cancelled
+// and the ones which are already planned keep their place
+func (s *reminderScheduler) Plan(owner string, reminders []*plannedReminder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	actual := map[string]bool{}
+	var entries []dao.OutboxEntryModel
+
+	for _, reminder := range reminders {
+		reminder.owner = owner
+		reminder.id = owner + "/" + reminder.id
+		actual[reminder.id] = true
+
+		if existing, exists := s.planned[reminder.id]; exists {
+			// the course info may be changed, the due time is a part of the id
+			existing.schedule = reminder.schedule
+			continue
+		}
+
+		entry, err := s.outbox.GetEntry(reminder.id)
+
+		if err == nil && entry.State != util.OutboxStatePending {
+			continue
+		}
+
+		if !reminder.dueAt.After(now) {
+			// only the reminders planned before the stop are missed, the first start owes nothing
+			if s.catchUp && err == nil {
+				s.missed[reminder.chatId] = append(s.missed[reminder.chatId], reminder)
+			}
+			continue
+		}
+
+		heap.Push(s.queue, reminder)
+		s.planned[reminder.id] = reminder
+		entries = append(entries, reminder.toEntry(util.OutboxStatePending, ""))
+	}
+
+	var cancelled []string
+
+	for id, reminder := range s.planned {
+		if reminder.owner == owner && !actual[id] {
+			s.remove(reminder)
+			cancelled = append(cancelled, id)
+		}
+	}
+
+	s.saveOutbox(entries, cancelled)
+	s.notify()
+}
+
+// Retain cancels the reminders of the owners which are not planned anymore, e.g. removed members or disabled chats
+func (s *reminderScheduler) Retain(owners map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cancelled []string
+
+	for id, reminder := range s.planned {
+		if !owners[reminder.owner] {
+			s.remove(reminder)
+			cancelled = append(cancelled, id)
+		}
+	}
+
+	s.saveOutbox(nil, cancelled)
+	s.notify()
+}
+
+// CatchUp sends a single summary per chat about the lessons whose reminders were missed while the service was
+// stopped, it is done once after the first planning
+func (s *reminderScheduler) CatchUp(handleFunc CatchUpHandleFunc) {
+	s.mu.Lock()
+	missed := s.missed
+	s.missed = map[int64][]*plannedReminder{}
+	s.catchUp = false
+	s.mu.Unlock()
+
+	for chatId, reminders := range missed {
+		reminders := reminders
+		var schedules []dto.ScheduleDto
+		lessons := map[string]bool{}
+
+		for _, reminder := range reminders {
+			lesson := fmt.Sprintf("%d/%s/%s", reminder.schedule.Order, reminder.schedule.Subgroup, reminder.schedule.CourseInfo.Name)
+
+			if !lessons[lesson] {
+				lessons[lesson] = true
+				schedules = append(schedules, reminder.schedule)
+			}
+		}
+
+		sort.Slice(schedules, func(i, j int) bool {
+			return schedules[i].Order < schedules[j].Order
+		})
+
+		// the entries stay pending until the summary is delivered, so a summary postponed by the quiet hours
+		// is sent again after a restart
+		handleFunc(schedules, chatId, func(err error) {
+			s.saveDelivered(reminders, err)
+		})
+	}
+}
+
+func (s *reminderScheduler) saveDelivered(reminders []*plannedReminder, err error) {
+	state, reason := util.OutboxStateSent, ""
+
+	if err != nil {
+		state, reason = util.OutboxStateFailed, err.Error()
+	}
+
+	var entries []dao.OutboxEntryModel
+
+	for _, reminder := range reminders {
+		entries = append(entries, reminder.toEntry(state, reason))
+	}
+
+	s.mu.Lock()
+	s.saveOutbox(entries, nil)
+	s.mu.Unlock()
+}
+
+func (s *reminderScheduler) Run(ctx context.Context, handleFunc HandleFunc) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		due := s.popDue(time.Now())
+
+		for _, reminder := range due {
+			go s.deliver(reminder, handleFunc)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		timer.Reset(s.nextWait(time.Now()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *reminderScheduler) deliver(reminder *plannedReminder, handleFunc HandleFunc) {
+	state, reason := util.OutboxStateSent, ""
+
+	if err := handleFunc(reminder.schedule, reminder.startTime, reminder.chatId); err != nil {
+		state, reason = util.OutboxStateFailed, err.Error()
+	}
+
+	if err := s.outbox.SetEntryState(reminder.id, state, reason); err != nil {
+		logrus.Errorf("Failed to save the state of the reminder %s: %s", reminder.id, err)
+	}
+}
+
+func (s *reminderScheduler) popDue(now time.Time) []*plannedReminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*plannedReminder
+
+	for s.queue.Len() > 0 && !(*s.queue)[0].dueAt.After(now) {
+		reminder := heap.Pop(s.queue).(*plannedReminder)
+		delete(s.planned, reminder.id)
+		due = append(due, reminder)
+	}
+
+	return due
+}
+
+// nextWait returns the time until the nearest reminder, the scheduler sleeps for an hour when nothing is planned
+func (s *reminderScheduler) nextWait(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queue.Len() == 0 {
+		return time.Hour
+	}
+
+	return (*s.queue)[0].dueAt.Sub(now)
+}
+
+func (s *reminderScheduler) remove(reminder *plannedReminder) {
+	heap.Remove(s.queue, reminder.index)
+	delete(s.planned, reminder.id)
+}
+
+// saveOutbox records the newly planned reminders and forgets the cancelled ones, the plan in memory stays
+// authoritative when the storage fails
+func (s *reminderScheduler) saveOutbox(entries []dao.OutboxEntryModel, cancelled []string) {
+	if err := s.outbox.SaveEntries(entries); err != nil {
+		logrus.Errorf("Failed to save the planned reminders: %s", err)
+	}
+
+	if err := s.outbox.DeleteEntries(cancelled); err != nil {
+		logrus.Errorf("Failed to delete the cancelled reminders: %s", err)
+	}
+}
+
+func (s *reminderScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *plannedReminder) toEntry(state util.OutboxState, reason string) dao.OutboxEntryModel {
+	return dao.OutboxEntryModel{
+		Id:        r.id,
+		Owner:     r.owner,
+		ChatId:    r.chatId,
+		Date:      r.date,
+		Order:     r.schedule.Order,
+		Offset:    r.offset,
+		DueAt:     r.dueAt,
+		State:     state,
+		Error:     reason,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// reminderId identifies the reminder of the lesson within its owner
+func reminderId(schedule dto.ScheduleDto, startTime time.Time, offset int) string {
+	return fmt.Sprintf("%s/%d/%s/%s/%d",
+		startTime.Format("2006-01-02"), schedule.Order, schedule.Subgroup, schedule.CourseInfo.Name, offset)
+}
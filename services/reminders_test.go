@@ -0,0 +1,263 @@
+package services
+
+import (
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+type fakeOutbox struct {
+	entries map[string]dao.OutboxEntryModel
+}
+
+func newFakeOutbox(entries ...dao.OutboxEntryModel) *fakeOutbox {
+	outbox := &fakeOutbox{entries: map[string]dao.OutboxEntryModel{}}
+
+	for _, entry := range entries {
+		outbox.entries[entry.Id] = entry
+	}
+
+	return outbox
+}
+
+func (f *fakeOutbox) SaveEntries(models []dao.OutboxEntryModel) error {
+	for _, model := range models {
+		f.entries[model.Id] = model
+	}
+
+	return nil
+}
+
+func (f *fakeOutbox) GetEntry(id string) (*dao.OutboxEntryModel, error) {
+	entry, ok := f.entries[id]
+
+	if !ok {
+		return nil, exceptions.NotFound
+	}
+
+	return &entry, nil
+}
+
+func (f *fakeOutbox) SetEntryState(id string, state util.OutboxState, reason string) error {
+	entry, ok := f.entries[id]
+
+	if !ok {
+		return exceptions.NotFound
+	}
+
+	entry.State, entry.Error = state, reason
+	f.entries[id] = entry
+
+	return nil
+}
+
+func (f *fakeOutbox) DeleteEntries(ids []string) error {
+	for _, id := range ids {
+		delete(f.entries, id)
+	}
+
+	return nil
+}
+
+func (f *fakeOutbox) DeleteEntriesBefore(date time.Time) error {
+	for id, entry := range f.entries {
+		if entry.Date.Before(date) {
+			delete(f.entries, id)
+		}
+	}
+
+	return nil
+}
+
+func testReminder(id string, dueAt time.Time) *plannedReminder {
+	return &plannedReminder{id: id, dueAt: dueAt, chatId: 1}
+}
+
+func TestReminderSchedulerOrder(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		plans    [][]*plannedReminder // the plans of the same owner one after another
+		want     []string
+		wantWait time.Duration
+	}{
+		{
+			name: "ordered by the due time",
+			plans: [][]*plannedReminder{{
+				testReminder("c", now.Add(3*time.Hour)),
+				testReminder("a", now.Add(time.Hour)),
+				testReminder("b", now.Add(2*time.Hour)),
+			}},
+			want:     []string{"owner/a", "owner/b", "owner/c"},
+			wantWait: time.Hour,
+		},
+		{
+			name: "new plan cancels the missing reminders",
+			plans: [][]*plannedReminder{
+				{testReminder("a", now.Add(time.Hour)), testReminder("b", now.Add(2*time.Hour))},
+				{testReminder("b", now.Add(2*time.Hour)), testReminder("c", now.Add(30*time.Minute))},
+			},
+			want:     []string{"owner/c", "owner/b"},
+			wantWait: 30 * time.Minute,
+		},
+		{
+			name: "planned again keeps a single reminder",
+			plans: [][]*plannedReminder{
+				{testReminder("a", now.Add(time.Hour))},
+				{testReminder("a", now.Add(time.Hour))},
+			},
+			want:     []string{"owner/a"},
+			wantWait: time.Hour,
+		},
+		{
+			name:     "nothing is planned",
+			plans:    [][]*plannedReminder{{}},
+			wantWait: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newReminderScheduler(newFakeOutbox())
+
+			for _, plan := range tt.plans {
+				scheduler.Plan("owner", plan)
+			}
+
+			if wait := scheduler.nextWait(now); wait != tt.wantWait {
+				t.Errorf("nextWait() = %v, want %v", wait, tt.wantWait)
+			}
+
+			if due := scheduler.popDue(now); len(due) != 0 {
+				t.Errorf("popDue() before the due time returned %d reminders", len(due))
+			}
+
+			due := scheduler.popDue(now.Add(24 * time.Hour))
+
+			if len(due) != len(tt.want) {
+				t.Fatalf("popDue() returned %d reminders, want %d", len(due), len(tt.want))
+			}
+
+			for i, reminder := range due {
+				if reminder.id != tt.want[i] {
+					t.Errorf("reminder %d = %s, want %s", i, reminder.id, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReminderSchedulerRetain(t *testing.T) {
+	now := time.Now()
+	outbox := newFakeOutbox()
+	scheduler := newReminderScheduler(outbox)
+
+	scheduler.Plan("first", []*plannedReminder{testReminder("a", now.Add(time.Hour))})
+	scheduler.Plan("second", []*plannedReminder{testReminder("a", now.Add(2*time.Hour))})
+	scheduler.Retain(map[string]bool{"second": true})
+
+	due := scheduler.popDue(now.Add(24 * time.Hour))
+
+	if len(due) != 1 || due[0].id != "second/a" {
+		t.Fatalf("popDue() = %v, want only second/a", due)
+	}
+
+	if _, err := outbox.GetEntry("first/a"); err != exceptions.NotFound {
+		t.Errorf("the outbox entry of the removed owner is kept")
+	}
+}
+
+func TestReminderSchedulerCatchUp(t *testing.T) {
+	now := time.Now()
+	past := testReminder("a", now.Add(-time.Hour))
+
+	pendingEntry := dao.OutboxEntryModel{Id: "owner/a", State: util.OutboxStatePending}
+	sentEntry := dao.OutboxEntryModel{Id: "owner/a", State: util.OutboxStateSent}
+
+	tests := []struct {
+		name        string
+		entries     []dao.OutboxEntryModel
+		reminder    *plannedReminder
+		result      func(done func(err error)) // nil leaves the summary postponed
+		wantSummary bool
+		wantState   util.OutboxState // 0 when the entry is not expected
+	}{
+		{
+			name:     "first start owes nothing",
+			reminder: past,
+		},
+		{
+			name:        "pending entry is missed",
+			entries:     []dao.OutboxEntryModel{pendingEntry},
+			reminder:    past,
+			result:      func(done func(err error)) { done(nil) },
+			wantSummary: true,
+			wantState:   util.OutboxStateSent,
+		},
+		{
+			name:      "delivered entry is not repeated",
+			entries:   []dao.OutboxEntryModel{sentEntry},
+			reminder:  past,
+			wantState: util.OutboxStateSent,
+		},
+		{
+			name:        "postponed summary stays pending",
+			entries:     []dao.OutboxEntryModel{pendingEntry},
+			reminder:    past,
+			wantSummary: true,
+			wantState:   util.OutboxStatePending,
+		},
+		{
+			name:        "failed summary",
+			entries:     []dao.OutboxEntryModel{pendingEntry},
+			reminder:    past,
+			result:      func(done func(err error)) { done(exceptions.NotFound) },
+			wantSummary: true,
+			wantState:   util.OutboxStateFailed,
+		},
+		{
+			name:      "future reminder is planned",
+			reminder:  testReminder("a", now.Add(time.Hour)),
+			wantState: util.OutboxStatePending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newFakeOutbox(tt.entries...)
+			scheduler := newReminderScheduler(outbox)
+			reminder := *tt.reminder
+
+			scheduler.Plan("owner", []*plannedReminder{&reminder})
+
+			summary := false
+
+			scheduler.CatchUp(func(schedules []dto.ScheduleDto, recipient int64, done func(err error)) {
+				summary = true
+
+				if tt.result != nil {
+					tt.result(done)
+				}
+			})
+
+			if summary != tt.wantSummary {
+				t.Errorf("summary sent = %v, want %v", summary, tt.wantSummary)
+			}
+
+			entry, err := outbox.GetEntry("owner/a")
+
+			switch {
+			case tt.wantState == 0 && err == nil:
+				t.Errorf("unexpected outbox entry in the state %d", entry.State)
+			case tt.wantState != 0 && err != nil:
+				t.Errorf("outbox entry is missing, want the state %d", tt.wantState)
+			case tt.wantState != 0 && entry.State != tt.wantState:
+				t.Errorf("outbox state = %d, want %d", entry.State, tt.wantState)
+			}
+		})
+	}
+}
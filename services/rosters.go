@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/util"
+)
+
+// GetElectiveRoster groups the students by the chosen optional courses and lists the students
+// who have not chosen a course for some of the optional slots yet
+func (e ElectiveService) GetElectiveRoster(studentIds []int) (*dto.ElectiveRosterResponse, error) {
+	courses, err := e.courseProvider.GetCourses()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(courses, func(i, j int) bool {
+		return courses[i].Name < courses[j].Name
+	})
+
+	slots := e.getOptionalSlots()
+
+	var rosters []dto.RosterCourseDto
+
+	for _, course := range courses {
+		if !course.IsOptional {
+			continue
+		}
+
+		roster := dto.RosterCourseDto{CourseInfo: getCourseDto(e.courseProvider, course.Id)}
+		enrolled := map[int]struct{}{}
+
+		for _, slot := range slots {
+			var students []dto.StudentDto
+
+			for userId, courseId := range slot.OptCourseParams.UserIdToCourseId {
+				if courseId != course.Id {
+					continue
+				}
+
+				enrolled[userId] = struct{}{}
+				students = append(students, getStudentDto(e.userProvider, userId))
+			}
+
+			var waitlisted []dto.StudentDto
+
+			waitlist, err := e.provider.GetWaitlist(slot.Id, course.Id)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, userId := range waitlist {
+				waitlisted = append(waitlisted, getStudentDto(e.userProvider, userId))
+			}
+
+			if len(students) == 0 && len(waitlisted) == 0 {
+				continue
+			}
+
+			sortStudents(students)
+
+			roster.Slots = append(roster.Slots, dto.RosterSlotDto{
+				Slot:       convertToOptionalSlotDto(slot),
+				Students:   students,
+				Waitlisted: waitlisted,
+			})
+		}
+
+		roster.Count = len(enrolled)
+		rosters = append(rosters, roster)
+	}
+
+	return &dto.ElectiveRosterResponse{Courses: rosters, Pending: e.getPendingSelections(studentIds, slots)}, nil
+}
+
+func (e ElectiveService) getPendingSelections(studentIds []int, slots []dao.ScheduleModel) []dto.RosterPendingDto {
+	var pending []dto.RosterPendingDto
+
+	for _, userId := range studentIds {
+		var missing []dto.OptionalSlotDto
+
+		for _, slot := range slots {
+			if !e.isVisibleForStudent(slot, userId) {
+				continue
+			}
+
+			if _, exists := slot.OptCourseParams.UserIdToCourseId[userId]; !exists {
+				missing = append(missing, convertToOptionalSlotDto(slot))
+			}
+		}
+
+		if len(missing) > 0 {
+			pending = append(pending, dto.RosterPendingDto{Student: getStudentDto(e.userProvider, userId), MissingSlots: missing})
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Student.FullName < pending[j].Student.FullName
+	})
+
+	return pending
+}
+
+// ExportElectiveRosterCsv renders the roster as CSV, one line per student and optional slot
+func (e ElectiveService) ExportElectiveRosterCsv(studentIds []int) ([]byte, error) {
+	roster, err := e.GetElectiveRoster(studentIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+
+	records := [][]string{{"course", "weekday", "order", "week_order", "user_id", "username", "full_name"}}
+
+	for _, course := range roster.Courses {
+		for _, slot := range course.Slots {
+			for _, student := range slot.Students {
+				records = append(records, append([]string{course.CourseInfo.Name}, convertToRosterRecord(slot.Slot, student)...))
+			}
+		}
+	}
+
+	for _, val := range roster.Pending {
+		for _, slot := range val.MissingSlots {
+			records = append(records, append([]string{""}, convertToRosterRecord(slot, val.Student)...))
+		}
+	}
+
+	if err = writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (e ElectiveService) getOptionalSlots() []dao.ScheduleModel {
+	var slots []dao.ScheduleModel
+
+	for _, val := range e.scheduleProvider.GetCommonSchedule() {
+		for _, v := range val {
+			if v.IsOptional {
+				slots = append(slots, v)
+			}
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].Weekday != slots[j].Weekday {
+			return slots[i].Weekday < slots[j].Weekday
+		}
+		return slots[i].Order < slots[j].Order
+	})
+
+	return slots
+}
+
+func getStudentDto(userProvider abstractions.IUserProvider, userId int) dto.StudentDto {
+	user, err := userProvider.GetUserById(userId)
+
+	if err != nil {
+		return dto.StudentDto{UserId: userId, FullName: fmt.Sprintf("id %d", userId)}
+	}
+
+	return dto.StudentDto{
+		UserId:   userId,
+		UserName: user.UserName,
+		FullName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}
+}
+
+func convertToOptionalSlotDto(slot dao.ScheduleModel) dto.OptionalSlotDto {
+	return dto.OptionalSlotDto{
+		ScheduleId: slot.Id,
+		Weekday:    slot.Weekday,
+		WeekOrder:  slot.WeekOrder,
+		Order:      slot.Order,
+	}
+}
+
+func convertToRosterRecord(slot dto.OptionalSlotDto, student dto.StudentDto) []string {
+	return []string{
+		slot.Weekday.String(),
+		fmt.Sprintf("%d", slot.Order),
+		util.ConvertToHumanReadableWeekOrder(slot.WeekOrder),
+		fmt.Sprintf("%d", student.UserId),
+		student.UserName,
+		student.FullName,
+	}
+}
+
+func sortStudents(students []dto.StudentDto) {
+	sort.Slice(students, func(i, j int) bool {
+		return students[i].FullName < students[j].FullName
+	})
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"testing"
+)
+
+// newTestRosterService has Alice and Bob in Math and Bob in Art, Dave waits for Math
+func newTestRosterService() ElectiveService {
+	slots := newTestSlots()
+	slots.slots["s1"].OptCourseParams.UserIdToCourseId[10] = "math"
+	slots.slots["s1"].OptCourseParams.UserIdToCourseId[11] = "math"
+	slots.slots["s2"].OptCourseParams.UserIdToCourseId[11] = "art"
+
+	return newTestElectiveServiceWith(slots, slots, &fakeElectiveProvider{waitlists: map[string]map[string][]int{
+		"s1": {"math": {13}},
+	}})
+}
+
+func TestGetElectiveRoster(t *testing.T) {
+	roster, err := newTestRosterService().GetElectiveRoster([]int{10, 11, 12, 13})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var courses []string
+
+	for _, course := range roster.Courses {
+		for _, slot := range course.Slots {
+			line := fmt.Sprintf("%s %d %s:", course.CourseInfo.Name, course.Count, slot.Slot.ScheduleId)
+
+			for _, student := range slot.Students {
+				line += " " + student.FullName
+			}
+
+			for _, student := range slot.Waitlisted {
+				line += " (" + student.FullName + ")"
+			}
+
+			courses = append(courses, line)
+		}
+	}
+
+	want := []string{"Art 1 s2: Bob", "Math 2 s1: Alice Bob (Dave)"}
+
+	if !equalStrings(courses, want) {
+		t.Errorf("courses = %v, want %v", courses, want)
+	}
+
+	// Carol does not see the slot of the subgroup "b", Bob has chosen everywhere
+	var pending []string
+
+	for _, val := range roster.Pending {
+		line := val.Student.FullName + ":"
+
+		for _, slot := range val.MissingSlots {
+			line += " " + slot.ScheduleId
+		}
+
+		pending = append(pending, line)
+	}
+
+	wantPending := []string{"Alice: s2", "Carol: s1", "Dave: s1 s2"}
+
+	if !equalStrings(pending, wantPending) {
+		t.Errorf("pending = %v, want %v", pending, wantPending)
+	}
+}
+
+func TestExportElectiveRosterCsv(t *testing.T) {
+	data, err := newTestRosterService().ExportElectiveRosterCsv([]int{10, 11, 12, 13})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+
+	if err != nil {
+		t.Fatalf("the export is not a valid csv: %s", err)
+	}
+
+	var lines []string
+
+	for _, record := range records {
+		// course, weekday, order and user_id
+		lines = append(lines, fmt.Sprintf("%s|%s|%s|%s", record[0], record[1], record[2], record[4]))
+	}
+
+	want := []string{
+		"course|weekday|order|user_id",
+		"Art|Tuesday|2|11",
+		"Math|Monday|1|10",
+		"Math|Monday|1|11",
+		"|Tuesday|2|10",
+		"|Monday|1|12",
+		"|Monday|1|13",
+		"|Tuesday|2|13",
+	}
+
+	if !equalStrings(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
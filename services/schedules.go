@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"github.com/sirupsen/logrus"
 	"sort"
 	"telegram-notification-bot-core/abstractions"
 	"telegram-notification-bot-core/configuration"
@@ -12,14 +13,44 @@ import (
 	"time"
 )
 
+const maxCancelRangeDays = 31 // a longer break is a change of the weekly schedule
+
+// ScheduleService reads the published schedule from provider, while the admin edits are
+// accumulated in draft until they are published
 type ScheduleService struct {
-	config         configuration.Configuration
-	provider       abstractions.IScheduleProvider
-	courseProvider abstractions.ICourseProvider
+	config           configuration.Configuration
+	provider         abstractions.IScheduleProvider
+	draft            abstractions.IScheduleProvider
+	courseProvider   abstractions.ICourseProvider
+	snapshotProvider abstractions.ISnapshotProvider
+	subgroupProvider abstractions.ISubgroupProvider
+	mutesProvider    abstractions.IPreferencesProvider
 }
 
-func NewScheduleService(config configuration.Configuration, provider abstractions.IScheduleProvider, courseProvider abstractions.ICourseProvider) *ScheduleService {
-	return &ScheduleService{config: config, provider: provider, courseProvider: courseProvider}
+func NewScheduleService(
+	config configuration.Configuration,
+	provider abstractions.IScheduleProvider,
+	draft abstractions.IScheduleProvider,
+	courseProvider abstractions.ICourseProvider,
+	snapshotProvider abstractions.ISnapshotProvider,
+	subgroupProvider abstractions.ISubgroupProvider,
+	mutesProvider abstractions.IPreferencesProvider) *ScheduleService {
+
+	if !draft.IsInitialized() {
+		if err := draft.Import(provider.Export()); err != nil {
+			logrus.Errorln("Failed to initialize a draft schedule: ", err)
+		}
+	}
+
+	return &ScheduleService{
+		config:           config,
+		provider:         provider,
+		draft:            draft,
+		courseProvider:   courseProvider,
+		snapshotProvider: snapshotProvider,
+		subgroupProvider: subgroupProvider,
+		mutesProvider:    mutesProvider,
+	}
 }
 
 func (s ScheduleService) CreateNewSchedule(request dto.CreateNewScheduleRequest) error {
@@ -29,7 +60,11 @@ func (s ScheduleService) CreateNewSchedule(request dto.CreateNewScheduleRequest)
 		return errors.New("InvalidOrder")
 	}
 
-	ok, err := s.provider.ValidateScheduleCreation(request.Weekday, request.Order, request.WeekOrder)
+	if err := s.validateSubgroup(request.Subgroup); err != nil {
+		return err
+	}
+
+	ok, err := s.draft.ValidateScheduleCreation(request.Weekday, request.Order, request.WeekOrder, request.Subgroup)
 
 	if err != nil {
 		return err
@@ -44,6 +79,7 @@ func (s ScheduleService) CreateNewSchedule(request dto.CreateNewScheduleRequest)
 		WeekOrder:  request.WeekOrder,
 		Order:      request.Order,
 		IsOptional: request.IsOptional,
+		Subgroup:   request.Subgroup,
 	}
 
 	if request.IsOptional {
@@ -52,13 +88,14 @@ func (s ScheduleService) CreateNewSchedule(request dto.CreateNewScheduleRequest)
 		daoModel.CourseId = request.CourseId
 	}
 
-	return s.provider.CreateNewSchedule(daoModel)
+	return s.draft.CreateNewSchedule(daoModel)
 }
 
 func (s ScheduleService) ClearSchedule() error {
-	return s.provider.DropAllSchedules()
+	return s.draft.DropAllSchedules()
 }
 
+// InsertAdditionalSchedule adds the replacement to the draft, the members are notified when it is published
 func (s ScheduleService) InsertAdditionalSchedule(request dto.CreateNewAdditionalScheduleRequest) error {
 	_, ok := s.config.ScheduleSettings.TimeSlotsConfiguration[request.Order]
 
@@ -66,44 +103,220 @@ func (s ScheduleService) InsertAdditionalSchedule(request dto.CreateNewAdditiona
 		return errors.New("InvalidOrder")
 	}
 
-	ok, err := s.provider.ValidateAddScheduleCreation(request.Date, request.Order)
+	if err := s.validateSubgroup(request.Subgroup); err != nil {
+		return err
+	}
+
+	ok, err := s.draft.ValidateAddScheduleCreation(request.Date, request.Order, request.Subgroup)
 
 	if err != nil {
 		return err
 	}
 
 	if !ok {
-		return err
+		return errors.New("AdditionalScheduleExists")
 	}
 
 	daoModel := dao.AdditionalScheduleModel{
 		AdditionalTime: request.Date,
 		Order:          request.Order,
 		IsEmpty:        request.IsEmpty,
+		Subgroup:       request.Subgroup,
 	}
 
 	if !request.IsEmpty {
 		daoModel.CourseId = request.CourseId
 	}
 
-	return s.provider.CreateNewAdditionalSchedule(daoModel)
+	return s.draft.CreateNewAdditionalSchedule(daoModel)
+}
+
+func (s ScheduleService) PreviewCancelRange(request dto.CancelRangeRequest) (*dto.CancelRangeResponse, error) {
+	lessons, _, err := s.collectLessonsForCancel(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CancelRangeResponse{Lessons: lessons}, nil
+}
+
+// CancelRange replaces every matched lesson in the range by an empty additional schedule in one write,
+// the cancellation is urgent, so it is applied to both the published schedule and the draft.
+// The published schedule is rolled back when the draft can not be written, so they stay the same
+func (s ScheduleService) CancelRange(request dto.CancelRangeRequest) (*dto.CancelRangeResponse, error) {
+	lessons, models, err := s.collectLessonsForCancel(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(models) == 0 {
+		return &dto.CancelRangeResponse{Lessons: lessons}, nil
+	}
+
+	backup := map[string][]dao.AdditionalScheduleModel{}
+
+	for _, model := range models {
+		backup[model.AdditionalTime.Format("2006-01-02")] = s.provider.GetAdditionalSchedulesByDate(model.AdditionalTime, "")
+	}
+
+	if err = s.provider.ReplaceAdditionalSchedules(models); err != nil {
+		return nil, err
+	}
+
+	if err = s.draft.ReplaceAdditionalSchedules(models); err != nil {
+		if restoreErr := s.provider.RestoreAdditionalSchedules(backup); restoreErr != nil {
+			logrus.Errorf("Failed to roll back the cancelled lessons: %s", restoreErr)
+		}
+
+		return nil, err
+	}
+
+	return &dto.CancelRangeResponse{Lessons: lessons}, nil
+}
+
+func (s ScheduleService) collectLessonsForCancel(request dto.CancelRangeRequest) ([]dto.CancelledLessonDto, []dao.AdditionalScheduleModel, error) {
+	if request.To.Before(request.From) {
+		return nil, nil, errors.New("InvalidDateRange")
+	}
+
+	if request.To.After(request.From.AddDate(0, 0, maxCancelRangeDays-1)) {
+		return nil, nil, errors.New("DateRangeTooLong")
+	}
+
+	if _, ok := s.config.ScheduleSettings.TimeSlotsConfiguration[request.Order]; request.Order != 0 && !ok {
+		return nil, nil, errors.New("InvalidOrder")
+	}
+
+	// an optional slot is shared by several courses, it can not be cancelled for one of them
+	if request.CourseId != "" {
+		course, err := s.courseProvider.GetCourseById(request.CourseId)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if course.IsOptional {
+			return nil, nil, exceptions.ElectiveNotCancellable
+		}
+	}
+
+	var lessons []dto.CancelledLessonDto
+	var models []dao.AdditionalScheduleModel
+
+	for date := request.From; !date.After(request.To); date = date.AddDate(0, 0, 1) {
+		schedule, err := s.provider.GetScheduleByDate(date, "")
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sort.Slice(schedule, func(i, j int) bool {
+			return schedule[i].Order < schedule[j].Order
+		})
+
+		for _, val := range schedule {
+			if request.Order != 0 && val.Order != request.Order {
+				continue
+			}
+
+			if request.CourseId != "" && (val.IsOptional || val.CourseId != request.CourseId) {
+				continue
+			}
+
+			courseInfo := &dao.CourseModel{Name: "Опціональний курс"}
+
+			if !val.IsOptional {
+				courseInfo, err = s.courseProvider.GetCourseById(val.CourseId)
+
+				if err != nil {
+					courseInfo = &dao.CourseModel{Id: val.CourseId}
+				}
+			}
+
+			lessons = append(lessons, dto.CancelledLessonDto{
+				Date:     date,
+				Order:    val.Order,
+				Subgroup: val.Subgroup,
+				CourseInfo: dto.CourseDto{
+					Name:           courseInfo.Name,
+					Id:             courseInfo.Id,
+					TeacherName:    courseInfo.TeacherName,
+					TeacherContact: courseInfo.TeacherContact,
+					MeetLink:       courseInfo.MeetLink,
+				},
+			})
+
+			models = append(models, dao.AdditionalScheduleModel{
+				AdditionalTime: date,
+				Order:          val.Order,
+				IsEmpty:        true,
+				Subgroup:       val.Subgroup,
+			})
+		}
+	}
+
+	return lessons, models, nil
 }
 
 func (s ScheduleService) GetCurrentSchedule(userId int) (*dto.GetScheduleResponse, error) {
-	currentTime := util.GetMidnightTime()
-	schedule, err := s.provider.GetScheduleByDate(currentTime)
+	return s.GetScheduleForDate(userId, util.GetMidnightTime())
+}
+
+// GetScheduleForDate returns the user's lessons of the date with the replacements marked,
+// the lessons of the weekly schedule cancelled for the date are returned separately
+func (s ScheduleService) GetScheduleForDate(userId int, date time.Time) (*dto.GetScheduleResponse, error) {
+	subgroup := s.subgroupProvider.GetUserSubgroup(userId)
+	schedule, err := s.provider.GetScheduleByDate(date, subgroup)
 
 	if err != nil {
 		return nil, err
 	}
 
-	result := s.enrichScheduleInfoByUserId(schedule, userId)
+	result := s.enrichScheduleInfoByUserId(schedule, userId, date)
+	additional := s.provider.GetAdditionalSchedulesByDate(date, subgroup)
+
+	for i, val := range result.Schedules {
+		for _, add := range additional {
+			if !add.IsEmpty && add.Order == val.Order && add.Subgroup == val.Subgroup {
+				result.Schedules[i].IsReplacement = true
+			}
+		}
+	}
+
+	var cancelled []dao.ScheduleModel
+	weekOrder := util.GetWeekOrderByDate(date)
+
+	for _, val := range s.provider.GetCommonSchedule()[date.Weekday()] {
+		if val.WeekOrder != weekOrder && val.WeekOrder > 0 || !util.IsVisibleForSubgroup(val.Subgroup, subgroup) {
+			continue
+		}
+
+		if isCancelled(val, additional, subgroup) {
+			cancelled = append(cancelled, val)
+		}
+	}
+
+	result.Cancelled = s.enrichScheduleInfoByUserId(cancelled, userId, date).Schedules
+	result.IsHoliday = s.isHoliday(date)
 
 	return &result, nil
 }
 
+func (s ScheduleService) isHoliday(date time.Time) bool {
+	for _, val := range s.config.ScheduleSettings.Holidays {
+		if val == date.Format("2006-01-02") {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s ScheduleService) GetCommonSchedule(userId int) (*dto.GetCommonScheduleResponse, error) {
 	result := s.provider.GetCommonSchedule()
+	subgroup := s.subgroupProvider.GetUserSubgroup(userId)
 
 	resultDto := dto.GetCommonScheduleResponse{
 		Schedules: map[time.Weekday]dto.CommonScheduleDto{},
@@ -117,6 +330,10 @@ func (s ScheduleService) GetCommonSchedule(userId int) (*dto.GetCommonScheduleRe
 		})
 
 		for _, v := range val {
+			if !util.IsVisibleForSubgroup(v.Subgroup, subgroup) {
+				continue
+			}
+
 			values := orderToSchedules[v.Order]
 
 			if values == nil {
@@ -155,6 +372,7 @@ func (s ScheduleService) GetCommonSchedule(userId int) (*dto.GetCommonScheduleRe
 					},
 					Order:     v.Order,
 					WeekOrder: v.WeekOrder,
+					Subgroup:  v.Subgroup,
 				})
 
 		}
@@ -165,29 +383,202 @@ func (s ScheduleService) GetCommonSchedule(userId int) (*dto.GetCommonScheduleRe
 	return &resultDto, nil
 }
 
-func (s ScheduleService) LinkOptionalCourseToUser(request dto.LinkOptionalCourseToUserRequest) error {
-	return s.provider.LinkCourseToUser(request.UserId, request.CourseId)
+// GetOptionalSlots returns optional lessons of the published weekly schedule with the user's choices
+func (s ScheduleService) GetOptionalSlots(userId int) (*dto.GetOptionalSlotsResponse, error) {
+	var slots []dto.OptionalSlotDto
+
+	subgroup := s.subgroupProvider.GetUserSubgroup(userId)
+
+	for weekday, val := range s.provider.GetCommonSchedule() {
+		for _, v := range val {
+			if !v.IsOptional || !util.IsVisibleForSubgroup(v.Subgroup, subgroup) {
+				continue
+			}
+
+			slot := dto.OptionalSlotDto{
+				ScheduleId: v.Id,
+				Weekday:    weekday,
+				WeekOrder:  v.WeekOrder,
+				Order:      v.Order,
+			}
+
+			if courseId, exists := v.OptCourseParams.UserIdToCourseId[userId]; exists {
+				course := getCourseDto(s.courseProvider, courseId)
+				slot.SelectedCourse = &course
+			}
+
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].Weekday != slots[j].Weekday {
+			return slots[i].Weekday < slots[j].Weekday
+		}
+		if slots[i].Order != slots[j].Order {
+			return slots[i].Order < slots[j].Order
+		}
+		return slots[i].WeekOrder < slots[j].WeekOrder
+	})
+
+	return &dto.GetOptionalSlotsResponse{Slots: slots}, nil
 }
 
-func (s ScheduleService) PrepareSchedulesListForNotify(userIds []int) (map[int][]dto.ScheduleDto, error) {
-	currentTime := util.GetMidnightTime()
-	schedule, err := s.provider.GetScheduleByDate(currentTime)
+// PrepareChatScheduleForNotify returns today's lessons of all subgroups for the chats and channels,
+// optional slots are not personalized
+func (s ScheduleService) PrepareChatScheduleForNotify() ([]dto.ScheduleDto, error) {
+	schedule, err := s.provider.GetScheduleByDate(util.GetMidnightTime(), "")
 
 	if err != nil {
 		return nil, err
 	}
 
+	var schedules []dto.ScheduleDto
+
+	for _, val := range schedule {
+		scheduleDto := dto.ScheduleDto{
+			Order:      val.Order,
+			WeekOrder:  val.WeekOrder,
+			Subgroup:   val.Subgroup,
+			CourseInfo: dto.CourseDto{Name: "Курс за вибором"},
+		}
+
+		if !val.IsOptional {
+			scheduleDto.CourseInfo = getCourseDto(s.courseProvider, val.CourseId)
+		}
+
+		schedules = append(schedules, scheduleDto)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Order < schedules[j].Order
+	})
+
+	return schedules, nil
+}
+
+// PrepareSchedulesListForNotify returns today's lessons of every user, students of a subgroup
+// get only the lessons of the whole group and of their subgroup, the muted courses and slots are left out
+func (s ScheduleService) PrepareSchedulesListForNotify(userIds []int) (map[int][]dto.ScheduleDto, error) {
+	currentTime := util.GetMidnightTime()
+	subgroupSchedules := map[string][]dao.ScheduleModel{}
 	resultMap := map[int][]dto.ScheduleDto{}
 
 	for _, userId := range userIds {
+		subgroup := s.subgroupProvider.GetUserSubgroup(userId)
+		schedule, exists := subgroupSchedules[subgroup]
+
+		if !exists {
+			var err error
+			schedule, err = s.provider.GetScheduleByDate(currentTime, subgroup)
 
-		resultMap[userId] = s.enrichScheduleInfoByUserId(schedule, userId).Schedules
+			if err != nil {
+				return nil, err
+			}
+
+			subgroupSchedules[subgroup] = schedule
+		}
+
+		resultMap[userId] = s.enrichScheduleInfoByUserId(s.filterMuted(schedule, userId), userId, currentTime).Schedules
 	}
 
 	return resultMap, nil
 }
 
-func (s ScheduleService) enrichScheduleInfoByUserId(schedule []dao.ScheduleModel, userId int) dto.GetScheduleResponse {
+// GetTeacherLessons returns the lessons of the teacher's courses in the date range, optional slots are included
+// when a student has chosen a course of the teacher there
+func (s ScheduleService) GetTeacherLessons(teacherId string, from time.Time, to time.Time) ([]dto.TeacherLessonDto, error) {
+	var lessons []dto.TeacherLessonDto
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		schedule, err := s.provider.GetScheduleByDate(date, "")
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, val := range schedule {
+			for _, courseId := range getLessonCourseIds(val) {
+				course := getCourseDto(s.courseProvider, courseId)
+
+				if course.TeacherId == "" || course.TeacherId != teacherId {
+					continue
+				}
+
+				lessons = append(lessons, dto.TeacherLessonDto{
+					Date:       date,
+					Order:      val.Order,
+					Subgroup:   val.Subgroup,
+					IsOptional: val.IsOptional,
+					CourseInfo: course,
+				})
+			}
+		}
+	}
+
+	sort.Slice(lessons, func(i, j int) bool {
+		if !lessons[i].Date.Equal(lessons[j].Date) {
+			return lessons[i].Date.Before(lessons[j].Date)
+		}
+		return lessons[i].Order < lessons[j].Order
+	})
+
+	return lessons, nil
+}
+
+// getLessonCourseIds returns the course of the lesson or the distinct courses chosen for the optional slot
+func getLessonCourseIds(lesson dao.ScheduleModel) []string {
+	if !lesson.IsOptional {
+		return []string{lesson.CourseId}
+	}
+
+	var courseIds []string
+	seen := map[string]struct{}{}
+
+	for _, courseId := range lesson.OptCourseParams.UserIdToCourseId {
+		if _, exists := seen[courseId]; !exists {
+			seen[courseId] = struct{}{}
+			courseIds = append(courseIds, courseId)
+		}
+	}
+
+	sort.Strings(courseIds)
+
+	return courseIds
+}
+
+// filterMuted leaves out the lessons the user muted, an elective is muted by its slot or by the chosen course
+func (s ScheduleService) filterMuted(schedule []dao.ScheduleModel, userId int) []dao.ScheduleModel {
+	preferences, err := s.mutesProvider.GetPreferences(userId)
+
+	if err != nil {
+		return schedule
+	}
+
+	var result []dao.ScheduleModel
+
+	for _, val := range schedule {
+		courseId := val.CourseId
+
+		if val.IsOptional {
+			if containsName(preferences.MutedSlotIds, val.Id) {
+				continue
+			}
+
+			courseId = val.OptCourseParams.UserIdToCourseId[userId]
+		}
+
+		if courseId != "" && containsName(preferences.MutedCourseIds, courseId) {
+			continue
+		}
+
+		result = append(result, val)
+	}
+
+	return result
+}
+
+func (s ScheduleService) enrichScheduleInfoByUserId(schedule []dao.ScheduleModel, userId int, date time.Time) dto.GetScheduleResponse {
 
 	var schedules []dto.ScheduleDto
 
@@ -215,6 +606,7 @@ func (s ScheduleService) enrichScheduleInfoByUserId(schedule []dao.ScheduleModel
 		scheduleDto := dto.ScheduleDto{
 			Order:     val.Order,
 			WeekOrder: val.WeekOrder,
+			Subgroup:  val.Subgroup,
 			CourseInfo: dto.CourseDto{
 				Name:           courseInfo.Name,
 				Id:             courseInfo.Id,
@@ -224,6 +616,10 @@ func (s ScheduleService) enrichScheduleInfoByUserId(schedule []dao.ScheduleModel
 			},
 		}
 
+		if val.IsOptional {
+			scheduleDto.ScheduleId = val.Id
+		}
+
 		schedules = append(schedules, scheduleDto)
 	}
 
@@ -232,8 +628,8 @@ func (s ScheduleService) enrichScheduleInfoByUserId(schedule []dao.ScheduleModel
 	})
 
 	return dto.GetScheduleResponse{
-		CurrentDate:      util.GetMidnightTime(),
-		CurrentWeekOrder: util.GetCurrentWeekOrder(),
+		CurrentDate:      date,
+		CurrentWeekOrder: util.GetWeekOrderByDate(date),
 		Schedules:        schedules,
 	}
 }
@@ -261,3 +657,41 @@ func (s ScheduleService) GetSchedulesIdsWithOptionalCourse() ([]string, error) {
 
 	return result, nil
 }
+
+func (s ScheduleService) validateSubgroup(subgroup string) error {
+	if subgroup == "" {
+		return nil
+	}
+
+	model, err := s.subgroupProvider.GetSubgroups()
+
+	if err != nil {
+		return err
+	}
+
+	if !containsName(model.Names, subgroup) {
+		return errors.New("InvalidSubgroup")
+	}
+
+	return nil
+}
+
+// isCancelled reports whether the lesson is hidden by the cancellations of its order and is not replaced,
+// a replacement or a cancellation of the whole group hides the lessons of every subgroup
+func isCancelled(lesson dao.ScheduleModel, additional []dao.AdditionalScheduleModel, subgroup string) bool {
+	hidden := false
+
+	for _, val := range additional {
+		if val.Order != lesson.Order || subgroup == "" && val.Subgroup != "" && val.Subgroup != lesson.Subgroup {
+			continue
+		}
+
+		if !val.IsEmpty {
+			return false
+		}
+
+		hidden = true
+	}
+
+	return hidden
+}
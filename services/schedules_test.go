@@ -0,0 +1,449 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"telegram-notification-bot-core/configuration"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"testing"
+	"time"
+)
+
+func (f *fakeScheduleProvider) GetScheduleByDate(date time.Time, subgroup string) ([]dao.ScheduleModel, error) {
+	var result []dao.ScheduleModel
+
+	for _, slot := range f.slots {
+		if slot.Weekday == date.Weekday() {
+			result = append(result, *slot)
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeScheduleProvider) GetAdditionalSchedulesByDate(date time.Time, subgroup string) []dao.AdditionalScheduleModel {
+	return f.additionals[date.Format("2006-01-02")]
+}
+
+func (f *fakeScheduleProvider) ReplaceAdditionalSchedules(models []dao.AdditionalScheduleModel) error {
+	if f.replaceErr != nil {
+		return f.replaceErr
+	}
+
+	for _, model := range models {
+		date := model.AdditionalTime.Format("2006-01-02")
+		f.additionals[date] = append(f.additionals[date], model)
+	}
+
+	return nil
+}
+
+func (f *fakeScheduleProvider) RestoreAdditionalSchedules(additionals map[string][]dao.AdditionalScheduleModel) error {
+	for date, models := range additionals {
+		f.additionals[date] = models
+	}
+
+	return nil
+}
+
+// newTestCancelSchedule has Math in the first and History in the second lesson on Mondays,
+// and the optional slot in the first lesson on Tuesdays
+func newTestCancelSchedule() *fakeScheduleProvider {
+	return &fakeScheduleProvider{
+		slots: map[string]*dao.ScheduleModel{
+			"m1": {Id: "m1", Weekday: time.Monday, Order: 1, CourseId: "math"},
+			"m2": {Id: "m2", Weekday: time.Monday, Order: 2, CourseId: "history"},
+			"t1": {Id: "t1", Weekday: time.Tuesday, Order: 1, IsOptional: true},
+		},
+		additionals: map[string][]dao.AdditionalScheduleModel{},
+	}
+}
+
+func newTestCancelService(t *testing.T, provider *fakeScheduleProvider, draft *fakeScheduleProvider) ScheduleService {
+	var config configuration.Configuration
+
+	err := yaml.Unmarshal([]byte(`
+schedule-settings:
+  time-slots-configuration:
+    1: {start-time: 8h, end-time: 9h}
+    2: {start-time: 10h, end-time: 11h}
+`), &config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ScheduleService{
+		config:   config,
+		provider: provider,
+		draft:    draft,
+		courseProvider: &fakeCourseProvider{courses: []dao.CourseModel{
+			{Id: "math", Name: "Math"},
+			{Id: "history", Name: "History"},
+			{Id: "art", Name: "Art", IsOptional: true},
+		}},
+	}
+}
+
+func TestPreviewCancelRange(t *testing.T) {
+	// 2024-01-01 is a Monday
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name    string
+		request dto.CancelRangeRequest
+		lessons []string
+		err     error
+	}{
+		{
+			name:    "every lesson",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, 1)},
+			lessons: []string{"2024-01-01 1", "2024-01-01 2", "2024-01-02 1"},
+		},
+		{
+			name:    "order filter",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, 7), Order: 1},
+			lessons: []string{"2024-01-01 1", "2024-01-02 1", "2024-01-08 1"},
+		},
+		{
+			name:    "course filter",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, 7), CourseId: "history"},
+			lessons: []string{"2024-01-01 2", "2024-01-08 2"},
+		},
+		{
+			name:    "optional course",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, 1), CourseId: "art"},
+			err:     exceptions.ElectiveNotCancellable,
+		},
+		{
+			name:    "unknown order",
+			request: dto.CancelRangeRequest{From: monday, To: monday, Order: 3},
+			err:     errors.New("InvalidOrder"),
+		},
+		{
+			name:    "longest range",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, maxCancelRangeDays-1), CourseId: "math"},
+			lessons: []string{"2024-01-01 1", "2024-01-08 1", "2024-01-15 1", "2024-01-22 1", "2024-01-29 1"},
+		},
+		{
+			name:    "too long range",
+			request: dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, maxCancelRangeDays)},
+			err:     errors.New("DateRangeTooLong"),
+		},
+		{
+			name:    "reversed range",
+			request: dto.CancelRangeRequest{From: monday.AddDate(0, 0, 1), To: monday},
+			err:     errors.New("InvalidDateRange"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := newTestCancelSchedule()
+			service := newTestCancelService(t, provider, provider)
+
+			response, err := service.PreviewCancelRange(c.request)
+
+			if c.err != nil {
+				if err == nil || err.Error() != c.err.Error() {
+					t.Fatalf("expected the error %v, got %v", c.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var lessons []string
+
+			for _, lesson := range response.Lessons {
+				lessons = append(lessons, fmt.Sprintf("%s %d", lesson.Date.Format("2006-01-02"), lesson.Order))
+			}
+
+			if !equalStrings(lessons, c.lessons) {
+				t.Errorf("expected %v, got %v", c.lessons, lessons)
+			}
+		})
+	}
+}
+
+func TestCancelRangeRollback(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	request := dto.CancelRangeRequest{From: monday, To: monday.AddDate(0, 0, 7), Order: 2}
+
+	existing := dao.AdditionalScheduleModel{Id: "a1", AdditionalTime: monday, Order: 1, CourseId: "history"}
+
+	provider := newTestCancelSchedule()
+	provider.additionals["2024-01-01"] = []dao.AdditionalScheduleModel{existing}
+
+	draft := newTestCancelSchedule()
+	draft.replaceErr = errors.New("WriteFailed")
+
+	service := newTestCancelService(t, provider, draft)
+
+	if _, err := service.CancelRange(request); err == nil || err.Error() != "WriteFailed" {
+		t.Fatalf("expected the error of the draft, got %v", err)
+	}
+
+	if additionals := provider.additionals["2024-01-01"]; len(additionals) != 1 || additionals[0] != existing {
+		t.Errorf("expected the replacement of the published schedule to be restored, got %v", additionals)
+	}
+
+	if additionals := provider.additionals["2024-01-08"]; len(additionals) != 0 {
+		t.Errorf("expected no cancellation in the published schedule, got %v", additionals)
+	}
+
+	draft.replaceErr = nil
+
+	response, err := service.CancelRange(request)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Lessons) != 2 || len(provider.additionals["2024-01-08"]) != 1 || len(draft.additionals["2024-01-08"]) != 1 {
+		t.Errorf("expected both lessons to be cancelled in the schedule and the draft, got %v", response.Lessons)
+	}
+}
+
+func TestGetOptionalSlots(t *testing.T) {
+	provider := newTestSlots()
+	provider.slots["s1"].OptCourseParams.UserIdToCourseId[11] = "math"
+	provider.slots["s3"] = &dao.ScheduleModel{Id: "s3", Weekday: time.Monday, Order: 2, IsOptional: true, Subgroup: "a"}
+	provider.slots["m1"] = &dao.ScheduleModel{Id: "m1", Weekday: time.Monday, Order: 3, CourseId: "history"}
+
+	service := newTestCancelService(t, provider, provider)
+	service.courseProvider = &fakeCourseProvider{courses: []dao.CourseModel{{Id: "math", Name: "Math", IsOptional: true}}}
+	service.subgroupProvider = &fakeSubgroupProvider{userSubgroups: map[int]string{11: "b"}}
+
+	cases := []struct {
+		name   string
+		userId int
+		slots  []string
+	}{
+		{name: "student of the subgroup", userId: 11, slots: []string{"s1 Math", "s2 -"}},
+		{name: "student without a subgroup", userId: 10, slots: []string{"s1 -", "s3 -", "s2 -"}},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.GetOptionalSlots(tt.userId)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var slots []string
+
+			for _, slot := range response.Slots {
+				selected := "-"
+
+				if slot.SelectedCourse != nil {
+					selected = slot.SelectedCourse.Name
+				}
+
+				slots = append(slots, slot.ScheduleId+" "+selected)
+			}
+
+			if !equalStrings(slots, tt.slots) {
+				t.Errorf("slots = %v, want %v", slots, tt.slots)
+			}
+		})
+	}
+}
+
+func TestPrepareChatScheduleForNotify(t *testing.T) {
+	today := time.Now().Weekday()
+
+	provider := &fakeScheduleProvider{slots: map[string]*dao.ScheduleModel{
+		"a": {Id: "a", Weekday: today, Order: 2, CourseId: "history", Subgroup: "b"},
+		"b": {Id: "b", Weekday: today, Order: 1, CourseId: "math"},
+		"c": {Id: "c", Weekday: today, Order: 3, IsOptional: true,
+			OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{10: "art"}}},
+		"d": {Id: "d", Weekday: (today + 1) % 7, Order: 1, CourseId: "math"},
+	}}
+
+	schedules, err := newTestCancelService(t, provider, provider).PrepareChatScheduleForNotify()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var lessons []string
+
+	for _, val := range schedules {
+		lessons = append(lessons, fmt.Sprintf("%d %s %s", val.Order, val.CourseInfo.Name, val.Subgroup))
+	}
+
+	// the chats get the lessons of every subgroup and the optional lessons are not personalized
+	want := []string{"1 Math ", "2 History b", "3 Курс за вибором "}
+
+	if !equalStrings(lessons, want) {
+		t.Errorf("lessons = %q, want %q", lessons, want)
+	}
+}
+
+// fakeDatedScheduleProvider resolves the lessons of a date like the storage does, the week order is
+// respected and the replacements of the date take the place of the weekly lessons
+type fakeDatedScheduleProvider struct {
+	*fakeScheduleProvider
+}
+
+func (f fakeDatedScheduleProvider) GetScheduleByDate(date time.Time, subgroup string) ([]dao.ScheduleModel, error) {
+	additionals := f.additionals[date.Format("2006-01-02")]
+	var result []dao.ScheduleModel
+
+	for _, slot := range f.slots {
+		if slot.Weekday != date.Weekday() || slot.WeekOrder > 0 && slot.WeekOrder != util.GetWeekOrderByDate(date) {
+			continue
+		}
+
+		replaced := false
+
+		for _, add := range additionals {
+			replaced = replaced || add.Order == slot.Order
+		}
+
+		if !replaced {
+			result = append(result, *slot)
+		}
+	}
+
+	for _, add := range additionals {
+		if !add.IsEmpty {
+			result = append(result, dao.ScheduleModel{Weekday: date.Weekday(), Order: add.Order, CourseId: add.CourseId, Subgroup: add.Subgroup})
+		}
+	}
+
+	return result, nil
+}
+
+func TestGetScheduleForDate(t *testing.T) {
+	// 2024-01-01 is a Monday of the upper week and 2024-01-08 is a Monday of the lower one
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	provider := newTestCancelSchedule()
+	provider.slots["t1"].OptCourseParams.UserIdToCourseId = map[int]string{10: "art"}
+	provider.slots["m3"] = &dao.ScheduleModel{Id: "m3", Weekday: time.Monday, Order: 3, CourseId: "math", WeekOrder: util.WeekOrderUpper}
+	provider.slots["m4"] = &dao.ScheduleModel{Id: "m4", Weekday: time.Monday, Order: 3, CourseId: "history", WeekOrder: util.WeekOrderDown}
+	provider.additionals["2024-01-01"] = []dao.AdditionalScheduleModel{
+		{AdditionalTime: monday, Order: 1, CourseId: "history"},
+		{AdditionalTime: monday, Order: 2, IsEmpty: true},
+	}
+
+	service := newTestCancelService(t, provider, provider)
+	service.provider = fakeDatedScheduleProvider{provider}
+	service.subgroupProvider = &fakeSubgroupProvider{}
+	service.config.ScheduleSettings.Holidays = []string{"2024-01-08"}
+
+	tests := []struct {
+		name      string
+		userId    int
+		date      time.Time
+		weekOrder util.WeekOrder
+		lessons   []string
+		cancelled []string
+		holiday   bool
+	}{
+		{
+			name: "replaced and cancelled lessons", userId: 10, date: monday, weekOrder: util.WeekOrderUpper,
+			lessons: []string{"1 History replacement", "3 Math"}, cancelled: []string{"2 History"},
+		},
+		{
+			name: "lower week on a holiday", userId: 10, date: monday.AddDate(0, 0, 7), weekOrder: util.WeekOrderDown,
+			lessons: []string{"1 Math", "2 History", "3 History"}, holiday: true,
+		},
+		{
+			name: "chosen optional course", userId: 10, date: monday.AddDate(0, 0, 1), weekOrder: util.WeekOrderUpper,
+			lessons: []string{"1 Art"},
+		},
+		{
+			name: "optional course not chosen", userId: 11, date: monday.AddDate(0, 0, 1), weekOrder: util.WeekOrderUpper,
+			lessons: []string{"1 Не обрано опціональний курс"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := service.GetScheduleForDate(tt.userId, tt.date)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var lessons, cancelled []string
+
+			for _, val := range schedule.Schedules {
+				line := fmt.Sprintf("%d %s", val.Order, val.CourseInfo.Name)
+
+				if val.IsReplacement {
+					line += " replacement"
+				}
+
+				lessons = append(lessons, line)
+			}
+
+			for _, val := range schedule.Cancelled {
+				cancelled = append(cancelled, fmt.Sprintf("%d %s", val.Order, val.CourseInfo.Name))
+			}
+
+			if !equalStrings(lessons, tt.lessons) || !equalStrings(cancelled, tt.cancelled) {
+				t.Errorf("lessons = %v, cancelled = %v, want %v and %v", lessons, cancelled, tt.lessons, tt.cancelled)
+			}
+
+			if schedule.CurrentWeekOrder != tt.weekOrder || schedule.IsHoliday != tt.holiday {
+				t.Errorf("week order = %d, holiday = %t, want %d and %t", schedule.CurrentWeekOrder, schedule.IsHoliday, tt.weekOrder, tt.holiday)
+			}
+		})
+	}
+}
+
+func TestPrepareSchedulesListForNotifyMuted(t *testing.T) {
+	today := time.Now().Weekday()
+
+	provider := &fakeScheduleProvider{slots: map[string]*dao.ScheduleModel{
+		"a": {Id: "a", Weekday: today, Order: 1, CourseId: "math"},
+		"b": {Id: "b", Weekday: today, Order: 2, CourseId: "history"},
+		"c": {Id: "c", Weekday: today, Order: 3, IsOptional: true,
+			OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{10: "art", 11: "art"}}},
+	}}
+
+	service := newTestCancelService(t, provider, provider)
+	service.subgroupProvider = &fakeSubgroupProvider{}
+	service.mutesProvider = &fakePreferencesProvider{models: map[int]dao.PreferencesModel{
+		10: {UserId: 10, MutedCourseIds: []string{"art"}},
+		11: {UserId: 11, MutedCourseIds: []string{"history"}, MutedSlotIds: []string{"c"}},
+		12: {UserId: 12, MutedCourseIds: []string{"art"}},
+	}}
+
+	schedules, err := service.PrepareSchedulesListForNotify([]int{10, 11, 12, 13})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[int][]string{
+		10: {"1 Math", "2 History"},
+		11: {"1 Math"},
+		// the muted course is not chosen in the slot
+		12: {"1 Math", "2 History", "3 Не обрано опціональний курс"},
+		13: {"1 Math", "2 History", "3 Не обрано опціональний курс"},
+	}
+
+	for userId, lessons := range want {
+		var got []string
+
+		for _, val := range schedules[userId] {
+			got = append(got, fmt.Sprintf("%d %s", val.Order, val.CourseInfo.Name))
+		}
+
+		if !equalStrings(got, lessons) {
+			t.Errorf("lessons of %d = %v, want %v", userId, got, lessons)
+		}
+	}
+}
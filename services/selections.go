@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"time"
+)
+
+// OpenSelectionWindow lets students choose optional courses until the deadline
+func (e ElectiveService) OpenSelectionWindow(request dto.OpenSelectionWindowRequest) error {
+	if !request.Deadline.After(time.Now()) {
+		return errors.New("InvalidDeadline")
+	}
+
+	return e.provider.SaveSelectionWindow(dao.ElectiveSelectionWindowModel{Deadline: request.Deadline})
+}
+
+func (e ElectiveService) GetSelectionWindow() (*dto.SelectionWindowDto, error) {
+	window, err := e.provider.GetSelectionWindow()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.SelectionWindowDto{
+		Deadline: window.Deadline,
+		IsLocked: !time.Now().Before(window.Deadline),
+	}, nil
+}
+
+// PrepareSelectionReminders returns the students who have not chosen a course for some optional slots
+// while the selection window is open, the reminders are repeated with the configured interval
+func (e ElectiveService) PrepareSelectionReminders(studentIds []int) (*dto.SelectionRemindersResponse, error) {
+	window, err := e.provider.GetSelectionWindow()
+
+	if err == exceptions.NotFound {
+		return &dto.SelectionRemindersResponse{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	actualTime := time.Now()
+
+	if !actualTime.Before(window.Deadline) || actualTime.Before(window.LastReminderAt.Add(e.config.ElectiveSettings.SelectionReminderInterval)) {
+		return &dto.SelectionRemindersResponse{Deadline: window.Deadline}, nil
+	}
+
+	window.LastReminderAt = actualTime
+
+	if err = e.provider.SaveSelectionWindow(*window); err != nil {
+		return nil, err
+	}
+
+	return &dto.SelectionRemindersResponse{
+		Deadline: window.Deadline,
+		Pending:  e.getPendingSelections(studentIds, e.getOptionalSlots()),
+	}, nil
+}
+
+// AutoAssignElectives links the students who have not chosen a course to the allowed course
+// with the most free seats, students are left unassigned when every allowed course is full
+func (e ElectiveService) AutoAssignElectives(studentIds []int) (*dto.AutoAssignElectivesResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	response := dto.AutoAssignElectivesResponse{}
+	stage := e.newStage()
+
+	for _, pending := range e.getPendingSelections(studentIds, e.getOptionalSlots()) {
+		unassigned := dto.RosterPendingDto{Student: pending.Student}
+
+		for _, slotDto := range pending.MissingSlots {
+			slot, err := stage.slot(slotDto.ScheduleId)
+
+			if err != nil {
+				return nil, err
+			}
+
+			courseId, err := e.findFreeCourse(*slot)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if courseId == "" {
+				unassigned.MissingSlots = append(unassigned.MissingSlots, slotDto)
+				continue
+			}
+
+			if err = stage.link(pending.Student.UserId, slotDto.ScheduleId, courseId); err != nil {
+				return nil, err
+			}
+
+			response.Assigned = append(response.Assigned, dto.AssignedStudentDto{
+				Student:    pending.Student,
+				Slot:       slotDto,
+				CourseInfo: getCourseDto(e.courseProvider, courseId),
+			})
+		}
+
+		if len(unassigned.MissingSlots) > 0 {
+			response.Unassigned = append(response.Unassigned, unassigned)
+		}
+	}
+
+	if err := stage.save(); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// findFreeCourse returns the allowed course of the slot with the most free seats,
+// courses without a capacity limit are chosen last to keep the limited courses filled
+func (e ElectiveService) findFreeCourse(slot dao.ScheduleModel) (string, error) {
+	allowed, err := e.GetAllowedCourses(slot.Id)
+
+	if err != nil {
+		return "", err
+	}
+
+	result, resultFree := "", 0
+
+	for _, course := range allowed.Courses {
+		if course.Capacity == 0 {
+			if result == "" {
+				result = course.Id
+			}
+			continue
+		}
+
+		free := course.Capacity - countEnrolled(slot, course.Id)
+
+		if free > resultFree {
+			result, resultFree = course.Id, free
+		}
+	}
+
+	return result, nil
+}
+
+func (e ElectiveService) isSelectionLocked() bool {
+	window, err := e.GetSelectionWindow()
+
+	return err == nil && window.IsLocked
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"testing"
+	"time"
+)
+
+func (f *fakeElectiveProvider) GetSelectionWindow() (*dao.ElectiveSelectionWindowModel, error) {
+	if f.window == nil {
+		return nil, exceptions.NotFound
+	}
+
+	window := *f.window
+	return &window, nil
+}
+
+func (f *fakeElectiveProvider) SaveSelectionWindow(model dao.ElectiveSelectionWindowModel) error {
+	f.window = &model
+	return nil
+}
+
+func TestOpenSelectionWindow(t *testing.T) {
+	electives := &fakeElectiveProvider{waitlists: map[string]map[string][]int{}}
+	service := newTestElectiveServiceWith(newTestSlots(), newTestSlots(), electives)
+
+	if err := service.OpenSelectionWindow(dto.OpenSelectionWindowRequest{Deadline: time.Now().Add(-time.Minute)}); err == nil || err.Error() != "InvalidDeadline" {
+		t.Errorf("err = %v, want InvalidDeadline", err)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+
+	if err := service.OpenSelectionWindow(dto.OpenSelectionWindowRequest{Deadline: deadline}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	window, err := service.GetSelectionWindow()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !window.Deadline.Equal(deadline) || window.IsLocked {
+		t.Errorf("window = %+v, want the open window until %s", window, deadline)
+	}
+}
+
+func TestPrepareSelectionReminders(t *testing.T) {
+	actualTime := time.Now()
+
+	cases := []struct {
+		name     string
+		window   *dao.ElectiveSelectionWindowModel
+		pending  []string
+		reminded bool
+	}{
+		{name: "no window"},
+		{
+			name:   "window closed",
+			window: &dao.ElectiveSelectionWindowModel{Deadline: actualTime.Add(-time.Hour)},
+		},
+		{
+			name:   "reminded recently",
+			window: &dao.ElectiveSelectionWindowModel{Deadline: actualTime.Add(time.Hour), LastReminderAt: actualTime.Add(-time.Minute)},
+		},
+		{
+			name:     "reminder due",
+			window:   &dao.ElectiveSelectionWindowModel{Deadline: actualTime.Add(time.Hour), LastReminderAt: actualTime.Add(-2 * time.Hour)},
+			pending:  []string{"Bob: s2", "Carol: s1"},
+			reminded: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := newTestSlots()
+			slots.slots["s1"].OptCourseParams.UserIdToCourseId[11] = "math"
+
+			electives := &fakeElectiveProvider{waitlists: map[string]map[string][]int{}, window: tt.window}
+			service := newTestElectiveServiceWith(slots, slots, electives)
+			service.config.ElectiveSettings.SelectionReminderInterval = time.Hour
+
+			response, err := service.PrepareSelectionReminders([]int{11, 12})
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var pending []string
+
+			for _, val := range response.Pending {
+				line := val.Student.FullName + ":"
+
+				for _, slot := range val.MissingSlots {
+					line += " " + slot.ScheduleId
+				}
+
+				pending = append(pending, line)
+			}
+
+			if !equalStrings(pending, tt.pending) {
+				t.Errorf("pending = %v, want %v", pending, tt.pending)
+			}
+
+			if reminded := tt.window != nil && electives.window.LastReminderAt.After(actualTime); reminded != tt.reminded {
+				t.Errorf("reminded = %t, want %t", reminded, tt.reminded)
+			}
+		})
+	}
+}
+
+func TestLinkOptionalCourseToUserLocked(t *testing.T) {
+	electives := &fakeElectiveProvider{
+		waitlists: map[string]map[string][]int{},
+		window:    &dao.ElectiveSelectionWindowModel{Deadline: time.Now().Add(-time.Minute)},
+	}
+	service := newTestElectiveServiceWith(newTestSlots(), newTestSlots(), electives)
+
+	request := dto.LinkOptionalCourseToUserRequest{UserId: 10, ScheduleId: "s1", CourseId: "math"}
+
+	if _, err := service.LinkOptionalCourseToUser(request); err != exceptions.SelectionLocked {
+		t.Errorf("err = %v, want %v", err, exceptions.SelectionLocked)
+	}
+
+	// the admins link the students after the deadline
+	request.IgnoreDeadline = true
+
+	if _, err := service.LinkOptionalCourseToUser(request); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestAutoAssignElectivesCapacity(t *testing.T) {
+	service, slots, _ := newTestWaitlistService(map[string]map[int]string{"s1": {10: "math"}}, nil)
+
+	response, err := service.AutoAssignElectives([]int{10, 11, 12, 13, 14})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var assigned []string
+
+	for _, val := range response.Assigned {
+		assigned = append(assigned, fmt.Sprintf("%d/%s/%s", val.Student.UserId, val.Slot.ScheduleId, val.CourseInfo.Id))
+	}
+
+	// the course with the most free seats is taken first, the ties go to the first course by name
+	want := []string{"10/s3/music", "11/s1/music", "11/s3/art", "12/s1/art", "12/s3/math", "13/s1/music", "13/s3/music"}
+
+	if !equalStrings(assigned, want) {
+		t.Errorf("assigned = %v, want %v", assigned, want)
+	}
+
+	if len(response.Unassigned) != 1 || response.Unassigned[0].Student.UserId != 14 || len(response.Unassigned[0].MissingSlots) != 2 {
+		t.Errorf("unassigned = %+v, want the student 14 in both slots", response.Unassigned)
+	}
+
+	if courseId := slots.slots["s3"].OptCourseParams.UserIdToCourseId[13]; courseId != "music" {
+		t.Errorf("the student 13 is linked to %q in s3, want music", courseId)
+	}
+}
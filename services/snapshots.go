@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"time"
+)
+
+// SaveScheduleSnapshot stores the current weekly schedule together with the course set,
+// selections of optional courses are not a part of a snapshot
+func (s ScheduleService) SaveScheduleSnapshot(request dto.CreateScheduleSnapshotRequest) (string, error) {
+	if strings.TrimSpace(request.Name) == "" {
+		return "", errors.New("InvalidName")
+	}
+
+	courses, err := s.courseProvider.GetCourses()
+
+	if err != nil {
+		return "", err
+	}
+
+	return s.snapshotProvider.CreateNewSnapshot(dao.ScheduleSnapshotModel{
+		Name:      request.Name,
+		CreatedAt: time.Now(),
+		Schedules: copyCommonSchedule(s.provider.GetCommonSchedule()),
+		Courses:   courses,
+	})
+}
+
+func (s ScheduleService) GetScheduleSnapshots() (*dto.GetScheduleSnapshotsResponse, error) {
+	snapshots, err := s.snapshotProvider.GetSnapshots()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotsDto []dto.ScheduleSnapshotDto
+
+	for _, snapshot := range snapshots {
+		schedulesCount := 0
+
+		for _, val := range snapshot.Schedules {
+			schedulesCount += len(val)
+		}
+
+		snapshotsDto = append(snapshotsDto, dto.ScheduleSnapshotDto{
+			Id:             snapshot.Id,
+			Name:           snapshot.Name,
+			CreatedAt:      snapshot.CreatedAt,
+			SchedulesCount: schedulesCount,
+			CoursesCount:   len(snapshot.Courses),
+		})
+	}
+
+	sort.Slice(snapshotsDto, func(i, j int) bool {
+		return snapshotsDto[i].CreatedAt.After(snapshotsDto[j].CreatedAt)
+	})
+
+	return &dto.GetScheduleSnapshotsResponse{Snapshots: snapshotsDto}, nil
+}
+
+// CloneScheduleSnapshot starts a new term from the snapshot: the weekly schedule of the draft is replaced,
+// replacements and optional selections start empty. Only the courses deleted since the snapshot are restored,
+// the courses in use keep their current teacher and links, so the published schedule is not changed
+func (s ScheduleService) CloneScheduleSnapshot(request dto.CloneScheduleSnapshotRequest) error {
+	snapshot, err := s.snapshotProvider.GetSnapshotById(request.SnapshotId)
+
+	if err != nil {
+		return err
+	}
+
+	var missing []dao.CourseModel
+
+	for _, course := range snapshot.Courses {
+		if _, err = s.courseProvider.GetCourseById(course.Id); err != nil {
+			missing = append(missing, course)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err = s.courseProvider.UpsertCourses(missing); err != nil {
+			return err
+		}
+	}
+
+	return s.draft.ReplaceCommonSchedule(copyCommonSchedule(snapshot.Schedules))
+}
+
+func (s ScheduleService) DeleteScheduleSnapshot(request dto.DeleteScheduleSnapshotRequest) error {
+	return s.snapshotProvider.DeleteSnapshot(request.SnapshotId)
+}
+
+// copyCommonSchedule makes a deep copy of the weekly schedule without selections of optional courses
+func copyCommonSchedule(schedules map[time.Weekday][]dao.ScheduleModel) map[time.Weekday][]dao.ScheduleModel {
+	result := map[time.Weekday][]dao.ScheduleModel{}
+
+	for weekday, val := range schedules {
+		result[weekday] = []dao.ScheduleModel{}
+
+		for _, v := range val {
+			if v.IsOptional {
+				v.OptCourseParams = dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{}}
+			}
+
+			result[weekday] = append(result[weekday], v)
+		}
+	}
+
+	return result
+}
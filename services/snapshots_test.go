@@ -0,0 +1,88 @@
+package services
+
+import (
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"testing"
+	"time"
+)
+
+func (f *fakeScheduleProvider) ReplaceCommonSchedule(schedules map[time.Weekday][]dao.ScheduleModel) error {
+	f.slots = map[string]*dao.ScheduleModel{}
+
+	for _, val := range schedules {
+		for i := range val {
+			f.slots[val[i].Id] = &val[i]
+		}
+	}
+
+	return nil
+}
+
+type fakeSnapshotProvider struct {
+	abstractions.ISnapshotProvider
+	snapshots []dao.ScheduleSnapshotModel
+}
+
+func (f *fakeSnapshotProvider) GetSnapshotById(id string) (*dao.ScheduleSnapshotModel, error) {
+	for _, snapshot := range f.snapshots {
+		if snapshot.Id == id {
+			return &snapshot, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func TestCloneScheduleSnapshot(t *testing.T) {
+	courses := &fakeCourseProvider{courses: []dao.CourseModel{
+		{Id: "math", Name: "Math", MeetLink: "https://meet/new"},
+	}}
+	provider := newTestCancelSchedule()
+	draft := newTestCancelSchedule()
+
+	service := ScheduleService{
+		provider:       provider,
+		draft:          draft,
+		courseProvider: courses,
+		snapshotProvider: &fakeSnapshotProvider{snapshots: []dao.ScheduleSnapshotModel{{
+			Id: "autumn",
+			Schedules: map[time.Weekday][]dao.ScheduleModel{
+				time.Monday: {{Id: "m1", Weekday: time.Monday, Order: 1, CourseId: "math"}},
+				time.Friday: {{Id: "f1", Weekday: time.Friday, Order: 1, CourseId: "physics"},
+					{Id: "f2", Weekday: time.Friday, Order: 2, IsOptional: true,
+						OptCourseParams: dao.OptionalCourseSettings{UserIdToCourseId: map[int]string{10: "art"}}}},
+			},
+			Courses: []dao.CourseModel{
+				{Id: "math", Name: "Math", MeetLink: "https://meet/old"},
+				{Id: "physics", Name: "Physics"},
+			},
+		}}},
+	}
+
+	if err := service.CloneScheduleSnapshot(dto.CloneScheduleSnapshotRequest{SnapshotId: "spring"}); err != exceptions.NotFound {
+		t.Errorf("expected an unknown snapshot to be refused, got %v", err)
+	}
+
+	if err := service.CloneScheduleSnapshot(dto.CloneScheduleSnapshotRequest{SnapshotId: "autumn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if math, _ := courses.GetCourseById("math"); math.MeetLink != "https://meet/new" {
+		t.Errorf("expected the live course to keep its link, got %s", math.MeetLink)
+	}
+
+	if _, err := courses.GetCourseById("physics"); err != nil {
+		t.Errorf("expected the deleted course to be restored")
+	}
+
+	if len(draft.slots) != 3 || draft.slots["f1"] == nil || len(draft.slots["f2"].OptCourseParams.UserIdToCourseId) != 0 {
+		t.Errorf("expected the draft to have the weekly schedule of the snapshot without selections, got %v", draft.slots)
+	}
+
+	if len(provider.slots) != 3 || provider.slots["m2"] == nil || provider.slots["f1"] != nil {
+		t.Errorf("expected the published schedule to stay the same, got %v", provider.slots)
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"github.com/sirupsen/logrus"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+)
+
+// electiveStage collects a change of the optional slots and their waitlists in memory, save writes all links
+// to the schedule at once, so a failed write leaves none of them behind
+type electiveStage struct {
+	service   ElectiveService
+	slots     map[string]*dao.ScheduleModel
+	previous  map[string]map[int]string
+	links     map[string]map[int]string
+	waitlists map[string]map[string][]int
+	changed   map[string]map[string]bool
+}
+
+func (e ElectiveService) newStage() *electiveStage {
+	return &electiveStage{
+		service:   e,
+		slots:     map[string]*dao.ScheduleModel{},
+		previous:  map[string]map[int]string{},
+		links:     map[string]map[int]string{},
+		waitlists: map[string]map[string][]int{},
+		changed:   map[string]map[string]bool{},
+	}
+}
+
+// slot returns the staged copy of the optional slot with the links made so far
+func (s *electiveStage) slot(scheduleId string) (*dao.ScheduleModel, error) {
+	if slot, ok := s.slots[scheduleId]; ok {
+		return slot, nil
+	}
+
+	slot, err := s.service.scheduleProvider.GetScheduleById(scheduleId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the provider shares the map of the choices with its cache
+	previous, links := map[int]string{}, map[int]string{}
+
+	for userId, courseId := range slot.OptCourseParams.UserIdToCourseId {
+		previous[userId] = courseId
+		links[userId] = courseId
+	}
+
+	slot.OptCourseParams.UserIdToCourseId = links
+	s.slots[scheduleId] = slot
+	s.previous[scheduleId] = previous
+
+	return slot, nil
+}
+
+func (s *electiveStage) waitlist(scheduleId string) (map[string][]int, error) {
+	if waitlists, ok := s.waitlists[scheduleId]; ok {
+		return waitlists, nil
+	}
+
+	loaded, err := s.service.provider.GetWaitlistsBySchedule(scheduleId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	waitlists := map[string][]int{}
+
+	for courseId, userIds := range loaded {
+		waitlists[courseId] = append([]int{}, userIds...)
+	}
+
+	s.waitlists[scheduleId] = waitlists
+	return waitlists, nil
+}
+
+// link stages the choice of the student, the student leaves every waitlist of the slot
+func (s *electiveStage) link(userId int, scheduleId string, courseId string) error {
+	slot, err := s.slot(scheduleId)
+
+	if err != nil {
+		return err
+	}
+
+	slot.OptCourseParams.UserIdToCourseId[userId] = courseId
+
+	if s.links[scheduleId] == nil {
+		s.links[scheduleId] = map[int]string{}
+	}
+
+	s.links[scheduleId][userId] = courseId
+
+	waitlists, err := s.waitlist(scheduleId)
+
+	if err != nil {
+		return err
+	}
+
+	for waitlistCourseId, userIds := range waitlists {
+		var filtered []int
+
+		for _, val := range userIds {
+			if val != userId {
+				filtered = append(filtered, val)
+			}
+		}
+
+		if len(filtered) == len(userIds) {
+			continue
+		}
+
+		waitlists[waitlistCourseId] = filtered
+
+		if s.changed[scheduleId] == nil {
+			s.changed[scheduleId] = map[string]bool{}
+		}
+
+		s.changed[scheduleId][waitlistCourseId] = true
+	}
+
+	return nil
+}
+
+// promote gives the free seats of the course to the waitlisted students in their order, a promoted student
+// frees the seat of the previous course, so the promotion goes on there. The capacity of a course is counted
+// within the slot, the same course in another slot has its own seats
+func (s *electiveStage) promote(scheduleId string, courseId string) ([]dto.PromotedStudentDto, error) {
+	var promoted []dto.PromotedStudentDto
+
+	queue := []string{courseId}
+
+	for len(queue) > 0 {
+		course, err := s.service.courseProvider.GetCourseById(queue[0])
+		queue = queue[1:]
+
+		if err != nil {
+			continue
+		}
+
+		for {
+			slot, err := s.slot(scheduleId)
+
+			if err != nil {
+				return promoted, err
+			}
+
+			if course.Capacity > 0 && countEnrolled(*slot, course.Id) >= course.Capacity {
+				break
+			}
+
+			waitlists, err := s.waitlist(scheduleId)
+
+			if err != nil {
+				return promoted, err
+			}
+
+			if len(waitlists[course.Id]) == 0 {
+				break
+			}
+
+			userId := waitlists[course.Id][0]
+			previous, hadPrevious := slot.OptCourseParams.UserIdToCourseId[userId]
+
+			if err = s.link(userId, scheduleId, course.Id); err != nil {
+				return promoted, err
+			}
+
+			promoted = append(promoted, dto.PromotedStudentDto{
+				UserId:     userId,
+				Slot:       convertToOptionalSlotDto(*slot),
+				CourseInfo: getCourseDto(s.service.courseProvider, course.Id),
+			})
+
+			if hadPrevious {
+				queue = append(queue, previous)
+			}
+		}
+	}
+
+	return promoted, nil
+}
+
+// save writes the links to the published schedule and to the draft, the published links are put back when
+// the draft fails. The waitlists are written after the links
+func (s *electiveStage) save() error {
+	if len(s.links) > 0 {
+		if err := s.service.scheduleProvider.LinkCoursesToUsers(s.links); err != nil {
+			return err
+		}
+
+		if err := s.service.draftProvider.LinkCoursesToUsers(s.links); err != nil {
+			s.restore()
+			return err
+		}
+	}
+
+	for scheduleId, courses := range s.changed {
+		for courseId := range courses {
+			if err := s.service.provider.SaveWaitlist(scheduleId, courseId, s.waitlists[scheduleId][courseId]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// restore puts back the published choices overwritten by the stage, a missing choice is removed
+func (s *electiveStage) restore() {
+	restored := map[string]map[int]string{}
+
+	for scheduleId, links := range s.links {
+		restored[scheduleId] = map[int]string{}
+
+		for userId := range links {
+			restored[scheduleId][userId] = s.previous[scheduleId][userId]
+		}
+	}
+
+	if err := s.service.scheduleProvider.LinkCoursesToUsers(restored); err != nil {
+		logrus.Errorf("Failed to restore the choices of the optional courses: %s", err)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+)
+
+// SubgroupService keeps the subgroups which split the group for labs and language classes
+type SubgroupService struct {
+	provider         abstractions.ISubgroupProvider
+	scheduleProvider abstractions.IScheduleProvider
+	draftProvider    abstractions.IScheduleProvider
+	userProvider     abstractions.IUserProvider
+}
+
+func NewSubgroupService(
+	provider abstractions.ISubgroupProvider,
+	scheduleProvider abstractions.IScheduleProvider,
+	draftProvider abstractions.IScheduleProvider,
+	userProvider abstractions.IUserProvider) *SubgroupService {
+	return &SubgroupService{
+		provider:         provider,
+		scheduleProvider: scheduleProvider,
+		draftProvider:    draftProvider,
+		userProvider:     userProvider,
+	}
+}
+
+func (s SubgroupService) CreateSubgroup(request dto.CreateSubgroupRequest) error {
+	name := strings.TrimSpace(request.Name)
+
+	if name == "" {
+		return errors.New("InvalidName")
+	}
+
+	model, err := s.provider.GetSubgroups()
+
+	if err != nil {
+		return err
+	}
+
+	if containsName(model.Names, name) {
+		return errors.New("AlreadyExists")
+	}
+
+	model.Names = append(model.Names, name)
+
+	return s.provider.SaveSubgroups(model)
+}
+
+// DeleteSubgroup removes the subgroup and unassigns its students, subgroups used by the published
+// schedule or the draft can't be removed
+func (s SubgroupService) DeleteSubgroup(request dto.DeleteSubgroupRequest) error {
+	model, err := s.provider.GetSubgroups()
+
+	if err != nil {
+		return err
+	}
+
+	if !containsName(model.Names, request.Name) {
+		return exceptions.NotFound
+	}
+
+	if isSubgroupUsed(s.scheduleProvider, request.Name) || isSubgroupUsed(s.draftProvider, request.Name) {
+		return errors.New("SubgroupInUse")
+	}
+
+	var names []string
+
+	for _, name := range model.Names {
+		if name != request.Name {
+			names = append(names, name)
+		}
+	}
+
+	model.Names = names
+
+	for userId, subgroup := range model.UserIdToSubgroup {
+		if subgroup == request.Name {
+			delete(model.UserIdToSubgroup, userId)
+		}
+	}
+
+	return s.provider.SaveSubgroups(model)
+}
+
+func (s SubgroupService) AssignStudent(request dto.AssignSubgroupRequest) error {
+	model, err := s.provider.GetSubgroups()
+
+	if err != nil {
+		return err
+	}
+
+	if request.Subgroup == "" {
+		delete(model.UserIdToSubgroup, request.UserId)
+		return s.provider.SaveSubgroups(model)
+	}
+
+	if !containsName(model.Names, request.Subgroup) {
+		return exceptions.NotFound
+	}
+
+	model.UserIdToSubgroup[request.UserId] = request.Subgroup
+
+	return s.provider.SaveSubgroups(model)
+}
+
+func (s SubgroupService) GetSubgroups() (*dto.GetSubgroupsResponse, error) {
+	model, err := s.provider.GetSubgroups()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var subgroups []dto.SubgroupDto
+
+	for _, name := range model.Names {
+		subgroup := dto.SubgroupDto{Name: name}
+
+		for userId, val := range model.UserIdToSubgroup {
+			if val == name {
+				subgroup.Students = append(subgroup.Students, getStudentDto(s.userProvider, userId))
+			}
+		}
+
+		sort.Slice(subgroup.Students, func(i, j int) bool {
+			return subgroup.Students[i].FullName < subgroup.Students[j].FullName
+		})
+
+		subgroups = append(subgroups, subgroup)
+	}
+
+	return &dto.GetSubgroupsResponse{Subgroups: subgroups}, nil
+}
+
+func (s SubgroupService) GetStudentSubgroup(userId int) string {
+	return s.provider.GetUserSubgroup(userId)
+}
+
+func isSubgroupUsed(provider abstractions.IScheduleProvider, subgroup string) bool {
+	schedules, additionals := provider.Export()
+
+	for _, val := range schedules {
+		for _, v := range val {
+			if v.Subgroup == subgroup {
+				return true
+			}
+		}
+	}
+
+	for _, val := range additionals {
+		for _, v := range val {
+			if v.Subgroup == subgroup {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, val := range names {
+		if val == name {
+			return true
+		}
+	}
+
+	return false
+}
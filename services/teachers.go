@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"telegram-notification-bot-core/util"
+	"time"
+)
+
+// TeacherService serves the teachers across all groups, a teacher owns the courses linked by TeacherId
+type TeacherService struct {
+	provider     abstractions.ITeacherProvider
+	groupService abstractions.IGroupService
+}
+
+func NewTeacherService(provider abstractions.ITeacherProvider, groupService abstractions.IGroupService) *TeacherService {
+	return &TeacherService{provider: provider, groupService: groupService}
+}
+
+// RegisterTeacher creates the teacher or updates the name of the already registered one,
+// courses of every group are relinked by the name
+func (t TeacherService) RegisterTeacher(request dto.RegisterTeacherRequest) (string, error) {
+	name := strings.TrimSpace(request.Name)
+
+	if name == "" {
+		return "", errors.New("InvalidName")
+	}
+
+	if other, err := t.provider.GetTeacherByName(name); err == nil && other.UserId != request.UserId {
+		return "", errors.New("AlreadyExists")
+	}
+
+	model := dao.TeacherModel{UserId: request.UserId, Name: name, Contact: request.Contact}
+
+	teacher, err := t.provider.GetTeacherByUserId(request.UserId)
+
+	if err == nil {
+		model.Id = teacher.Id
+		err = t.provider.UpdateTeacher(model)
+	} else {
+		model.Id, err = t.provider.CreateNewTeacher(model)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, scope := range t.groupService.GetScopes() {
+		if err = scope.Course.LinkTeacher(model.Id, model.Name); err != nil {
+			return "", err
+		}
+	}
+
+	return model.Id, nil
+}
+
+func (t TeacherService) GetTeacher(userId int) (*dto.TeacherDto, error) {
+	teacher, err := t.provider.GetTeacherByUserId(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TeacherDto{
+		Id:      teacher.Id,
+		UserId:  teacher.UserId,
+		Name:    teacher.Name,
+		Contact: teacher.Contact,
+	}, nil
+}
+
+// GetTeacherClasses returns the lessons of the teacher in every group for the date range
+func (t TeacherService) GetTeacherClasses(userId int, from time.Time, to time.Time) (*dto.GetTeacherClassesResponse, error) {
+	teacher, err := t.provider.GetTeacherByUserId(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lessons []dto.TeacherLessonDto
+
+	for _, scope := range t.groupService.GetScopes() {
+		scopeLessons, err := scope.Schedule.GetTeacherLessons(teacher.Id, from, to)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lesson := range scopeLessons {
+			lesson.GroupId = scope.GroupId
+			lesson.GroupName = scope.Name
+			lessons = append(lessons, lesson)
+		}
+	}
+
+	sort.SliceStable(lessons, func(i, j int) bool {
+		if !lessons[i].Date.Equal(lessons[j].Date) {
+			return lessons[i].Date.Before(lessons[j].Date)
+		}
+		return lessons[i].Order < lessons[j].Order
+	})
+
+	return &dto.GetTeacherClassesResponse{Lessons: lessons}, nil
+}
+
+func (t TeacherService) GetTeacherCourses(userId int) (*dto.GetTeacherCoursesResponse, error) {
+	teacher, err := t.provider.GetTeacherByUserId(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var courses []dto.TeacherCourseDto
+
+	for _, scope := range t.groupService.GetScopes() {
+		scopeCourses, err := scope.Course.GetCourses()
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, course := range scopeCourses.Courses {
+			if course.TeacherId == teacher.Id {
+				courses = append(courses, dto.TeacherCourseDto{GroupId: scope.GroupId, GroupName: scope.Name, CourseInfo: course})
+			}
+		}
+	}
+
+	return &dto.GetTeacherCoursesResponse{Courses: courses}, nil
+}
+
+// CancelClass cancels the lesson of the teacher's course, the cancellation is applied at once like CancelRange
+func (t TeacherService) CancelClass(request dto.CancelClassRequest) (*dto.CancelClassResponse, error) {
+	scope, _, err := t.findTeacherCourse(request.UserId, request.CourseId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := scope.Schedule.CancelRange(dto.CancelRangeRequest{
+		From:     request.Date,
+		To:       request.Date,
+		CourseId: request.CourseId,
+		Order:    request.Order,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Lessons) == 0 {
+		return nil, exceptions.NotFound
+	}
+
+	return &dto.CancelClassResponse{GroupId: scope.GroupId, Lessons: result.Lessons}, nil
+}
+
+func (t TeacherService) UpdateMeetLink(request dto.UpdateTeacherMeetLinkRequest) (*dto.UpdateTeacherMeetLinkResponse, error) {
+	scope, course, err := t.findTeacherCourse(request.UserId, request.CourseId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = scope.Course.UpdateMeetLink(dto.UpdateMeetLinkRequest{CourseId: course.Id, MeetLink: request.MeetLink}); err != nil {
+		return nil, err
+	}
+
+	course.MeetLink = request.MeetLink
+
+	return &dto.UpdateTeacherMeetLinkResponse{GroupId: scope.GroupId, CourseInfo: *course}, nil
+}
+
+// PrepareTeacherSchedulesForNotify returns today's lessons of every teacher by the user id of the teacher
+func (t TeacherService) PrepareTeacherSchedulesForNotify() (map[int][]dto.ScheduleDto, error) {
+	teachers, err := t.provider.GetTeachers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	today := util.GetMidnightTime()
+	result := map[int][]dto.ScheduleDto{}
+
+	for _, teacher := range teachers {
+		classes, err := t.GetTeacherClasses(teacher.UserId, today, today)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lesson := range classes.Lessons {
+			result[teacher.UserId] = append(result[teacher.UserId], dto.ScheduleDto{
+				CourseInfo: lesson.CourseInfo,
+				Order:      lesson.Order,
+				WeekOrder:  util.GetWeekOrderByDate(lesson.Date),
+				Subgroup:   lesson.Subgroup,
+				GroupName:  lesson.GroupName,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func (t TeacherService) findTeacherCourse(userId int, courseId string) (*abstractions.GroupScope, *dto.CourseDto, error) {
+	teacher, err := t.provider.GetTeacherByUserId(userId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, scope := range t.groupService.GetScopes() {
+		course, err := scope.Course.GetCourseById(courseId)
+
+		if err != nil {
+			continue
+		}
+
+		if course.TeacherId != teacher.Id {
+			return nil, nil, exceptions.NotFound
+		}
+
+		return &scope, course, nil
+	}
+
+	return nil, nil, exceptions.NotFound
+}
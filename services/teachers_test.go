@@ -0,0 +1,208 @@
+package services
+
+import (
+	"strings"
+	"telegram-notification-bot-core/abstractions"
+	"telegram-notification-bot-core/dao"
+	"telegram-notification-bot-core/dto"
+	"telegram-notification-bot-core/exceptions"
+	"testing"
+	"time"
+)
+
+func (f *fakeCourseProvider) UpdateCourse(model dao.CourseModel) error {
+	return f.UpsertCourses([]dao.CourseModel{model})
+}
+
+func (f *fakeCourseProvider) UpsertCourses(models []dao.CourseModel) error {
+	for _, model := range models {
+		if _, err := f.GetCourseById(model.Id); err != nil {
+			f.courses = append(f.courses, model)
+			continue
+		}
+
+		for i, course := range f.courses {
+			if course.Id == model.Id {
+				f.courses[i] = model
+			}
+		}
+	}
+
+	return nil
+}
+
+type fakeTeacherProvider struct {
+	abstractions.ITeacherProvider
+	teachers []dao.TeacherModel
+}
+
+func (f *fakeTeacherProvider) CreateNewTeacher(model dao.TeacherModel) (string, error) {
+	model.Id = "t" + model.Name
+	f.teachers = append(f.teachers, model)
+	return model.Id, nil
+}
+
+func (f *fakeTeacherProvider) UpdateTeacher(model dao.TeacherModel) error {
+	for i, teacher := range f.teachers {
+		if teacher.Id == model.Id {
+			f.teachers[i] = model
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeTeacherProvider) GetTeacherByUserId(userId int) (*dao.TeacherModel, error) {
+	for _, teacher := range f.teachers {
+		if teacher.UserId == userId {
+			return &teacher, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+func (f *fakeTeacherProvider) GetTeacherByName(name string) (*dao.TeacherModel, error) {
+	for _, teacher := range f.teachers {
+		if strings.EqualFold(teacher.Name, name) {
+			return &teacher, nil
+		}
+	}
+
+	return nil, exceptions.NotFound
+}
+
+type fakeGroupService struct {
+	abstractions.IGroupService
+	scopes []abstractions.GroupScope
+}
+
+func (f *fakeGroupService) GetScopes() []abstractions.GroupScope {
+	return f.scopes
+}
+
+// newTestTeacherService has Ivan teaching Math and Art in the group "a" and History in the group "b",
+// Olena teaches History in the group "a", Math has lessons on Mondays and Art is held in the optional slot
+func newTestTeacherService(t *testing.T) (TeacherService, *fakeCourseProvider, *fakeCourseProvider) {
+	first := &fakeCourseProvider{courses: []dao.CourseModel{
+		{Id: "math", Name: "Math", TeacherName: "Ivan", TeacherId: "tIvan"},
+		{Id: "history", Name: "History", TeacherName: "Olena", TeacherId: "tOlena"},
+		{Id: "art", Name: "Art", TeacherName: "Ivan", TeacherId: "tIvan", IsOptional: true},
+	}}
+	second := &fakeCourseProvider{courses: []dao.CourseModel{
+		{Id: "history-b", Name: "History", TeacherName: "ivan "},
+	}}
+
+	schedule := newTestCancelService(t, newTestCancelSchedule(), newTestCancelSchedule())
+	schedule.courseProvider = first
+
+	teachers := &fakeTeacherProvider{teachers: []dao.TeacherModel{
+		{Id: "tIvan", UserId: 100, Name: "Ivan"},
+		{Id: "tOlena", UserId: 101, Name: "Olena"},
+	}}
+
+	return *NewTeacherService(teachers, &fakeGroupService{scopes: []abstractions.GroupScope{
+		{GroupId: "a", Course: NewCourseService(first, teachers), Schedule: schedule},
+		{GroupId: "b", Course: NewCourseService(second, teachers)},
+	}}), first, second
+}
+
+func TestRegisterTeacher(t *testing.T) {
+	service, first, second := newTestTeacherService(t)
+
+	teacherOf := func(courses *fakeCourseProvider, courseId string) string {
+		course, _ := courses.GetCourseById(courseId)
+		return course.TeacherId
+	}
+
+	if _, err := service.RegisterTeacher(dto.RegisterTeacherRequest{UserId: 102, Name: "olena"}); err == nil || err.Error() != "AlreadyExists" {
+		t.Errorf("expected the name of another teacher to be refused, got %v", err)
+	}
+
+	if _, err := service.RegisterTeacher(dto.RegisterTeacherRequest{UserId: 102, Name: " "}); err == nil || err.Error() != "InvalidName" {
+		t.Errorf("expected the empty name to be refused, got %v", err)
+	}
+
+	id, err := service.RegisterTeacher(dto.RegisterTeacherRequest{UserId: 100, Name: "Ivan"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "tIvan" || teacherOf(second, "history-b") != "tIvan" || teacherOf(first, "math") != "tIvan" {
+		t.Errorf("expected the courses of every group to be linked to %s", id)
+	}
+
+	// renaming relinks the courses, the courses of the previous name are left without a teacher
+	first.courses[0].TeacherName = "Ivan Petrenko"
+
+	if _, err = service.RegisterTeacher(dto.RegisterTeacherRequest{UserId: 100, Name: "Ivan Petrenko"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if teacherOf(first, "math") != "tIvan" || teacherOf(first, "art") != "" || teacherOf(second, "history-b") != "" {
+		t.Errorf("expected only Math to stay linked, got %v and %v", first.courses, second.courses)
+	}
+
+	if teacherOf(first, "history") != "tOlena" {
+		t.Errorf("expected the course of another teacher to stay linked")
+	}
+}
+
+func TestCancelClass(t *testing.T) {
+	// 2024-01-01 is a Monday
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name    string
+		request dto.CancelClassRequest
+		err     error
+	}{
+		{name: "own course", request: dto.CancelClassRequest{UserId: 100, CourseId: "math", Date: monday, Order: 1}},
+		{name: "course of another teacher", request: dto.CancelClassRequest{UserId: 100, CourseId: "history", Date: monday, Order: 2}, err: exceptions.NotFound},
+		{name: "not a teacher", request: dto.CancelClassRequest{UserId: 102, CourseId: "math", Date: monday, Order: 1}, err: exceptions.NotFound},
+		{name: "no lesson at the time", request: dto.CancelClassRequest{UserId: 100, CourseId: "math", Date: monday, Order: 2}, err: exceptions.NotFound},
+		{name: "optional course", request: dto.CancelClassRequest{UserId: 100, CourseId: "art", Date: monday.AddDate(0, 0, 1), Order: 1}, err: exceptions.ElectiveNotCancellable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service, _, _ := newTestTeacherService(t)
+
+			response, err := service.CancelClass(c.request)
+
+			if err != c.err {
+				t.Fatalf("expected the error %v, got %v", c.err, err)
+			}
+
+			if err == nil && (response.GroupId != "a" || len(response.Lessons) != 1 || response.Lessons[0].CourseInfo.Id != "math") {
+				t.Errorf("expected the lesson of Math in the group a to be cancelled, got %v", response)
+			}
+		})
+	}
+}
+
+func TestUpdateMeetLink(t *testing.T) {
+	service, first, _ := newTestTeacherService(t)
+
+	if _, err := service.UpdateMeetLink(dto.UpdateTeacherMeetLinkRequest{UserId: 100, CourseId: "history", MeetLink: "https://meet/x"}); err != exceptions.NotFound {
+		t.Errorf("expected the course of another teacher to be refused, got %v", err)
+	}
+
+	response, err := service.UpdateMeetLink(dto.UpdateTeacherMeetLinkRequest{UserId: 100, CourseId: "math", MeetLink: "https://meet/y"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	math, _ := first.GetCourseById("math")
+	history, _ := first.GetCourseById("history")
+
+	if response.GroupId != "a" || response.CourseInfo.MeetLink != "https://meet/y" || math.MeetLink != "https://meet/y" {
+		t.Errorf("expected the link of Math to be updated, got %v", response)
+	}
+
+	if history.MeetLink != "" {
+		t.Errorf("expected the link of History to stay empty, got %s", history.MeetLink)
+	}
+}
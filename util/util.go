@@ -2,6 +2,7 @@ package util
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -14,9 +15,11 @@ const (
 )
 
 func GetCurrentWeekOrder() WeekOrder {
-	actualTime := time.Now()
+	return GetWeekOrderByDate(time.Now())
+}
 
-	actualWeekCount := actualTime.Day() / 7
+func GetWeekOrderByDate(date time.Time) WeekOrder {
+	actualWeekCount := date.Day() / 7
 
 	if actualWeekCount%2 == 0 {
 		return 1
@@ -96,6 +99,12 @@ func ConvertToHumanReadableWeek(weekday time.Weekday) string {
 	}
 }
 
+// IsVisibleForSubgroup reports whether the lesson of the subgroup is shown to the subgroup, lessons of the whole group
+// are shown to everyone, an empty subgroup sees the lessons of all subgroups
+func IsVisibleForSubgroup(lessonSubgroup string, subgroup string) bool {
+	return lessonSubgroup == "" || subgroup == "" || lessonSubgroup == subgroup
+}
+
 func GetMidnightTime() time.Time {
 	currentTime := time.Now()
 
@@ -104,3 +113,74 @@ func GetMidnightTime() time.Time {
 
 	return midnight
 }
+
+type ExamKind int
+
+const (
+	ExamKindExam   ExamKind = 1
+	ExamKindCredit ExamKind = 2
+)
+
+func ConvertFromHumanReadableExamKind(data string) (ExamKind, error) {
+	values := map[string]ExamKind{
+		"Іспит": ExamKindExam,
+		"Залік": ExamKindCredit,
+	}
+
+	converted, ok := values[data]
+
+	if !ok {
+		return 0, errors.New("InvalidExamKind")
+	}
+
+	return converted, nil
+}
+
+func ConvertToHumanReadableExamKind(kind ExamKind) string {
+	switch kind {
+	case ExamKindExam:
+		return "Іспит"
+	case ExamKindCredit:
+		return "Залік"
+	default:
+		return ""
+	}
+}
+
+type TargetKind int
+
+const (
+	TargetKindGroupChat TargetKind = 1
+	TargetKindChannel   TargetKind = 2
+)
+
+type OutboxState int
+
+const (
+	OutboxStatePending OutboxState = 1
+	OutboxStateSent    OutboxState = 2
+	OutboxStateFailed  OutboxState = 3
+)
+
+type DigestKind int
+
+const (
+	DigestKindMorning DigestKind = 1 // the schedule of today
+	DigestKindEvening DigestKind = 2 // the preview of tomorrow
+)
+
+func ConvertToHumanReadableCountdown(duration time.Duration) string {
+	if duration < 0 {
+		duration = 0
+	}
+
+	days := int(duration.Hours()) / 24
+	hours := int(duration.Hours()) % 24
+	minutes := int(duration.Minutes()) % 60
+
+	if days > 0 {
+		return fmt.Sprintf("%d дн. %d год.", days, hours)
+	}
+
+	return fmt.Sprintf("%d год. %d хв.", hours, minutes)
+}